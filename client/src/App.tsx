@@ -19,6 +19,8 @@ function App() {
   // State variables for user information and authentication
   const [name, setName] = useState('');
   const [email, setEmail] = useState('');
+  // ETag of the user resource, sent as If-Match when the profile is edited
+  const [etag, setEtag] = useState('');
   const [isAuthenticated, setIsAuthenticated] = useState(false);
   const [loading, setLoading] = useState(true);
   const [error, setError] = useState<string | null>(null);
@@ -36,6 +38,7 @@ function App() {
         const content = await response.json();
         setName(content.name);
         setEmail(content.email);
+        setEtag(response.headers.get('ETag') || '');
         setIsAuthenticated(true);
       } else if (response.status === 401) {
         // User is not authenticated
@@ -54,9 +57,10 @@ function App() {
   };
 
   // Function to handle profile updates
-  const handleProfileUpdate = (updatedProfile: { name: string; email: string }) => {
+  const handleProfileUpdate = (updatedProfile: { name: string; email: string; etag: string }) => {
     setName(updatedProfile.name);
     setEmail(updatedProfile.email);
+    setEtag(updatedProfile.etag);
   };
 
   // Fetch user data on component mount
@@ -84,6 +88,7 @@ function App() {
               <Route path='/edit-profile' element={<EditProfile
                   name={name}
                   email={email}
+                  etag={etag}
                   onProfileUpdate={handleProfileUpdate}
                 />} />
             </Routes>
@@ -17,6 +17,8 @@
 import { SyntheticEvent, useEffect, useState, useCallback } from 'react';
 import { useLocation, useNavigate } from 'react-router-dom';
 import utils from '../../styles/utils.module.css';
+import { csrfFetch } from '../../utils/csrf';
+import { problemMessage } from '../../utils/problem';
 
 const Login = (props: {
     setName: (name: string) => void;
@@ -74,9 +76,8 @@ const Login = (props: {
 
         try {
             // Send login request to the server
-            const response = await fetch(`${process.env.REACT_APP_API_URL}/api/login`, {
+            const response = await csrfFetch(`${process.env.REACT_APP_API_URL}/api/login`, {
                 method: 'POST',
-                headers: { 'Content-Type': 'application/json' },
                 credentials: 'include',
                 body: JSON.stringify({ email, password })
             });
@@ -92,7 +93,7 @@ const Login = (props: {
                         setError('Internal server error. Please try again later.');
                         break;
                     default:
-                        setError(content.message || 'Login failed');
+                        setError(problemMessage(content, 'Login failed'));
                         break;
                 }
                 setLoading(false);
@@ -7,6 +7,8 @@
 import { SyntheticEvent, useEffect, useState, useCallback } from 'react';
 import { useNavigate } from "react-router-dom";
 import utils from '../../styles/utils.module.css';
+import { csrfFetch } from '../../utils/csrf';
+import { problemMessage } from '../../utils/problem';
 
 const Register = () => {
     // Set the document title when the component mounts
@@ -49,9 +51,9 @@ const Register = () => {
 
         try {
             // Send registration request to the server
-            const response = await fetch(`${process.env.REACT_APP_API_URL}/api/register`, {
+            const response = await csrfFetch(`${process.env.REACT_APP_API_URL}/api/register`, {
                 method: 'POST',
-                headers: { 'Content-Type': 'application/json' },
+                credentials: 'include',
                 body: JSON.stringify({
                     name,
                     email,
@@ -64,7 +66,7 @@ const Register = () => {
                 const content = await response.json();
                 switch (response.status) {
                     case 400:
-                        setError(content.message || 'Registration failed. Please check your input.');
+                        setError(problemMessage(content, 'Registration failed. Please check your input.'));
                         break;
                     case 500:
                         setError('Internal server error. Please try again later.');
@@ -16,6 +16,7 @@ import { useState } from 'react';
 import styles from '../../styles/Nav.module.css';
 import '../../styles/index.css';
 import utils from '../../styles/utils.module.css';
+import { csrfFetch } from '../../utils/csrf';
 
 const Nav = (props: {
     name: string;
@@ -44,9 +45,8 @@ const Nav = (props: {
         setError(null);
 
         try {
-            const response = await fetch(`${process.env.REACT_APP_API_URL}/api/logout`, {
+            const response = await csrfFetch(`${process.env.REACT_APP_API_URL}/api/logout`, {
                 method: 'POST',
-                headers: { 'Content-Type': 'application/json' },
                 credentials: 'include',
             });
 
@@ -73,9 +73,8 @@ const Nav = (props: {
     setError(null);
 
     try {
-        const response = await fetch(`${process.env.REACT_APP_API_URL}/api/user`, {
+        const response = await csrfFetch(`${process.env.REACT_APP_API_URL}/api/user`, {
             method: 'DELETE',
-            headers: { 'Content-Type': 'application/json' },
             credentials: 'include',
         });
 
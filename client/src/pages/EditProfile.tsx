@@ -7,14 +7,16 @@
  *
  * When the form is submitted successfully, the component calls the `onProfileUpdate` callback function provided by the parent component, passing the updated profile data.
  *
- * @param props - An object containing the user's current name, email, and a callback function to update the profile.
+ * @param props - An object containing the user's current name, email, the ETag they were loaded with, and a callback function to update the profile.
  * @returns The `EditProfile` component.
  */
 import utils from '../styles/utils.module.css';
 import { useEffect, useState } from 'react';
 import { Link } from 'react-router-dom';
+import { csrfFetch } from '../utils/csrf';
+import { problemMessage } from '../utils/problem';
 
-const EditProfile = (props: { name: string; email: string; onProfileUpdate: (updatedProfile: any) => void }) => {
+const EditProfile = (props: { name: string; email: string; etag: string; onProfileUpdate: (updatedProfile: any) => void }) => {
     // Set the document title when the component mounts
     useEffect(() => {
         document.title = "Edit Profile";
@@ -75,23 +77,29 @@ const EditProfile = (props: { name: string; email: string; onProfileUpdate: (upd
         }
 
         try {
-            // Send PUT request to update the profile
-            const response = await fetch(`${process.env.REACT_APP_API_URL}/api/user`, {
-                method: 'PUT',
-                headers: {
-                    'Content-Type': 'application/json',
-                },
+            // Send a merge patch that only applies if nobody changed the profile since it was loaded
+            const headers: Record<string, string> = {
+                'Content-Type': 'application/merge-patch+json',
+            };
+            if (props.etag) {
+                headers['If-Match'] = props.etag;
+            }
+            const response = await csrfFetch(`${process.env.REACT_APP_API_URL}/api/user`, {
+                method: 'PATCH',
+                headers,
                 credentials: 'include',
                 body: JSON.stringify(updatedProfile),
             });
 
             if (response.ok) {
                 const data = await response.json();
-                props.onProfileUpdate(data);
+                props.onProfileUpdate({ ...data, etag: response.headers.get('ETag') || '' });
                 setSuccessMessage('Profile updated successfully!');
+            } else if (response.status === 412 || response.status === 409) {
+                setErrorMessage('Your profile was changed elsewhere. Reload the page to see the latest version.');
             } else if (response.status === 400) {
                 const data = await response.json();
-                setErrorMessage(data.error || 'Invalid input');
+                setErrorMessage(problemMessage(data, 'Invalid input'));
             } else {
                 setErrorMessage('Failed to update profile. Please try again later.');
             }
@@ -11,6 +11,7 @@ import styles from '../styles/Home.module.css';
 import utils from '../styles/utils.module.css';
 import { useEffect, useState } from 'react';
 import { Link, useLocation } from 'react-router-dom';
+import { csrfFetch } from '../utils/csrf';
 
 const Home = ({ name, isAuthenticated, setName, setIsAuthenticated }: {
     name: string;
@@ -50,9 +51,8 @@ const Home = ({ name, isAuthenticated, setName, setIsAuthenticated }: {
      */
     const logout = async () => {
         // Send logout request to the server
-        await fetch(`${process.env.REACT_APP_API_URL}/api/logout`, {
+        await csrfFetch(`${process.env.REACT_APP_API_URL}/api/logout`, {
             method: 'POST',
-            headers: { 'Content-Type': 'application/json' },
             credentials: 'include',
         });
 
@@ -0,0 +1,76 @@
+/**
+ * Helpers for the server's CSRF protection.
+ * State-changing requests authenticated by the session cookie must echo a CSRF token in a request header.
+ * The token is fetched from `GET /api/csrf` (which also stores it in a cookie) and cached for later requests.
+ * If the server rejects the cached token, for example because the cookie expired, a new one is fetched.
+ */
+
+let cachedHeaders: Promise<Record<string, string>> | null = null;
+
+/**
+ * Fetches the CSRF token from the server and returns it as a header object.
+ */
+const fetchCsrfHeaders = async (): Promise<Record<string, string>> => {
+    const response = await fetch(`${process.env.REACT_APP_API_URL}/api/csrf`, {
+        credentials: 'include',
+    });
+    if (!response.ok) {
+        throw new Error('Could not obtain a CSRF token. Please try again.');
+    }
+    const data = await response.json();
+    return { [data.header || 'X-CSRF-Token']: data.csrf_token };
+};
+
+/**
+ * Returns the headers to send with a state-changing request: JSON content type plus the CSRF token.
+ */
+const csrfHeaders = async (): Promise<Record<string, string>> => {
+    if (!cachedHeaders) {
+        cachedHeaders = fetchCsrfHeaders().catch((err) => {
+            // Allow the next request to retry
+            cachedHeaders = null;
+            throw err;
+        });
+    }
+    return { 'Content-Type': 'application/json', ...(await cachedHeaders) };
+};
+
+/**
+ * Forgets the cached CSRF token, so the next request fetches a new one.
+ */
+export const resetCsrfToken = () => {
+    cachedHeaders = null;
+};
+
+/**
+ * Reports whether the response is the server's rejection of a missing or stale CSRF token.
+ */
+const isCsrfRejection = async (response: Response): Promise<boolean> => {
+    if (response.status !== 403) {
+        return false;
+    }
+    try {
+        const problem = await response.clone().json();
+        return problem.code === 'invalid_csrf_token';
+    } catch {
+        return false;
+    }
+};
+
+/**
+ * Sends a state-changing request with the CSRF headers added to `init.headers`, which take precedence.
+ * If the server rejects the CSRF token, the cached token is dropped and the request is retried once with a new one.
+ */
+export const csrfFetch = async (url: string, init: RequestInit = {}): Promise<Response> => {
+    const send = async () => fetch(url, {
+        ...init,
+        headers: { ...(await csrfHeaders()), ...(init.headers as Record<string, string> | undefined) },
+    });
+
+    const response = await send();
+    if (!(await isCsrfRejection(response))) {
+        return response;
+    }
+    resetCsrfToken();
+    return send();
+};
@@ -0,0 +1,31 @@
+/**
+ * Helpers for the server's error responses, which are RFC 7807 problem details (application/problem+json).
+ * Branch on `code` rather than on `detail`, which is meant for humans and may change.
+ */
+
+export interface FieldError {
+    field: string;
+    code: string;
+    message: string;
+}
+
+export interface Problem {
+    type: string;
+    title: string;
+    status: number;
+    detail?: string;
+    instance?: string;
+    code: string;
+    request_id?: string;
+    errors?: FieldError[];
+}
+
+/**
+ * Returns a message describing the problem, listing the invalid fields if there are any.
+ */
+export const problemMessage = (problem: Partial<Problem> | null | undefined, fallback: string): string => {
+    if (problem?.errors?.length) {
+        return problem.errors.map((error) => error.message).join(', ');
+    }
+    return problem?.detail || fallback;
+};
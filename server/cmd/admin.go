@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/models"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/repository"
+	"gorm.io/gorm"
+)
+
+// adminUsage describes the admin subcommand.
+const adminUsage = `usage: server admin <command>
+
+commands:
+  grant <email>   make the user with the email address an administrator
+  revoke <email>  make the administrator with the email address a regular user
+  list            list every administrator`
+
+// runAdmin implements the "admin grant|revoke|list" subcommand and returns the process exit code.
+// Administrator rights are only granted here, never through the API, so users cannot grant them to themselves.
+func runAdmin(db *gorm.DB, args []string) int {
+	users := repository.NewGormUserRepository(db)
+	ctx := context.Background()
+
+	switch {
+	case len(args) == 2 && (args[0] == "grant" || args[0] == "revoke"):
+		role := models.RoleAdmin
+		if args[0] == "revoke" {
+			role = models.RoleUser
+		}
+		user, err := users.FindByEmail(ctx, args[1])
+		if errors.Is(err, repository.ErrNotFound) {
+			slog.Error("No user has this email address", "email", args[1])
+			return 1
+		}
+		if err != nil {
+			slog.Error("Could not load the user", "error", err)
+			return 1
+		}
+		if user.Role == role {
+			slog.Info("Role is unchanged", "user_id", user.Id, "role", role)
+			return 0
+		}
+		if err := users.SetRole(ctx, user.Id, role); err != nil {
+			slog.Error("Could not change the role", "error", err)
+			return 1
+		}
+		slog.Info("Changed role", "user_id", user.Id, "from", user.Role, "to", role)
+		return 0
+
+	case len(args) == 1 && args[0] == "list":
+		admins, err := users.FindByRole(ctx, models.RoleAdmin)
+		if err != nil {
+			slog.Error("Could not list the administrators", "error", err)
+			return 1
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tEMAIL\tNAME")
+		for _, admin := range admins {
+			fmt.Fprintf(w, "%d\t%s\t%s\n", admin.Id, admin.Email, admin.Name)
+		}
+		w.Flush()
+		return 0
+
+	default:
+		fmt.Fprintln(os.Stderr, adminUsage)
+		return 2
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/secrets"
+	"github.com/joho/godotenv"
+)
+
+// keystoreUsage describes the keystore subcommand.
+const keystoreUsage = `usage: server keystore <command>
+
+The keystore file and its passphrase are read from SECRETS_KEYSTORE and
+SECRETS_KEYSTORE_PASSPHRASE (or SECRETS_KEYSTORE_PASSPHRASE_FILE).
+
+commands:
+  set <name>     store a secret, reading its value from the first line of standard input
+  delete <name>  remove a secret
+  list           list the names of the stored secrets`
+
+// runKeystore implements the "keystore set|delete|list" subcommand and returns the process exit code.
+func runKeystore(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, keystoreUsage)
+		return 2
+	}
+
+	// Read the keystore location from the environment or the .env file
+	if _, err := os.Stat(".env"); err == nil {
+		godotenv.Load()
+	}
+	path := os.Getenv("SECRETS_KEYSTORE")
+	passphrase, err := secrets.Chain{
+		secrets.EnvFileProvider{},
+		secrets.StaticProvider{"SECRETS_KEYSTORE_PASSPHRASE": os.Getenv("SECRETS_KEYSTORE_PASSPHRASE")},
+	}.Lookup("SECRETS_KEYSTORE_PASSPHRASE")
+	if path == "" || err != nil {
+		fmt.Fprintln(os.Stderr, "SECRETS_KEYSTORE and SECRETS_KEYSTORE_PASSPHRASE must be set")
+		return 2
+	}
+
+	// Start from the existing secrets, or an empty keystore if the file does not exist yet
+	values, err := secrets.LoadKeystore(path, passphrase)
+	if errors.Is(err, os.ErrNotExist) {
+		values, err = map[string]string{}, nil
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	switch {
+	case args[0] == "list" && len(args) == 1:
+		names := make([]string, 0, len(values))
+		for name := range values {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Println(name)
+		}
+		return 0
+
+	case args[0] == "set" && len(args) == 2:
+		value, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		value = strings.TrimRight(value, "\r\n")
+		if value == "" {
+			fmt.Fprintln(os.Stderr, "no secret value on standard input", err)
+			return 1
+		}
+		values[args[1]] = value
+
+	case args[0] == "delete" && len(args) == 2:
+		if _, ok := values[args[1]]; !ok {
+			fmt.Fprintf(os.Stderr, "secret %s not found\n", args[1])
+			return 1
+		}
+		delete(values, args[1])
+
+	default:
+		fmt.Fprintln(os.Stderr, keystoreUsage)
+		return 2
+	}
+
+	if err := secrets.SaveKeystore(path, passphrase, values); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
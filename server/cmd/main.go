@@ -1,62 +1,266 @@
 // main is the entry point of the Go application. It sets up the Fiber web framework, configures CORS, and registers the application routes.
-// The server listens for incoming requests on the port specified by the SERVER_PORT environment variable.
-// When the server receives an interrupt signal (e.g., Ctrl+C), it gracefully shuts down within 5 seconds, closing any active connections.
+// Configuration is loaded once at startup (see the config package); the server listens on the configured SERVER_PORT,
+// over HTTPS when TLS_CERT_FILE and TLS_KEY_FILE are set.
+// Run as `server migrate up|down|status` to manage the database schema instead; the server refuses to start while migrations are pending.
+// Run as `server keystore set|delete|list` to manage the encrypted secrets keystore.
+// Run as `server admin grant|revoke|list` to manage administrators.
+// When the server receives an interrupt signal (e.g., Ctrl+C), /readyz starts failing; after SHUTDOWN_DELAY the server
+// gracefully shuts down within 5 seconds, closing any active connections.
 package main
 
 import (
 	"context"
-	"log"
+	"errors"
+	"log/slog"
 	"os"
 	"os/signal"
-	"strings"
 	"syscall"
 	"time"
 
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/certs"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/config"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/controllers"
 	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/database"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/health"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/logging"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/metrics"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/middleware"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/problem"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/repository"
 	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/routes"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/secrets"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/storage"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/tracing"
 	"github.com/gofiber/fiber/v3"
 	"github.com/gofiber/fiber/v3/middleware/cors"
+	"github.com/gofiber/fiber/v3/middleware/static"
 )
 
 func main() {
+    // Run the keystore subcommand, which needs neither the full configuration nor the database
+    if len(os.Args) > 1 && os.Args[1] == "keystore" {
+        os.Exit(runKeystore(os.Args[2:]))
+    }
+
+    // Load and validate the configuration
+    cfg, err := config.Load()
+    if err != nil {
+        logging.Fatal("Invalid configuration", "error", err)
+    }
+
+    // Log structured, redacted output from here on
+    if err := logging.Setup(cfg.Logging); err != nil {
+        logging.Fatal("Could not set up logging", "error", err)
+    }
+
+    // Export traces to the configured collector
+    shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+    if err != nil {
+        logging.Fatal("Could not set up tracing", "error", err)
+    }
+
+    // Resolve the secrets from files, the configured provider and the configuration
+    secretProvider, err := secrets.NewProvider(cfg)
+    if err != nil {
+        logging.Fatal("Could not open the secrets provider", "error", err)
+    }
+    var dbPassword func() string
+    dbPasswordSecret, err := secrets.Load(secretProvider, "DB_PASSWORD")
+    if err == nil {
+        dbPassword = dbPasswordSecret.Current
+    } else if !errors.Is(err, secrets.ErrNotFound) {
+        logging.Fatal("Could not load secret", "error", err)
+    }
+
     // Connect to the database
-    database.Connect()
+    db := database.Connect(cfg.Database, dbPassword)
+
+    // Run the migrate subcommand instead of the server if requested; it needs neither the JWT secret nor the certificates
+    if len(os.Args) > 1 && os.Args[1] == "migrate" {
+        os.Exit(runMigrate(db, os.Args[2:]))
+    }
+
+    // Refuse to serve on a pending schema
+    checkSchema(db)
+
+    // Run the admin subcommand instead of the server if requested
+    if len(os.Args) > 1 && os.Args[1] == "admin" {
+        os.Exit(runAdmin(db, os.Args[2:]))
+    }
+
+    // Load the JWT signing key
+    jwtSecret, err := secrets.Load(secretProvider, "JWT_SECRET_KEY")
+    if err != nil {
+        logging.Fatal("Could not load secret", "error", err)
+    }
+    jwtSecret.SetPreviousTTL(cfg.Secrets.PreviousTTL)
+
+    // Reload rotated secrets in the background
+    watchCtx, stopWatching := context.WithCancel(context.Background())
+    defer stopWatching()
+    watched := []*secrets.Secret{jwtSecret}
+    if dbPasswordSecret != nil {
+        watched = append(watched, dbPasswordSecret)
+    }
+    go secrets.Watch(watchCtx, cfg.Secrets.ReloadInterval, watched...)
+
+    // Load the TLS certificate and reload it when it is renewed
+    var certificates *certs.Reloader
+    if cfg.Server.TLS.Enabled() {
+        certificates, err = certs.Load(cfg.Server.TLS.CertFile, cfg.Server.TLS.KeyFile, cfg.Server.TLS.ClientCAFile)
+        if err != nil {
+            logging.Fatal("Could not load TLS certificate", "error", err)
+        }
+        go certificates.Watch(watchCtx, cfg.Server.TLS.ReloadInterval)
+    }
+
+    // Ping the database periodically so connection problems show up in the logs
+    dbHealth := database.StartHealthCheck(db, cfg.Database.PingInterval)
+    defer dbHealth.Stop()
+
+    // Store uploaded files on the local filesystem or in an S3-compatible bucket
+    blobs, err := storage.New(cfg.Storage)
+    if err != nil {
+        logging.Fatal("Could not set up file storage", "error", err)
+    }
+
+    // Create the handlers backed by the database repositories
+    sessions := repository.NewGormSessionRepository(db)
+    handler := controllers.NewHandler(
+        cfg.Auth,
+        jwtSecret,
+        repository.NewGormUserRepository(db),
+        repository.NewGormAuditRepository(db),
+        repository.NewGormAttributeRepository(db),
+        repository.NewGormRevisionRepository(db),
+        repository.NewGormPreferencesRepository(db),
+        sessions,
+        blobs,
+        cfg.Avatars,
+    )
+
+    // Report liveness and readiness to the orchestrator
+    probe := health.NewProbe(
+        health.DatabaseCheck(dbHealth),
+        health.MigrationsCheck(db),
+        health.SecretCheck("signing_keys", jwtSecret),
+    )
+
+    // Create a new Fiber app instance that reports every error as an RFC 7807 problem
+    // The body limit leaves room for an avatar upload plus the rest of its multipart form
+    app := fiber.New(fiber.Config{
+        ErrorHandler: problem.ErrorHandler,
+        BodyLimit:    max(fiber.DefaultBodyLimit, cfg.Avatars.MaxBytes+1<<20),
+    })
 
-    // Create a new Fiber app instance
-    app := fiber.New()
+    // Count and time every request, and export the metrics together with the database pool statistics
+    // and the number of active sessions
+    if cfg.Metrics.Enabled {
+        if sqlDB, err := db.DB(); err == nil {
+            if err := metrics.RegisterDB(sqlDB, cfg.Database.Name); err != nil {
+                slog.Warn("Could not export database pool metrics", "error", err)
+            }
+        }
+        if err := metrics.RegisterActiveSessions(sessions.CountActive); err != nil {
+            slog.Warn("Could not export the active sessions metric", "error", err)
+        }
+        app.Use(metrics.Middleware())
+        app.Get(cfg.Metrics.Path, metrics.Handler())
+    }
 
-    // Get allowed origins from environment variable
-    allowedOriginsEnv := os.Getenv("ALLOWED_ORIGINS")
-    allowedOrigins := strings.Split(allowedOriginsEnv, ",")
+    // Serve the probes before the remaining middleware so they stay cheap, untraced and unaffected by CORS or CSRF
+    app.Get("/healthz", probe.Liveness)
+    app.Get("/readyz", probe.Readiness)
+
+    // Trace every request, continuing the caller's trace if it sent a traceparent header
+    app.Use(tracing.Middleware())
+
+    // Tag every request with an X-Request-ID, give handlers a request logger and write access logs
+    app.Use(logging.Middleware())
 
     // Configure CORS middleware
     app.Use(cors.New(cors.Config{
         AllowCredentials: true,
-        AllowOrigins:     allowedOrigins,
+        AllowOrigins:     cfg.Server.AllowedOrigins,
+        ExposeHeaders:    []string{fiber.HeaderETag, logging.HeaderRequestID},
     }))
 
+    // Tell browsers to only use HTTPS from now on
+    app.Use(middleware.HSTS(cfg.Server.TLS.HSTSHeader()))
+
+    // Send the security headers (CSP, Referrer-Policy, ...) with every response
+    app.Use(middleware.SecurityHeaders(cfg.Headers))
+
+    // Serve locally stored uploads; their keys are never reused, so they can be cached indefinitely
+    if local, ok := blobs.(*storage.LocalStore); ok {
+        app.Get(cfg.Storage.PublicURL+"/*", static.New(local.Dir(), static.Config{MaxAge: 365 * 24 * 60 * 60}))
+    }
+
+    // Accept CSP violation reports, which browsers send without a CSRF token
+    app.Post("/api/csp-report", handler.ReportCSPViolation)
+
+    // Require a CSRF token for cookie-authenticated, state-changing requests; clients fetch it from GET /api/csrf
+    csrf := middleware.NewCSRF(cfg.CSRF, cfg.Auth.Cookie)
+    app.Use(csrf.Protect)
+    app.Get("/api/csrf", csrf.Issue)
+
     // Set up application routes
-    routes.Setup(app)
+    routes.Setup(app, handler)
+
+    // Label requests that matched no route as such in the metrics
+    if cfg.Metrics.Enabled {
+        app.Use(metrics.Unmatched())
+    }
 
     // Start the server in a goroutine
     go func() {
-        if err := app.Listen(os.Getenv("SERVER_PORT")); err != nil {
-            log.Fatalf("Failed to start server: %v", err)
+        if err := listen(app, cfg.Server, certificates); err != nil {
+            logging.Fatal("Failed to start server", "error", err)
         }
     }()
 
+    // Redirect plain HTTP requests to HTTPS if requested
+    var redirectApp *fiber.App
+    if certificates != nil && cfg.Server.TLS.RedirectPort != "" {
+        redirectApp = newRedirectApp(cfg.Server.Port)
+        go func() {
+            if err := redirectApp.Listen(cfg.Server.TLS.RedirectPort, fiber.ListenConfig{DisableStartupMessage: true}); err != nil {
+                logging.Fatal("Failed to start HTTP redirect server", "error", err)
+            }
+        }()
+    }
+
     // Create a channel to listen for shutdown signals
     shutdown := make(chan os.Signal, 1)
     signal.Notify(shutdown, os.Interrupt, syscall.SIGTERM)
     <-shutdown
 
+    // Fail readiness first so the orchestrator stops routing new requests here, then stop accepting them.
+    // A second signal skips the wait.
+    probe.SetShuttingDown()
+    slog.Info("Shutting down", "delay", cfg.Server.ShutdownDelay)
+    select {
+    case <-time.After(cfg.Server.ShutdownDelay):
+    case <-shutdown:
+    }
+
     // Create a context with a 5-second timeout for graceful shutdown
     ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
     defer cancel()
 
-    // Attempt to shut down the server gracefully
+    // Attempt to shut down the servers gracefully
+    if redirectApp != nil {
+        if err := redirectApp.ShutdownWithContext(ctx); err != nil {
+            slog.Warn("HTTP redirect server forced to shutdown", "error", err)
+        }
+    }
     if err := app.ShutdownWithContext(ctx); err != nil {
-        log.Fatalf("Server forced to shutdown: %v", err)
+        logging.Fatal("Server forced to shutdown", "error", err)
+    }
+
+    // Flush the remaining spans
+    if err := shutdownTracing(ctx); err != nil {
+        slog.Warn("Could not flush traces", "error", err)
     }
-    log.Println("Server exited gracefully")
+    slog.Info("Server exited gracefully")
 }
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/logging"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/migrations"
+	"gorm.io/gorm"
+)
+
+// migrateUsage describes the migrate subcommand.
+const migrateUsage = `usage: server migrate <command>
+
+commands:
+  up          apply all pending migrations
+  down [n]    revert the last n applied migrations (default 1)
+  status      list every migration and whether it has been applied`
+
+// runMigrate implements the "migrate up|down|status" subcommand and returns the process exit code.
+func runMigrate(db *gorm.DB, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrations.Up(db)
+		for _, m := range applied {
+			slog.Info("Applied migration", "version", m.Version, "name", m.Name)
+		}
+		if err != nil {
+			slog.Error("Migration failed", "error", err)
+			return 1
+		}
+		if len(applied) == 0 {
+			slog.Info("Schema is up to date")
+		}
+		return 0
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				fmt.Fprintln(os.Stderr, migrateUsage)
+				return 2
+			}
+			steps = n
+		}
+		reverted, err := migrations.Down(db, steps)
+		for _, m := range reverted {
+			slog.Info("Reverted migration", "version", m.Version, "name", m.Name)
+		}
+		if err != nil {
+			slog.Error("Migration failed", "error", err)
+			return 1
+		}
+		if len(reverted) == 0 {
+			slog.Info("No applied migrations to revert")
+		}
+		return 0
+
+	case "status":
+		report, err := migrations.StatusReport(db)
+		if err != nil {
+			slog.Error("Could not read migration status", "error", err)
+			return 1
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+		for _, s := range report {
+			status, appliedAt := "pending", ""
+			if s.Applied {
+				status, appliedAt = "applied", s.AppliedAt.Format("2006-01-02 15:04:05 MST")
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", s.Version, s.Name, status, appliedAt)
+		}
+		w.Flush()
+		return 0
+
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+}
+
+// checkSchema refuses to start the server while migrations are pending.
+func checkSchema(db *gorm.DB) {
+	pending, err := migrations.Pending(db)
+	if err != nil {
+		logging.Fatal("Could not check the database schema", "error", err)
+	}
+	if len(pending) > 0 {
+		logging.Fatal("Database schema is out of date; run `server migrate up` first",
+			"pending", len(pending), "version", pending[0].Version, "name", pending[0].Name)
+	}
+}
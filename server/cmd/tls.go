@@ -0,0 +1,42 @@
+package main
+
+import (
+	"crypto/tls"
+	"net"
+
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/certs"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/config"
+	"github.com/gofiber/fiber/v3"
+)
+
+// listen serves the app on SERVER_PORT until it is shut down, over HTTPS when a certificate is loaded.
+func listen(app *fiber.App, settings config.ServerConfig, certificates *certs.Reloader) error {
+	if certificates == nil {
+		return app.Listen(settings.Port)
+	}
+
+	ln, err := net.Listen("tcp", settings.Port)
+	if err != nil {
+		return err
+	}
+	tlsConfig := certificates.TLSConfig(settings.TLS.MinTLSVersion(), settings.TLS.ClientAuthType())
+	return app.Listener(tls.NewListener(ln, tlsConfig))
+}
+
+// newRedirectApp returns an app that permanently redirects every plain HTTP request to the same URL over HTTPS.
+func newRedirectApp(httpsPort string) *fiber.App {
+	app := fiber.New()
+
+	// Keep the HTTPS port in the URL unless it is the default one
+	_, port, _ := net.SplitHostPort(httpsPort)
+
+	app.Use(func(c fiber.Ctx) error {
+		host := c.Hostname()
+		if port != "" && port != "443" {
+			host = net.JoinHostPort(host, port)
+		}
+		return c.Redirect().Status(fiber.StatusPermanentRedirect).To("https://" + host + c.OriginalURL())
+	})
+
+	return app
+}
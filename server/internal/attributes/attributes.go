@@ -0,0 +1,373 @@
+// Package attributes implements custom profile attributes: administrators define them with a type, validation
+// rules, visibility and who may edit them, and their values are validated against those definitions before they
+// are stored next to the user.
+//
+// Values are stored and returned as JSON: strings, dates and enum options as JSON strings, numbers as JSON
+// numbers and booleans as true or false.
+package attributes
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/models"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/problem"
+	"golang.org/x/text/unicode/norm"
+)
+
+// MaxStringLength bounds string values whose definition sets no max_length.
+const MaxStringLength = 1000
+
+// DateLayout is the format of date values.
+const DateLayout = time.DateOnly
+
+// Audience is who values are shown to or changed by.
+type Audience int
+
+const (
+	// AudiencePublic is anyone, including anonymous visitors.
+	AudiencePublic Audience = iota
+	// AudienceOwner is the user the values belong to.
+	AudienceOwner
+	// AudienceAdmin is an administrator.
+	AudienceAdmin
+)
+
+// CanView reports whether the audience may see values of the attribute.
+func CanView(def models.AttributeDefinition, audience Audience) bool {
+	switch def.Visibility {
+	case models.VisibilityPublic:
+		return true
+	case models.VisibilityPrivate:
+		return audience >= AudienceOwner
+	default:
+		return audience == AudienceAdmin
+	}
+}
+
+// CanEdit reports whether the audience may change values of the attribute.
+func CanEdit(def models.AttributeDefinition, audience Audience) bool {
+	switch def.EditableBy {
+	case models.EditableByUser:
+		return audience >= AudienceOwner
+	default:
+		return audience == AudienceAdmin
+	}
+}
+
+var (
+	keyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+	types      = []string{models.AttributeString, models.AttributeNumber, models.AttributeBoolean, models.AttributeDate, models.AttributeEnum}
+	visibility = []string{models.VisibilityPublic, models.VisibilityPrivate, models.VisibilityAdmin}
+	editableBy = []string{models.EditableByUser, models.EditableByAdmin}
+)
+
+// CheckDefinition fills in the default visibility (private) and editor (user) and returns an error
+// for each invalid field of the definition, including rules that do not apply to its type.
+func CheckDefinition(def *models.AttributeDefinition) []problem.FieldError {
+	var errors []problem.FieldError
+	fail := func(field, code, message string) {
+		errors = append(errors, problem.FieldError{Field: field, Code: code, Message: field + " " + message})
+	}
+	oneOf := func(field, value string, allowed []string) {
+		if !slices.Contains(allowed, value) {
+			fail(field, problem.FieldInvalid, "must be one of "+strings.Join(allowed, ", "))
+		}
+	}
+
+	if def.Visibility == "" {
+		def.Visibility = models.VisibilityPrivate
+	}
+	if def.EditableBy == "" {
+		def.EditableBy = models.EditableByUser
+	}
+
+	if def.Key == "" {
+		fail("key", problem.FieldRequired, "is required")
+	} else if !keyPattern.MatchString(def.Key) {
+		fail("key", problem.FieldInvalid, "must start with a lower-case letter followed by up to 63 lower-case letters, digits or underscores")
+	}
+	oneOf("type", def.Type, types)
+	oneOf("visibility", def.Visibility, visibility)
+	oneOf("editable_by", def.EditableBy, editableBy)
+
+	if def.Type != models.AttributeString {
+		for field, set := range map[string]bool{"min_length": def.MinLength != nil, "max_length": def.MaxLength != nil, "pattern": def.Pattern != ""} {
+			if set {
+				fail(field, problem.FieldInvalid, "only applies to string attributes")
+			}
+		}
+	}
+	if def.Type != models.AttributeNumber {
+		for field, set := range map[string]bool{"min": def.Min != nil, "max": def.Max != nil} {
+			if set {
+				fail(field, problem.FieldInvalid, "only applies to number attributes")
+			}
+		}
+	}
+
+	switch def.Type {
+	case models.AttributeString:
+		if def.MinLength != nil && *def.MinLength < 0 {
+			fail("min_length", problem.FieldInvalid, "must not be negative")
+		}
+		if def.MaxLength != nil && (*def.MaxLength < 1 || *def.MaxLength > MaxStringLength) {
+			fail("max_length", problem.FieldInvalid, fmt.Sprintf("must be between 1 and %d", MaxStringLength))
+		}
+		if def.MinLength != nil && def.MaxLength != nil && *def.MinLength > *def.MaxLength {
+			fail("min_length", problem.FieldInvalid, "must not be greater than max_length")
+		}
+		if def.Pattern != "" {
+			if _, err := regexp.Compile(def.Pattern); err != nil {
+				fail("pattern", problem.FieldInvalid, "must be a valid regular expression")
+			}
+		}
+	case models.AttributeNumber:
+		if def.Min != nil && def.Max != nil && *def.Min > *def.Max {
+			fail("min", problem.FieldInvalid, "must not be greater than max")
+		}
+	case models.AttributeEnum:
+		if len(def.Options) == 0 {
+			fail("options", problem.FieldRequired, "are required for enum attributes")
+		}
+		seen := map[string]bool{}
+		for _, option := range def.Options {
+			if option == "" || seen[option] {
+				fail("options", problem.FieldInvalid, "must be distinct, non-empty strings")
+				break
+			}
+			seen[option] = true
+		}
+	}
+	if def.Type != models.AttributeEnum && len(def.Options) > 0 {
+		fail("options", problem.FieldInvalid, "only apply to enum attributes")
+	}
+
+	sort.Slice(errors, func(i, j int) bool { return errors[i].Field < errors[j].Field })
+	return errors
+}
+
+// Normalize validates a JSON value against the attribute definition and returns it in the form it is stored in.
+// Strings are trimmed and normalised to Unicode NFC; an empty string is returned as is and means no value.
+// The error names the field as attributes.<key>.
+func Normalize(def models.AttributeDefinition, raw json.RawMessage) (string, *problem.FieldError) {
+	field := FieldName(def.Key)
+	fail := func(code, format string, args ...any) (string, *problem.FieldError) {
+		return "", &problem.FieldError{Field: field, Code: code, Message: field + " " + fmt.Sprintf(format, args...)}
+	}
+
+	switch def.Type {
+	case models.AttributeString, models.AttributeDate, models.AttributeEnum:
+		var value string
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return fail(problem.FieldInvalid, "must be a string")
+		}
+		value = norm.NFC.String(strings.TrimSpace(value))
+		if value == "" {
+			return "", nil
+		}
+
+		switch def.Type {
+		case models.AttributeString:
+			length := utf8.RuneCountInString(value)
+			if def.MinLength != nil && length < *def.MinLength {
+				return fail(problem.FieldTooShort, "must be at least %d characters", *def.MinLength)
+			}
+			maxLength := MaxStringLength
+			if def.MaxLength != nil {
+				maxLength = *def.MaxLength
+			}
+			if length > maxLength {
+				return fail(problem.FieldTooLong, "must be at most %d characters", maxLength)
+			}
+			if strings.IndexFunc(value, isDisallowedControl) >= 0 {
+				return fail(problem.FieldInvalid, "must not contain control characters")
+			}
+			if def.Pattern != "" {
+				pattern, err := regexp.Compile(`^(?:` + def.Pattern + `)$`)
+				if err != nil || !pattern.MatchString(value) {
+					return fail(problem.FieldInvalid, "does not have the expected format")
+				}
+			}
+		case models.AttributeDate:
+			if _, err := time.Parse(DateLayout, value); err != nil {
+				return fail(problem.FieldInvalid, "must be a date such as 2024-12-31")
+			}
+		case models.AttributeEnum:
+			if !slices.Contains(def.Options, value) {
+				return fail(problem.FieldInvalid, "must be one of %s", strings.Join(def.Options, ", "))
+			}
+		}
+		encoded, _ := json.Marshal(value)
+		return string(encoded), nil
+
+	case models.AttributeNumber:
+		decoder := json.NewDecoder(bytes.NewReader(raw))
+		decoder.UseNumber()
+		var value any
+		if err := decoder.Decode(&value); err != nil {
+			return fail(problem.FieldInvalid, "must be a number")
+		}
+		number, ok := value.(json.Number)
+		if !ok {
+			return fail(problem.FieldInvalid, "must be a number")
+		}
+		parsed, err := number.Float64()
+		if err != nil || math.IsInf(parsed, 0) || math.IsNaN(parsed) {
+			return fail(problem.FieldInvalid, "must be a number")
+		}
+		if def.Min != nil && parsed < *def.Min {
+			return fail(problem.FieldInvalid, "must be at least %s", formatNumber(*def.Min))
+		}
+		if def.Max != nil && parsed > *def.Max {
+			return fail(problem.FieldInvalid, "must be at most %s", formatNumber(*def.Max))
+		}
+		return formatNumber(parsed), nil
+
+	case models.AttributeBoolean:
+		var value bool
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return fail(problem.FieldInvalid, "must be true or false")
+		}
+		return strconv.FormatBool(value), nil
+	}
+	return fail(problem.FieldInvalid, "has an unknown type")
+}
+
+// Changes are the validated updates to a user's attribute values.
+type Changes struct {
+	Set     []models.UserAttribute // New or replaced values
+	Removed []uint                 // IDs of the attributes whose values are removed
+	Before  map[string]any         // Previous values of the changed attributes by field name, for auditing
+	After   map[string]any         // New values of the changed attributes by field name, for auditing
+}
+
+// Apply validates a patch of attribute values by key against the definitions and the user's stored values.
+// A null value or empty string removes the attribute. Attributes the audience cannot see are reported as unknown, and ones it
+// cannot change as read-only. Required attributes the audience can change must have a value afterwards.
+func Apply(defs []models.AttributeDefinition, stored []models.UserAttribute, patch map[string]json.RawMessage, audience Audience) (Changes, []problem.FieldError) {
+	changes := Changes{Before: map[string]any{}, After: map[string]any{}}
+	var errors []problem.FieldError
+
+	current := map[uint]string{}
+	for _, value := range stored {
+		current[value.AttributeId] = value.Value
+	}
+	byKey := map[string]models.AttributeDefinition{}
+	for _, def := range defs {
+		byKey[def.Key] = def
+	}
+
+	for key, raw := range patch {
+		field := FieldName(key)
+		def, ok := byKey[key]
+		if !ok || !CanView(def, audience) {
+			errors = append(errors, problem.FieldError{Field: field, Code: problem.FieldUnknown, Message: field + " is not a known attribute"})
+			continue
+		}
+		if !CanEdit(def, audience) {
+			errors = append(errors, problem.FieldError{Field: field, Code: problem.FieldReadOnly, Message: field + " can only be changed by administrators"})
+			continue
+		}
+
+		value := ""
+		if string(bytes.TrimSpace(raw)) != "null" {
+			normalized, err := Normalize(def, raw)
+			if err != nil {
+				errors = append(errors, *err)
+				continue
+			}
+			value = normalized
+		}
+
+		old, had := current[def.Id]
+		if value == "" {
+			if had {
+				changes.Removed = append(changes.Removed, def.Id)
+				changes.Before[field], changes.After[field] = decode(old), nil
+				delete(current, def.Id)
+			}
+			continue
+		}
+		if had && value == old {
+			continue
+		}
+		changes.Set = append(changes.Set, models.UserAttribute{AttributeId: def.Id, Value: value})
+		changes.Before[field], changes.After[field] = decode(old), decode(value)
+		current[def.Id] = value
+	}
+
+	for _, def := range defs {
+		if _, ok := current[def.Id]; !ok && def.Required && CanEdit(def, audience) && CanView(def, audience) {
+			field := FieldName(def.Key)
+			if !slices.ContainsFunc(errors, func(err problem.FieldError) bool { return err.Field == field }) {
+				errors = append(errors, problem.FieldError{Field: field, Code: problem.FieldRequired, Message: field + " is required"})
+			}
+		}
+	}
+
+	sort.Slice(errors, func(i, j int) bool { return errors[i].Field < errors[j].Field })
+	return changes, errors
+}
+
+// Values returns the stored values the audience may see, by attribute key. Values of attributes that
+// are no longer defined are skipped.
+func Values(defs []models.AttributeDefinition, stored []models.UserAttribute, audience Audience) map[string]json.RawMessage {
+	byId := map[uint]models.AttributeDefinition{}
+	for _, def := range defs {
+		byId[def.Id] = def
+	}
+	values := map[string]json.RawMessage{}
+	for _, value := range stored {
+		if def, ok := byId[value.AttributeId]; ok && CanView(def, audience) {
+			values[def.Key] = json.RawMessage(value.Value)
+		}
+	}
+	return values
+}
+
+// Visible returns the definitions the audience may see.
+func Visible(defs []models.AttributeDefinition, audience Audience) []models.AttributeDefinition {
+	visible := []models.AttributeDefinition{}
+	for _, def := range defs {
+		if CanView(def, audience) {
+			visible = append(visible, def)
+		}
+	}
+	return visible
+}
+
+// FieldName returns the name of the attribute in field errors and audit log changes.
+func FieldName(key string) string {
+	return "attributes." + key
+}
+
+// decode returns the stored value as a plain Go value, or nil if it is empty.
+func decode(value string) any {
+	var decoded any
+	if value == "" || json.Unmarshal([]byte(value), &decoded) != nil {
+		return nil
+	}
+	return decoded
+}
+
+// formatNumber formats a number in its shortest JSON representation.
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// isDisallowedControl reports whether r is a control character other than a line break or tab,
+// which are allowed so string attributes can hold several lines.
+func isDisallowedControl(r rune) bool {
+	return unicode.IsControl(r) && r != '\n' && r != '\r' && r != '\t'
+}
@@ -0,0 +1,265 @@
+package attributes
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/models"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/problem"
+)
+
+func intPtr(n int) *int           { return &n }
+func floatPtr(f float64) *float64 { return &f }
+
+// fieldCodes returns the code of each field error by field name.
+func fieldCodes(errors []problem.FieldError) map[string]string {
+	codes := map[string]string{}
+	for _, err := range errors {
+		codes[err.Field] = err.Code
+	}
+	return codes
+}
+
+func TestNormalize(t *testing.T) {
+	str := models.AttributeDefinition{Key: "nickname", Type: models.AttributeString, MinLength: intPtr(2), MaxLength: intPtr(5)}
+	code := models.AttributeDefinition{Key: "code", Type: models.AttributeString, Pattern: `[A-Z]{3}`}
+	number := models.AttributeDefinition{Key: "age", Type: models.AttributeNumber, Min: floatPtr(0), Max: floatPtr(150)}
+	boolean := models.AttributeDefinition{Key: "newsletter", Type: models.AttributeBoolean}
+	date := models.AttributeDefinition{Key: "birthday", Type: models.AttributeDate}
+	enum := models.AttributeDefinition{Key: "size", Type: models.AttributeEnum, Options: []string{"S", "M", "L"}}
+
+	tests := []struct {
+		name string
+		def  models.AttributeDefinition
+		raw  string
+		want string
+		code string
+	}{
+		{"string trimmed", str, `"  abc "`, `"abc"`, ""},
+		{"string NFC", str, `"café"`, `"café"`, ""},
+		{"string empty", str, `"   "`, "", ""},
+		{"string too short", str, `"a"`, "", problem.FieldTooShort},
+		{"string too long", str, `"abcdef"`, "", problem.FieldTooLong},
+		{"string length in characters", str, `"ééééé"`, `"ééééé"`, ""},
+		{"string control character", str, `"a\u0000b"`, "", problem.FieldInvalid},
+		{"string line break", str, `"a\nb"`, `"a\nb"`, ""},
+		{"string not a string", str, `12`, "", problem.FieldInvalid},
+		{"default max length", models.AttributeDefinition{Key: "s", Type: models.AttributeString}, `"` + strings.Repeat("a", MaxStringLength+1) + `"`, "", problem.FieldTooLong},
+		{"pattern matches", code, `"ABC"`, `"ABC"`, ""},
+		{"pattern anchored", code, `"ABCD"`, "", problem.FieldInvalid},
+		{"number", number, `42`, `42`, ""},
+		{"number shortest form", number, `42.50`, `42.5`, ""},
+		{"number exponent", number, `1e2`, `100`, ""},
+		{"number below min", number, `-1`, "", problem.FieldInvalid},
+		{"number above max", number, `151`, "", problem.FieldInvalid},
+		{"number as string", number, `"42"`, "", problem.FieldInvalid},
+		{"boolean true", boolean, `true`, `true`, ""},
+		{"boolean false", boolean, `false`, `false`, ""},
+		{"boolean as string", boolean, `"true"`, "", problem.FieldInvalid},
+		{"date", date, `"2024-02-29"`, `"2024-02-29"`, ""},
+		{"date invalid day", date, `"2023-02-29"`, "", problem.FieldInvalid},
+		{"date with time", date, `"2024-02-29T10:00:00Z"`, "", problem.FieldInvalid},
+		{"enum option", enum, `"M"`, `"M"`, ""},
+		{"enum case sensitive", enum, `"m"`, "", problem.FieldInvalid},
+		{"unknown type", models.AttributeDefinition{Key: "x", Type: "color"}, `"red"`, "", problem.FieldInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Normalize(tt.def, json.RawMessage(tt.raw))
+			if tt.code != "" {
+				if err == nil {
+					t.Fatalf("Normalize(%s) = %q, want error %s", tt.raw, got, tt.code)
+				}
+				if err.Code != tt.code {
+					t.Fatalf("Normalize(%s) error code %s, want %s", tt.raw, err.Code, tt.code)
+				}
+				if err.Field != FieldName(tt.def.Key) {
+					t.Fatalf("Normalize(%s) error field %s, want %s", tt.raw, err.Field, FieldName(tt.def.Key))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Normalize(%s) error: %s", tt.raw, err.Message)
+			}
+			if got != tt.want {
+				t.Fatalf("Normalize(%s) = %s, want %s", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	defs := []models.AttributeDefinition{
+		{Id: 1, Key: "department", Type: models.AttributeString, Required: true, Visibility: models.VisibilityPrivate, EditableBy: models.EditableByUser},
+		{Id: 2, Key: "employee_id", Type: models.AttributeString, Visibility: models.VisibilityPrivate, EditableBy: models.EditableByAdmin},
+		{Id: 3, Key: "clearance", Type: models.AttributeNumber, Visibility: models.VisibilityAdmin, EditableBy: models.EditableByAdmin},
+		{Id: 4, Key: "team", Type: models.AttributeString, Visibility: models.VisibilityPublic, EditableBy: models.EditableByUser},
+	}
+	stored := []models.UserAttribute{
+		{AttributeId: 1, Value: `"Sales"`},
+		{AttributeId: 4, Value: `"Blue"`},
+	}
+
+	tests := []struct {
+		name     string
+		patch    string
+		audience Audience
+		set      []models.UserAttribute
+		removed  []uint
+		before   map[string]any
+		after    map[string]any
+		errors   map[string]string
+	}{
+		{
+			name:     "change",
+			patch:    `{"department": " Marketing "}`,
+			audience: AudienceOwner,
+			set:      []models.UserAttribute{{AttributeId: 1, Value: `"Marketing"`}},
+			before:   map[string]any{"attributes.department": "Sales"},
+			after:    map[string]any{"attributes.department": "Marketing"},
+		},
+		{
+			name:     "unchanged value",
+			patch:    `{"department": "Sales"}`,
+			audience: AudienceOwner,
+		},
+		{
+			name:     "remove with null",
+			patch:    `{"team": null}`,
+			audience: AudienceOwner,
+			removed:  []uint{4},
+			before:   map[string]any{"attributes.team": "Blue"},
+			after:    map[string]any{"attributes.team": nil},
+		},
+		{
+			name:     "remove with empty string",
+			patch:    `{"team": ""}`,
+			audience: AudienceOwner,
+			removed:  []uint{4},
+			before:   map[string]any{"attributes.team": "Blue"},
+			after:    map[string]any{"attributes.team": nil},
+		},
+		{
+			name:     "remove unset value",
+			patch:    `{"employee_id": null}`,
+			audience: AudienceAdmin,
+		},
+		{
+			name:     "remove required",
+			patch:    `{"department": null}`,
+			audience: AudienceOwner,
+			removed:  []uint{1},
+			before:   map[string]any{"attributes.department": "Sales"},
+			after:    map[string]any{"attributes.department": nil},
+			errors:   map[string]string{"attributes.department": problem.FieldRequired},
+		},
+		{
+			name:     "read-only for the owner",
+			patch:    `{"employee_id": "E1"}`,
+			audience: AudienceOwner,
+			errors:   map[string]string{"attributes.employee_id": problem.FieldReadOnly},
+		},
+		{
+			name:     "hidden from the owner",
+			patch:    `{"clearance": 3}`,
+			audience: AudienceOwner,
+			errors:   map[string]string{"attributes.clearance": problem.FieldUnknown},
+		},
+		{
+			name:     "undefined",
+			patch:    `{"shoe_size": 42}`,
+			audience: AudienceAdmin,
+			errors:   map[string]string{"attributes.shoe_size": problem.FieldUnknown},
+		},
+		{
+			name:     "administrator",
+			patch:    `{"employee_id": "E1", "clearance": 3}`,
+			audience: AudienceAdmin,
+			set:      []models.UserAttribute{{AttributeId: 2, Value: `"E1"`}, {AttributeId: 3, Value: `3`}},
+			before:   map[string]any{"attributes.employee_id": nil, "attributes.clearance": nil},
+			after:    map[string]any{"attributes.employee_id": "E1", "attributes.clearance": float64(3)},
+		},
+		{
+			name:     "invalid value",
+			patch:    `{"clearance": "top"}`,
+			audience: AudienceAdmin,
+			errors:   map[string]string{"attributes.clearance": problem.FieldInvalid},
+		},
+		{
+			name:     "public audience",
+			patch:    `{"team": "Red"}`,
+			audience: AudiencePublic,
+			errors:   map[string]string{"attributes.team": problem.FieldReadOnly},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var patch map[string]json.RawMessage
+			if err := json.Unmarshal([]byte(tt.patch), &patch); err != nil {
+				t.Fatal(err)
+			}
+			changes, errors := Apply(defs, stored, patch, tt.audience)
+
+			if codes := fieldCodes(errors); len(codes) != len(tt.errors) || (len(codes) > 0 && !reflect.DeepEqual(codes, tt.errors)) {
+				t.Fatalf("errors = %v, want %v", codes, tt.errors)
+			}
+			if len(tt.errors) > 0 && tt.set == nil && tt.removed == nil {
+				return
+			}
+
+			set := changes.Set
+			if len(set) > 1 && set[0].AttributeId > set[1].AttributeId {
+				set[0], set[1] = set[1], set[0]
+			}
+			if len(set) != len(tt.set) || (len(set) > 0 && !reflect.DeepEqual(set, tt.set)) {
+				t.Errorf("Set = %v, want %v", set, tt.set)
+			}
+			if len(changes.Removed) != len(tt.removed) || (len(tt.removed) > 0 && !reflect.DeepEqual(changes.Removed, tt.removed)) {
+				t.Errorf("Removed = %v, want %v", changes.Removed, tt.removed)
+			}
+			if len(changes.Before) != len(tt.before) || (len(tt.before) > 0 && !reflect.DeepEqual(changes.Before, tt.before)) {
+				t.Errorf("Before = %v, want %v", changes.Before, tt.before)
+			}
+			if len(changes.After) != len(tt.after) || (len(tt.after) > 0 && !reflect.DeepEqual(changes.After, tt.after)) {
+				t.Errorf("After = %v, want %v", changes.After, tt.after)
+			}
+		})
+	}
+}
+
+func TestCheckDefinition(t *testing.T) {
+	tests := []struct {
+		name   string
+		def    models.AttributeDefinition
+		errors map[string]string
+	}{
+		{"defaults", models.AttributeDefinition{Key: "department", Type: models.AttributeString}, nil},
+		{"missing key", models.AttributeDefinition{Type: models.AttributeString}, map[string]string{"key": problem.FieldRequired}},
+		{"invalid key", models.AttributeDefinition{Key: "Department", Type: models.AttributeString}, map[string]string{"key": problem.FieldInvalid}},
+		{"unknown type", models.AttributeDefinition{Key: "a", Type: "color"}, map[string]string{"type": problem.FieldInvalid}},
+		{"rule of another type", models.AttributeDefinition{Key: "a", Type: models.AttributeNumber, MaxLength: intPtr(5)}, map[string]string{"max_length": problem.FieldInvalid}},
+		{"max length too large", models.AttributeDefinition{Key: "a", Type: models.AttributeString, MaxLength: intPtr(MaxStringLength + 1)}, map[string]string{"max_length": problem.FieldInvalid}},
+		{"min above max", models.AttributeDefinition{Key: "a", Type: models.AttributeNumber, Min: floatPtr(2), Max: floatPtr(1)}, map[string]string{"min": problem.FieldInvalid}},
+		{"invalid pattern", models.AttributeDefinition{Key: "a", Type: models.AttributeString, Pattern: "("}, map[string]string{"pattern": problem.FieldInvalid}},
+		{"enum without options", models.AttributeDefinition{Key: "a", Type: models.AttributeEnum}, map[string]string{"options": problem.FieldRequired}},
+		{"duplicate options", models.AttributeDefinition{Key: "a", Type: models.AttributeEnum, Options: []string{"x", "x"}}, map[string]string{"options": problem.FieldInvalid}},
+		{"invalid visibility", models.AttributeDefinition{Key: "a", Type: models.AttributeBoolean, Visibility: "friends"}, map[string]string{"visibility": problem.FieldInvalid}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			def := tt.def
+			codes := fieldCodes(CheckDefinition(&def))
+			if len(codes) != len(tt.errors) || (len(codes) > 0 && !reflect.DeepEqual(codes, tt.errors)) {
+				t.Fatalf("errors = %v, want %v", codes, tt.errors)
+			}
+			if tt.def.Visibility == "" && def.Visibility != models.VisibilityPrivate {
+				t.Errorf("default visibility = %q, want %q", def.Visibility, models.VisibilityPrivate)
+			}
+			if tt.def.EditableBy == "" && def.EditableBy != models.EditableByUser {
+				t.Errorf("default editor = %q, want %q", def.EditableBy, models.EditableByUser)
+			}
+		})
+	}
+}
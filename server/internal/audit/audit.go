@@ -0,0 +1,129 @@
+// Package audit records account events (registration, logins, profile changes, logouts and deletions)
+// in an append-only table and provides queries over the recorded history.
+package audit
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/logging"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/models"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/repository"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/utils"
+	"github.com/gofiber/fiber/v3"
+)
+
+// Actions recorded in the audit log.
+const (
+	ActionRegister          = "register"
+	ActionLoginSuccess      = "login.success"
+	ActionLoginFailure      = "login.failure"
+	ActionProfileUpdate     = "profile.update"
+	ActionPasswordChange    = "password.change"
+	ActionAvatarUpdate      = "avatar.update"
+	ActionAvatarDelete      = "avatar.delete"
+	ActionAttributeCreate   = "attribute.create"
+	ActionAttributeUpdate   = "attribute.update"
+	ActionAttributeDelete   = "attribute.delete"
+	ActionPreferencesUpdate = "preferences.update"
+	ActionSessionRevoke     = "session.revoke"
+	ActionLogout            = "logout"
+	ActionDelete            = "account.delete"
+)
+
+// redacted replaces the value of secret fields in recorded diffs.
+const redacted = "[REDACTED]"
+
+// secretFields lists the field names whose values must never be written to the audit log.
+var secretFields = map[string]bool{
+	"password": true,
+	"token":    true,
+	"secret":   true,
+}
+
+// Change describes the old and new value of a single field.
+type Change struct {
+	Old any `json:"old"`
+	New any `json:"new"`
+}
+
+// Entry describes an event to be recorded.
+type Entry struct {
+	Action   string
+	ActorId  *uint
+	TargetId *uint
+	Detail   string
+	Changes  map[string]Change
+}
+
+// Diff compares two field maps of comparable values and returns the fields whose values differ.
+// Values of secret fields are replaced with a redaction marker.
+func Diff(before, after map[string]any) map[string]Change {
+	changes := map[string]Change{}
+	for field, newValue := range after {
+		oldValue := before[field]
+		if oldValue == newValue {
+			continue
+		}
+		if isSecret(field) {
+			changes[field] = Change{Old: redacted, New: redacted}
+			continue
+		}
+		changes[field] = Change{Old: oldValue, New: newValue}
+	}
+	return changes
+}
+
+// isSecret reports whether the named field holds a secret value.
+func isSecret(field string) bool {
+	return secretFields[strings.ToLower(field)]
+}
+
+// Recorder writes audit log entries for HTTP requests.
+type Recorder struct {
+	repo repository.AuditRepository
+}
+
+// NewRecorder returns a Recorder that appends entries to the given repository.
+func NewRecorder(repo repository.AuditRepository) *Recorder {
+	return &Recorder{repo: repo}
+}
+
+// Record writes an audit log entry for the current request.
+// Failures are logged rather than returned so that auditing never breaks the request itself.
+func (r *Recorder) Record(c fiber.Ctx, entry Entry) {
+	logEntry := models.AuditLog{
+		ActorId:   entry.ActorId,
+		TargetId:  entry.TargetId,
+		Action:    entry.Action,
+		IP:        utils.Truncate(c.IP(), models.MaxIPLength),
+		UserAgent: utils.Truncate(c.Get(fiber.HeaderUserAgent), models.MaxUserAgentLength),
+		Detail:    entry.Detail,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	if len(entry.Changes) > 0 {
+		// Redact again here so callers building changes by hand cannot leak secrets
+		for field := range entry.Changes {
+			if isSecret(field) {
+				entry.Changes[field] = Change{Old: redacted, New: redacted}
+			}
+		}
+		encoded, err := json.Marshal(entry.Changes)
+		if err != nil {
+			logging.FromContext(c.UserContext()).Error("Could not encode audit changes", "action", entry.Action, "error", err)
+		} else {
+			logEntry.Changes = encoded
+		}
+	}
+
+	if err := r.repo.Append(c.UserContext(), &logEntry); err != nil {
+		logging.FromContext(c.UserContext()).Error("Could not write audit log entry", "action", entry.Action, "error", err)
+	}
+}
+
+// List returns the audit log entries matching the filter, newest first, together with the total number of matches.
+func (r *Recorder) List(c fiber.Ctx, filter repository.AuditFilter) ([]models.AuditLog, int64, error) {
+	return r.repo.List(c.UserContext(), filter)
+}
@@ -0,0 +1,83 @@
+package audit
+
+import (
+	"context"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/models"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/repository"
+	"github.com/gofiber/fiber/v3"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name          string
+		before, after map[string]any
+		want          map[string]Change
+	}{
+		{"unchanged", map[string]any{"name": "Ann"}, map[string]any{"name": "Ann"}, map[string]Change{}},
+		{"changed", map[string]any{"name": "Ann"}, map[string]any{"name": "Annie"}, map[string]Change{"name": {Old: "Ann", New: "Annie"}}},
+		{"added", map[string]any{}, map[string]any{"bio": "Hi"}, map[string]Change{"bio": {Old: nil, New: "Hi"}}},
+		{"removed field ignored", map[string]any{"bio": "Hi"}, map[string]any{}, map[string]Change{}},
+		{"secret redacted", map[string]any{"password": "old"}, map[string]any{"password": "new"}, map[string]Change{"password": {Old: redacted, New: redacted}}},
+		{"secret case-insensitive", map[string]any{"Token": "a"}, map[string]any{"Token": "b"}, map[string]Change{"Token": {Old: redacted, New: redacted}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Diff(tt.before, tt.after); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Diff = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecord(t *testing.T) {
+	repo := repository.NewMemoryAuditRepository()
+	recorder := NewRecorder(repo)
+
+	app := fiber.New()
+	app.Post("/", func(c fiber.Ctx) error {
+		recorder.Record(c, Entry{
+			Action:  ActionProfileUpdate,
+			Changes: map[string]Change{"secret": {Old: "a", New: "b"}},
+		})
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+
+	tests := []struct {
+		name          string
+		userAgent     string
+		wantUserAgent string
+	}{
+		{"short", "curl/8.0", "curl/8.0"},
+		{"too long", strings.Repeat("a", models.MaxUserAgentLength+100), strings.Repeat("a", models.MaxUserAgentLength)},
+		{"too long multi-byte", strings.Repeat("é", models.MaxUserAgentLength), strings.Repeat("é", models.MaxUserAgentLength/2)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(fiber.MethodPost, "/", nil)
+			req.Header.Set(fiber.HeaderUserAgent, tt.userAgent)
+			if _, err := app.Test(req); err != nil {
+				t.Fatal(err)
+			}
+
+			entries, _, err := repo.List(context.Background(), repository.AuditFilter{Limit: 1})
+			if err != nil || len(entries) != 1 {
+				t.Fatalf("List: %d entries, %v", len(entries), err)
+			}
+			entry := entries[0]
+			if entry.UserAgent != tt.wantUserAgent {
+				t.Errorf("UserAgent has %d bytes, want %d", len(entry.UserAgent), len(tt.wantUserAgent))
+			}
+			if len(entry.IP) > models.MaxIPLength {
+				t.Errorf("IP has %d bytes, more than the column holds", len(entry.IP))
+			}
+			if string(entry.Changes) != `{"secret":{"old":"[REDACTED]","new":"[REDACTED]"}}` {
+				t.Errorf("Changes = %s, want the secret redacted", entry.Changes)
+			}
+		})
+	}
+}
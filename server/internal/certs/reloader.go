@@ -0,0 +1,146 @@
+// Package certs loads the server's TLS certificate and client CA bundle and reloads them when the files change,
+// so certificates can be renewed without restarting the server.
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// Reloader holds the current certificate and client CAs and replaces them when their files change.
+type Reloader struct {
+	certFile     string
+	keyFile      string
+	clientCAFile string
+
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+	stamp     string
+}
+
+// Load reads the certificate, its key and the optional client CA bundle ("" disables client certificates).
+func Load(certFile, keyFile, clientCAFile string) (*Reloader, error) {
+	r := &Reloader{certFile: certFile, keyFile: keyFile, clientCAFile: clientCAFile}
+	if _, err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload reads the files again if any of them changed since the last load and reports whether they did.
+// On error the certificate in use is kept.
+func (r *Reloader) Reload() (bool, error) {
+	stamp, err := r.fileStamp()
+	if err != nil {
+		return false, err
+	}
+
+	r.mu.RLock()
+	unchanged := stamp == r.stamp
+	r.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return false, fmt.Errorf("load key pair: %w", err)
+	}
+
+	var clientCAs *x509.CertPool
+	if r.clientCAFile != "" {
+		pem, err := os.ReadFile(r.clientCAFile)
+		if err != nil {
+			return false, err
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return false, errors.New(r.clientCAFile + ": no PEM certificates found")
+		}
+	}
+
+	r.mu.Lock()
+	r.cert, r.clientCAs, r.stamp = &cert, clientCAs, stamp
+	r.mu.Unlock()
+	return true, nil
+}
+
+// fileStamp summarises the modification time and size of every file, so changes can be detected without reading them.
+func (r *Reloader) fileStamp() (string, error) {
+	var stamp string
+	for _, path := range []string{r.certFile, r.keyFile, r.clientCAFile} {
+		if path == "" {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", err
+		}
+		stamp += fmt.Sprintf("%s:%d:%d;", path, info.ModTime().UnixNano(), info.Size())
+	}
+	return stamp, nil
+}
+
+// GetCertificate returns the current certificate. It is used as tls.Config.GetCertificate.
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// TLSConfig returns a server TLS configuration that always uses the current certificate and client CAs.
+// clientAuth selects whether client certificates are requested; it only applies when a client CA bundle is loaded.
+func (r *Reloader) TLSConfig(minVersion uint16, clientAuth tls.ClientAuthType) *tls.Config {
+	config := &tls.Config{
+		MinVersion:     minVersion,
+		GetCertificate: r.GetCertificate,
+	}
+	if r.clientCAFile == "" {
+		return config
+	}
+
+	// Build the configuration per handshake so a reloaded CA bundle takes effect immediately
+	config.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		r.mu.RLock()
+		defer r.mu.RUnlock()
+		return &tls.Config{
+			MinVersion:     minVersion,
+			GetCertificate: r.GetCertificate,
+			ClientAuth:     clientAuth,
+			ClientCAs:      r.clientCAs,
+		}, nil
+	}
+	return config
+}
+
+// Watch reloads the files every interval until the context is cancelled, logging renewals and failures.
+func (r *Reloader) Watch(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			changed, err := r.Reload()
+			if err != nil {
+				slog.Error("Could not reload TLS certificate", "error", err)
+			} else if changed {
+				slog.Info("TLS certificate was reloaded", "file", r.certFile)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,558 @@
+// Package config loads the server configuration once at startup and validates it.
+//
+// Values are read, in increasing order of precedence, from the built-in defaults,
+// an optional YAML or TOML file named by CONFIG_FILE, a .env file in the working directory,
+// and the process environment. Every problem found is collected into a single report
+// so a misconfigured deployment can be fixed in one pass.
+package config
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// Config is the complete server configuration.
+type Config struct {
+	Server   ServerConfig   `yaml:"server" toml:"server"`
+	Database DatabaseConfig `yaml:"database" toml:"database"`
+	Auth     AuthConfig     `yaml:"auth" toml:"auth"`
+	CSRF     CSRFConfig     `yaml:"csrf" toml:"csrf"`
+	Headers  HeadersConfig  `yaml:"headers" toml:"headers"`
+	Metrics  MetricsConfig  `yaml:"metrics" toml:"metrics"`
+	Tracing  TracingConfig  `yaml:"tracing" toml:"tracing"`
+	Logging  LoggingConfig  `yaml:"logging" toml:"logging"`
+	Secrets  SecretsConfig  `yaml:"secrets" toml:"secrets"`
+	Storage  StorageConfig  `yaml:"storage" toml:"storage"`
+	Avatars  AvatarConfig   `yaml:"avatars" toml:"avatars"`
+}
+
+// ServerConfig configures the HTTP server.
+type ServerConfig struct {
+	Port           string        `env:"SERVER_PORT" yaml:"port" toml:"port"`                           // Listen address, e.g. ":8000"
+	AllowedOrigins []string      `env:"ALLOWED_ORIGINS" yaml:"allowed_origins" toml:"allowed_origins"` // CORS origins allowed to send credentials
+	ShutdownDelay  time.Duration `env:"SHUTDOWN_DELAY" yaml:"shutdown_delay" toml:"shutdown_delay"`    // How long /readyz fails before the server stops accepting requests
+	TLS            TLSConfig     `yaml:"tls" toml:"tls"`                                               // HTTPS serving, enabled by setting a certificate
+}
+
+// TLSConfig configures HTTPS serving. TLS is enabled when a certificate and key are configured.
+type TLSConfig struct {
+	CertFile       string        `env:"TLS_CERT_FILE" yaml:"cert_file" toml:"cert_file"`                   // PEM certificate chain, reloaded when it changes
+	KeyFile        string        `env:"TLS_KEY_FILE" yaml:"key_file" toml:"key_file"`                      // PEM private key, reloaded when it changes
+	MinVersion     string        `env:"TLS_MIN_VERSION" yaml:"min_version" toml:"min_version"`             // "1.2" or "1.3"
+	ClientCAFile   string        `env:"TLS_CLIENT_CA_FILE" yaml:"client_ca_file" toml:"client_ca_file"`    // PEM bundle of CAs for client certificates (mutual TLS)
+	ClientAuth     string        `env:"TLS_CLIENT_AUTH" yaml:"client_auth" toml:"client_auth"`             // "require" or "optional" (verify only if presented)
+	ReloadInterval time.Duration `env:"TLS_RELOAD_INTERVAL" yaml:"reload_interval" toml:"reload_interval"` // How often the files are checked for changes (0 = never)
+	RedirectPort   string        `env:"TLS_REDIRECT_PORT" yaml:"redirect_port" toml:"redirect_port"`       // Plain HTTP listen address redirecting to HTTPS, e.g. ":80"
+
+	HSTSMaxAge            time.Duration `env:"HSTS_MAX_AGE" yaml:"hsts_max_age" toml:"hsts_max_age"`                                  // Strict-Transport-Security max-age (0 = no header)
+	HSTSIncludeSubdomains bool          `env:"HSTS_INCLUDE_SUBDOMAINS" yaml:"hsts_include_subdomains" toml:"hsts_include_subdomains"` // Apply HSTS to all subdomains
+	HSTSPreload           bool          `env:"HSTS_PRELOAD" yaml:"hsts_preload" toml:"hsts_preload"`                                  // Request inclusion in browser preload lists
+}
+
+// Enabled reports whether the server listens over HTTPS.
+func (t TLSConfig) Enabled() bool {
+	return t.CertFile != "" || t.KeyFile != ""
+}
+
+// MinTLSVersion returns the configured minimum protocol version.
+func (t TLSConfig) MinTLSVersion() uint16 {
+	if t.MinVersion == "1.3" {
+		return tls.VersionTLS13
+	}
+	return tls.VersionTLS12
+}
+
+// ClientAuthType returns how client certificates are verified when a client CA bundle is configured.
+func (t TLSConfig) ClientAuthType() tls.ClientAuthType {
+	if strings.EqualFold(t.ClientAuth, "optional") {
+		return tls.VerifyClientCertIfGiven
+	}
+	return tls.RequireAndVerifyClientCert
+}
+
+// HSTSHeader returns the value of the Strict-Transport-Security header, or "" if none should be sent.
+func (t TLSConfig) HSTSHeader() string {
+	if !t.Enabled() || t.HSTSMaxAge <= 0 {
+		return ""
+	}
+	value := fmt.Sprintf("max-age=%d", int64(t.HSTSMaxAge.Seconds()))
+	if t.HSTSIncludeSubdomains {
+		value += "; includeSubDomains"
+	}
+	if t.HSTSPreload {
+		value += "; preload"
+	}
+	return value
+}
+
+// DatabaseConfig configures the database connection and pool.
+type DatabaseConfig struct {
+	Driver   string `env:"DB_DRIVER" yaml:"driver" toml:"driver"`       // mysql, postgres or sqlite
+	URL      string `env:"DATABASE_URL" yaml:"url" toml:"url"`          // Full connection URL, replacing the fields below
+	Username string `env:"DB_USERNAME" yaml:"username" toml:"username"` // Database user
+	Password string `env:"DB_PASSWORD" yaml:"password" toml:"password"` // Database password
+	Address  string `env:"DB_IP" yaml:"address" toml:"address"`         // Host and port, e.g. "127.0.0.1:3306"
+	Name     string `env:"DB_NAME" yaml:"name" toml:"name"`             // Database name, or the file path for SQLite
+
+	MaxOpenConns    int           `env:"DB_MAX_OPEN_CONNS" yaml:"max_open_conns" toml:"max_open_conns"`             // Maximum open connections (0 = unlimited)
+	MaxIdleConns    int           `env:"DB_MAX_IDLE_CONNS" yaml:"max_idle_conns" toml:"max_idle_conns"`             // Maximum idle connections kept in the pool
+	ConnMaxLifetime time.Duration `env:"DB_CONN_MAX_LIFETIME" yaml:"conn_max_lifetime" toml:"conn_max_lifetime"`    // Maximum time a connection may be reused
+	ConnMaxIdleTime time.Duration `env:"DB_CONN_MAX_IDLE_TIME" yaml:"conn_max_idle_time" toml:"conn_max_idle_time"` // Maximum time a connection may sit idle
+	ConnectAttempts int           `env:"DB_CONNECT_ATTEMPTS" yaml:"connect_attempts" toml:"connect_attempts"`       // Attempts to connect on startup before giving up
+	ConnectBackoff  time.Duration `env:"DB_CONNECT_BACKOFF" yaml:"connect_backoff" toml:"connect_backoff"`          // Delay before the first retry, doubled after each failure
+	PingInterval    time.Duration `env:"DB_PING_INTERVAL" yaml:"ping_interval" toml:"ping_interval"`                // How often the health check pings the database
+	QueryTimeout    time.Duration `env:"DB_QUERY_TIMEOUT" yaml:"query_timeout" toml:"query_timeout"`                // Maximum duration of a single query (0 = no limit)
+}
+
+// AuthConfig configures authentication.
+type AuthConfig struct {
+	JWTSecretKey string       `env:"JWT_SECRET_KEY" yaml:"jwt_secret_key" toml:"jwt_secret_key"` // Key used to sign and verify JWTs
+	Cookie       CookieConfig `yaml:"cookie" toml:"cookie"`                                      // Attributes of the authentication cookie
+}
+
+// CookieConfig configures the cookie carrying the JWT. The defaults are secure for deployments behind HTTPS.
+type CookieConfig struct {
+	Name        string `env:"COOKIE_NAME" yaml:"name" toml:"name"`                      // Cookie name, without the __Host- prefix
+	Domain      string `env:"COOKIE_DOMAIN" yaml:"domain" toml:"domain"`                // Domain attribute (empty = host-only cookie)
+	Path        string `env:"COOKIE_PATH" yaml:"path" toml:"path"`                      // Path attribute
+	Secure      bool   `env:"COOKIE_SECURE" yaml:"secure" toml:"secure"`                // Only send the cookie over HTTPS
+	SameSite    string `env:"COOKIE_SAMESITE" yaml:"same_site" toml:"same_site"`        // Lax, Strict or None
+	HostPrefix  bool   `env:"COOKIE_HOST_PREFIX" yaml:"host_prefix" toml:"host_prefix"` // Prefix the name with __Host- to lock the cookie to this host
+	Partitioned bool   `env:"COOKIE_PARTITIONED" yaml:"partitioned" toml:"partitioned"` // Store the cookie in a partitioned (CHIPS) cookie jar
+}
+
+// FullName returns the cookie name including the __Host- prefix when enabled.
+func (c CookieConfig) FullName() string {
+	if c.HostPrefix {
+		return "__Host-" + c.Name
+	}
+	return c.Name
+}
+
+// CSRFConfig configures the double-submit CSRF protection of state-changing requests.
+type CSRFConfig struct {
+	Enabled    bool   `env:"CSRF_ENABLED" yaml:"enabled" toml:"enabled"`             // Reject cookie-authenticated mutations without a matching token
+	CookieName string `env:"CSRF_COOKIE_NAME" yaml:"cookie_name" toml:"cookie_name"` // Cookie holding the token, without the __Host- prefix
+	HeaderName string `env:"CSRF_HEADER_NAME" yaml:"header_name" toml:"header_name"` // Request header the client echoes the token in
+}
+
+// SecurityHeaders holds the values of the security headers sent with a response. Empty values are
+// inherited from the defaults when used as a route override; "-" omits the header.
+type SecurityHeaders struct {
+	ContentSecurityPolicy     string `env:"CSP" yaml:"content_security_policy" toml:"content_security_policy"`                                    // Content-Security-Policy, without frame-ancestors
+	FrameAncestors            string `env:"CSP_FRAME_ANCESTORS" yaml:"frame_ancestors" toml:"frame_ancestors"`                                    // CSP frame-ancestors sources, also mapped to X-Frame-Options
+	ContentTypeOptions        string `env:"X_CONTENT_TYPE_OPTIONS" yaml:"content_type_options" toml:"content_type_options"`                       // X-Content-Type-Options
+	ReferrerPolicy            string `env:"REFERRER_POLICY" yaml:"referrer_policy" toml:"referrer_policy"`                                        // Referrer-Policy
+	PermissionsPolicy         string `env:"PERMISSIONS_POLICY" yaml:"permissions_policy" toml:"permissions_policy"`                               // Permissions-Policy
+	CrossOriginOpenerPolicy   string `env:"CROSS_ORIGIN_OPENER_POLICY" yaml:"cross_origin_opener_policy" toml:"cross_origin_opener_policy"`       // Cross-Origin-Opener-Policy
+	CrossOriginEmbedderPolicy string `env:"CROSS_ORIGIN_EMBEDDER_POLICY" yaml:"cross_origin_embedder_policy" toml:"cross_origin_embedder_policy"` // Cross-Origin-Embedder-Policy
+	CrossOriginResourcePolicy string `env:"CROSS_ORIGIN_RESOURCE_POLICY" yaml:"cross_origin_resource_policy" toml:"cross_origin_resource_policy"` // Cross-Origin-Resource-Policy
+}
+
+// HeadersConfig configures the security headers sent with every response. The defaults suit a JSON API
+// that is never rendered or framed by a browser.
+type HeadersConfig struct {
+	SecurityHeaders `yaml:",inline"`
+
+	CSPReportOnly bool                       `env:"CSP_REPORT_ONLY" yaml:"csp_report_only" toml:"csp_report_only"` // Send Content-Security-Policy-Report-Only instead of enforcing the policy
+	CSPReportURI  string                     `env:"CSP_REPORT_URI" yaml:"csp_report_uri" toml:"csp_report_uri"`    // Where browsers send violation reports ("" = no reports)
+	Routes        map[string]SecurityHeaders `yaml:"routes" toml:"routes"`                                         // Overrides by route prefix, e.g. "/api/admin"; the longest match wins
+}
+
+// MetricsConfig configures the Prometheus metrics endpoint.
+type MetricsConfig struct {
+	Enabled bool   `env:"METRICS_ENABLED" yaml:"enabled" toml:"enabled"` // Collect and serve metrics
+	Path    string `env:"METRICS_PATH" yaml:"path" toml:"path"`          // Path the metrics are served on
+}
+
+// TracingConfig configures OpenTelemetry tracing.
+type TracingConfig struct {
+	Exporter     string `env:"TRACING_EXPORTER" yaml:"exporter" toml:"exporter"`                // "" (disabled), "otlp" or "stdout"
+	OTLPEndpoint string `env:"TRACING_OTLP_ENDPOINT" yaml:"otlp_endpoint" toml:"otlp_endpoint"` // OTLP/HTTP collector URL; http:// disables TLS
+	ServiceName  string `env:"TRACING_SERVICE_NAME" yaml:"service_name" toml:"service_name"`    // service.name resource attribute
+}
+
+// LoggingConfig configures the structured log output.
+type LoggingConfig struct {
+	Level  string `env:"LOG_LEVEL" yaml:"level" toml:"level"`    // debug, info, warn or error
+	Format string `env:"LOG_FORMAT" yaml:"format" toml:"format"` // json or text
+}
+
+// SecretsConfig selects where secrets such as JWT_SECRET_KEY and DB_PASSWORD are read from and how often they are reloaded.
+type SecretsConfig struct {
+	Provider           string        `env:"SECRETS_PROVIDER" yaml:"provider" toml:"provider"`                                  // "" (configuration only), "file" or "keystore"
+	Dir                string        `env:"SECRETS_DIR" yaml:"dir" toml:"dir"`                                                 // Directory holding one file per secret, for the file provider
+	KeystorePath       string        `env:"SECRETS_KEYSTORE" yaml:"keystore" toml:"keystore"`                                  // Encrypted keystore file, for the keystore provider
+	KeystorePassphrase string        `env:"SECRETS_KEYSTORE_PASSPHRASE" yaml:"keystore_passphrase" toml:"keystore_passphrase"` // Passphrase that decrypts the keystore
+	ReloadInterval     time.Duration `env:"SECRETS_RELOAD_INTERVAL" yaml:"reload_interval" toml:"reload_interval"`             // How often secrets are re-read to pick up rotations (0 = never)
+	PreviousTTL        time.Duration `env:"SECRETS_PREVIOUS_TTL" yaml:"previous_ttl" toml:"previous_ttl"`                      // How long after a rotation tokens signed with the previous JWT key are accepted
+}
+
+// StorageConfig selects where uploaded files such as avatars are stored.
+type StorageConfig struct {
+	Backend   string   `env:"STORAGE_BACKEND" yaml:"backend" toml:"backend"`          // "local" or "s3"
+	Dir       string   `env:"STORAGE_DIR" yaml:"dir" toml:"dir"`                      // Directory the local backend writes to
+	PublicURL string   `env:"STORAGE_PUBLIC_URL" yaml:"public_url" toml:"public_url"` // URL prefix of stored files; for the local backend, a path this server serves them on
+	S3        S3Config `yaml:"s3" toml:"s3"`                                          // S3-compatible object storage
+}
+
+// S3Config configures an S3-compatible storage backend such as AWS S3 or MinIO.
+type S3Config struct {
+	Endpoint        string `env:"S3_ENDPOINT" yaml:"endpoint" toml:"endpoint"`                            // Service URL, e.g. https://s3.eu-central-1.amazonaws.com or http://localhost:9000
+	Region          string `env:"S3_REGION" yaml:"region" toml:"region"`                                  // Signing region
+	Bucket          string `env:"S3_BUCKET" yaml:"bucket" toml:"bucket"`                                  // Bucket the files are stored in
+	AccessKeyID     string `env:"S3_ACCESS_KEY_ID" yaml:"access_key_id" toml:"access_key_id"`             // Access key
+	SecretAccessKey string `env:"S3_SECRET_ACCESS_KEY" yaml:"secret_access_key" toml:"secret_access_key"` // Secret key
+	PathStyle       bool   `env:"S3_PATH_STYLE" yaml:"path_style" toml:"path_style"`                      // Address the bucket in the path (MinIO) instead of the host name
+}
+
+// AvatarConfig limits avatar uploads and sets the generated thumbnail sizes.
+type AvatarConfig struct {
+	MaxBytes     int   `env:"AVATAR_MAX_BYTES" yaml:"max_bytes" toml:"max_bytes"`             // Largest accepted upload
+	MaxDimension int   `env:"AVATAR_MAX_DIMENSION" yaml:"max_dimension" toml:"max_dimension"` // Largest accepted width or height in pixels
+	Sizes        []int `env:"AVATAR_SIZES" yaml:"sizes" toml:"sizes"`                         // Square thumbnail sizes in pixels
+}
+
+// Default returns the configuration used for any value that is not set elsewhere.
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			Port:          ":8000",
+			ShutdownDelay: 5 * time.Second,
+			TLS: TLSConfig{
+				MinVersion:     "1.2",
+				ClientAuth:     "require",
+				ReloadInterval: time.Minute,
+				HSTSMaxAge:     365 * 24 * time.Hour,
+			},
+		},
+		Database: DatabaseConfig{
+			Driver:          "mysql",
+			MaxOpenConns:    25,
+			MaxIdleConns:    25,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnMaxIdleTime: 5 * time.Minute,
+			ConnectAttempts: 5,
+			ConnectBackoff:  time.Second,
+			PingInterval:    30 * time.Second,
+			QueryTimeout:    5 * time.Second,
+		},
+		Auth: AuthConfig{
+			Cookie: CookieConfig{
+				Name:     "jwt",
+				Path:     "/",
+				Secure:   true,
+				SameSite: "Lax",
+			},
+		},
+		CSRF: CSRFConfig{
+			Enabled:    true,
+			CookieName: "csrf_token",
+			HeaderName: "X-CSRF-Token",
+		},
+		Headers: HeadersConfig{
+			SecurityHeaders: SecurityHeaders{
+				ContentSecurityPolicy:     "default-src 'none'; base-uri 'none'; form-action 'none'",
+				FrameAncestors:            "'none'",
+				ContentTypeOptions:        "nosniff",
+				ReferrerPolicy:            "no-referrer",
+				PermissionsPolicy:         "accelerometer=(), camera=(), geolocation=(), gyroscope=(), magnetometer=(), microphone=(), payment=(), usb=()",
+				CrossOriginOpenerPolicy:   "same-origin",
+				CrossOriginEmbedderPolicy: "require-corp",
+				CrossOriginResourcePolicy: "same-site",
+			},
+			CSPReportURI: "/api/csp-report",
+		},
+		Metrics: MetricsConfig{
+			Enabled: true,
+			Path:    "/metrics",
+		},
+		Tracing: TracingConfig{
+			OTLPEndpoint: "http://localhost:4318",
+			ServiceName:  "go-react-jwtauth",
+		},
+		Logging: LoggingConfig{
+			Level:  "info",
+			Format: "json",
+		},
+		Secrets: SecretsConfig{
+			Dir:            "/run/secrets",
+			ReloadInterval: 30 * time.Second,
+			PreviousTTL:    24 * time.Hour,
+		},
+		Storage: StorageConfig{
+			Backend:   "local",
+			Dir:       "uploads",
+			PublicURL: "/media",
+			S3: S3Config{
+				Region:    "us-east-1",
+				PathStyle: true,
+			},
+		},
+		Avatars: AvatarConfig{
+			MaxBytes:     5 << 20,
+			MaxDimension: 4096,
+			Sizes:        []int{64, 128, 256, 512},
+		},
+	}
+}
+
+// ValidationError lists every problem found while loading the configuration.
+type ValidationError struct {
+	Problems []string
+}
+
+// Error formats the problems as a multi-line report.
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// Load reads the configuration from the defaults, the optional CONFIG_FILE, the .env file and the environment,
+// and validates it. Any string value may instead be read from a file named by the <NAME>_FILE variable,
+// e.g. JWT_SECRET_KEY_FILE=/run/secrets/jwt_secret_key. On failure the returned error is a *ValidationError listing every problem found.
+func Load() (*Config, error) {
+	var problems []string
+
+	// Load .env into the environment without overriding variables that are already set
+	if _, err := os.Stat(".env"); err == nil {
+		if err := godotenv.Load(); err != nil {
+			problems = append(problems, fmt.Sprintf(".env: %v", err))
+		}
+	}
+
+	cfg := Default()
+
+	// Apply the optional configuration file
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		if err := loadFile(path, &cfg); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", path, err))
+		}
+	}
+
+	// Apply the environment, which takes precedence over the file
+	problems = append(problems, applyEnv(&cfg)...)
+	if _, ok := os.LookupEnv("ADMIN_EMAILS"); ok {
+		problems = append(problems, "ADMIN_EMAILS is no longer supported; grant administrator rights with `server admin grant <email>`")
+	}
+
+	// Check required keys and value ranges
+	cfg.Database.Driver = strings.ToLower(strings.TrimSpace(cfg.Database.Driver))
+	problems = append(problems, cfg.validate()...)
+
+	if len(problems) > 0 {
+		return nil, &ValidationError{Problems: problems}
+	}
+	return &cfg, nil
+}
+
+// validate checks required keys and value ranges and returns a description of each problem.
+func (c *Config) validate() []string {
+	var problems []string
+	required := func(value, name string) {
+		if strings.TrimSpace(value) == "" {
+			problems = append(problems, name+" is required")
+		}
+	}
+
+	required(c.Server.Port, "SERVER_PORT")
+	if len(c.Server.AllowedOrigins) == 0 {
+		problems = append(problems, "ALLOWED_ORIGINS must list at least one origin")
+	}
+	for _, origin := range c.Server.AllowedOrigins {
+		if origin == "*" {
+			problems = append(problems, "ALLOWED_ORIGINS must not contain * because credentials are allowed")
+		}
+	}
+	problems = append(problems, c.Server.TLS.validate()...)
+	// Secrets may also come from a provider, which is checked when the secrets are loaded
+	secretsFromProvider := c.Secrets.Provider != ""
+	if !secretsFromProvider {
+		required(c.Auth.JWTSecretKey, "JWT_SECRET_KEY")
+	}
+
+	db := c.Database
+	switch {
+	case db.URL != "":
+		// The URL carries everything needed to connect
+	case db.Driver == "sqlite":
+		required(db.Name, "DB_NAME")
+	case db.Driver == "mysql" || db.Driver == "postgres":
+		required(db.Username, "DB_USERNAME")
+		if !secretsFromProvider {
+			required(db.Password, "DB_PASSWORD")
+		}
+		required(db.Address, "DB_IP")
+		required(db.Name, "DB_NAME")
+	default:
+		problems = append(problems, fmt.Sprintf("DB_DRIVER must be mysql, postgres or sqlite, got %q", db.Driver))
+	}
+
+	if db.MaxOpenConns < 0 || db.MaxIdleConns < 0 {
+		problems = append(problems, "DB_MAX_OPEN_CONNS and DB_MAX_IDLE_CONNS must not be negative")
+	}
+	if db.ConnectAttempts < 1 {
+		problems = append(problems, "DB_CONNECT_ATTEMPTS must be at least 1")
+	}
+	for name, value := range map[string]time.Duration{
+		"DB_CONN_MAX_LIFETIME":    db.ConnMaxLifetime,
+		"DB_CONN_MAX_IDLE_TIME":   db.ConnMaxIdleTime,
+		"DB_CONNECT_BACKOFF":      db.ConnectBackoff,
+		"DB_PING_INTERVAL":        db.PingInterval,
+		"DB_QUERY_TIMEOUT":        db.QueryTimeout,
+		"SECRETS_RELOAD_INTERVAL": c.Secrets.ReloadInterval,
+		"SECRETS_PREVIOUS_TTL":    c.Secrets.PreviousTTL,
+		"SHUTDOWN_DELAY":          c.Server.ShutdownDelay,
+	} {
+		if value < 0 {
+			problems = append(problems, name+" must not be negative")
+		}
+	}
+
+	problems = append(problems, c.Auth.Cookie.validate()...)
+	if c.CSRF.Enabled {
+		required(c.CSRF.CookieName, "CSRF_COOKIE_NAME")
+		required(c.CSRF.HeaderName, "CSRF_HEADER_NAME")
+	}
+
+	for prefix := range c.Headers.Routes {
+		if !strings.HasPrefix(prefix, "/") {
+			problems = append(problems, fmt.Sprintf("headers.routes: route prefix %q must start with /", prefix))
+		}
+	}
+
+	if c.Metrics.Enabled && !strings.HasPrefix(c.Metrics.Path, "/") {
+		problems = append(problems, fmt.Sprintf("METRICS_PATH must start with /, got %q", c.Metrics.Path))
+	}
+
+	switch c.Tracing.Exporter {
+	case "", "stdout":
+	case "otlp":
+		required(c.Tracing.OTLPEndpoint, "TRACING_OTLP_ENDPOINT")
+	default:
+		problems = append(problems, fmt.Sprintf("TRACING_EXPORTER must be otlp or stdout, got %q", c.Tracing.Exporter))
+	}
+	required(c.Tracing.ServiceName, "TRACING_SERVICE_NAME")
+
+	switch strings.ToLower(c.Logging.Level) {
+	case "debug", "info", "warn", "error":
+	default:
+		problems = append(problems, fmt.Sprintf("LOG_LEVEL must be debug, info, warn or error, got %q", c.Logging.Level))
+	}
+	switch strings.ToLower(c.Logging.Format) {
+	case "json", "text":
+	default:
+		problems = append(problems, fmt.Sprintf("LOG_FORMAT must be json or text, got %q", c.Logging.Format))
+	}
+
+	switch c.Secrets.Provider {
+	case "":
+	case "file":
+		required(c.Secrets.Dir, "SECRETS_DIR")
+	case "keystore":
+		required(c.Secrets.KeystorePath, "SECRETS_KEYSTORE")
+		required(c.Secrets.KeystorePassphrase, "SECRETS_KEYSTORE_PASSPHRASE")
+	default:
+		problems = append(problems, fmt.Sprintf("SECRETS_PROVIDER must be file or keystore, got %q", c.Secrets.Provider))
+	}
+
+	switch c.Storage.Backend {
+	case "local":
+		required(c.Storage.Dir, "STORAGE_DIR")
+		if !strings.HasPrefix(c.Storage.PublicURL, "/") {
+			problems = append(problems, fmt.Sprintf("STORAGE_PUBLIC_URL must be a path starting with / for local storage, got %q", c.Storage.PublicURL))
+		}
+	case "s3":
+		required(c.Storage.S3.Endpoint, "S3_ENDPOINT")
+		required(c.Storage.S3.Region, "S3_REGION")
+		required(c.Storage.S3.Bucket, "S3_BUCKET")
+		required(c.Storage.S3.AccessKeyID, "S3_ACCESS_KEY_ID")
+		required(c.Storage.S3.SecretAccessKey, "S3_SECRET_ACCESS_KEY")
+	default:
+		problems = append(problems, fmt.Sprintf("STORAGE_BACKEND must be local or s3, got %q", c.Storage.Backend))
+	}
+
+	if c.Avatars.MaxBytes < 1 {
+		problems = append(problems, fmt.Sprintf("AVATAR_MAX_BYTES must be positive, got %d", c.Avatars.MaxBytes))
+	}
+	if c.Avatars.MaxDimension < 1 {
+		problems = append(problems, fmt.Sprintf("AVATAR_MAX_DIMENSION must be positive, got %d", c.Avatars.MaxDimension))
+	}
+	if len(c.Avatars.Sizes) == 0 {
+		problems = append(problems, "AVATAR_SIZES must list at least one size")
+	}
+	for _, size := range c.Avatars.Sizes {
+		if size < 16 || size > 2048 {
+			problems = append(problems, fmt.Sprintf("AVATAR_SIZES must be between 16 and 2048 pixels, got %d", size))
+		}
+	}
+
+	return problems
+}
+
+// validate checks that the TLS files and options are consistent.
+func (t TLSConfig) validate() []string {
+	var problems []string
+
+	if t.Enabled() && (t.CertFile == "" || t.KeyFile == "") {
+		problems = append(problems, "TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	if !t.Enabled() {
+		if t.ClientCAFile != "" {
+			problems = append(problems, "TLS_CLIENT_CA_FILE requires TLS_CERT_FILE and TLS_KEY_FILE")
+		}
+		if t.RedirectPort != "" {
+			problems = append(problems, "TLS_REDIRECT_PORT requires TLS_CERT_FILE and TLS_KEY_FILE")
+		}
+	}
+	if t.MinVersion != "1.2" && t.MinVersion != "1.3" {
+		problems = append(problems, fmt.Sprintf("TLS_MIN_VERSION must be 1.2 or 1.3, got %q", t.MinVersion))
+	}
+	switch strings.ToLower(t.ClientAuth) {
+	case "require", "optional":
+	default:
+		problems = append(problems, fmt.Sprintf("TLS_CLIENT_AUTH must be require or optional, got %q", t.ClientAuth))
+	}
+	if t.ReloadInterval < 0 || t.HSTSMaxAge < 0 {
+		problems = append(problems, "TLS_RELOAD_INTERVAL and HSTS_MAX_AGE must not be negative")
+	}
+	if t.HSTSPreload && (t.HSTSMaxAge < 365*24*time.Hour || !t.HSTSIncludeSubdomains) {
+		problems = append(problems, "HSTS_PRELOAD requires HSTS_MAX_AGE of at least one year and HSTS_INCLUDE_SUBDOMAINS=true")
+	}
+
+	return problems
+}
+
+// validate checks that the cookie attributes are consistent, following the rules browsers enforce.
+func (c CookieConfig) validate() []string {
+	var problems []string
+
+	if strings.TrimSpace(c.Name) == "" {
+		problems = append(problems, "COOKIE_NAME is required")
+	}
+	switch strings.ToLower(c.SameSite) {
+	case "lax", "strict":
+	case "none":
+		if !c.Secure {
+			problems = append(problems, "COOKIE_SAMESITE=None requires COOKIE_SECURE=true")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("COOKIE_SAMESITE must be Lax, Strict or None, got %q", c.SameSite))
+	}
+	if c.HostPrefix && (!c.Secure || c.Path != "/" || c.Domain != "") {
+		problems = append(problems, "COOKIE_HOST_PREFIX requires COOKIE_SECURE=true, COOKIE_PATH=/ and no COOKIE_DOMAIN")
+	}
+	if c.Partitioned && !c.Secure {
+		problems = append(problems, "COOKIE_PARTITIONED requires COOKIE_SECURE=true")
+	}
+
+	return problems
+}
+
+// errUnsupportedFormat is returned for configuration files that are neither YAML nor TOML.
+var errUnsupportedFormat = errors.New("unsupported configuration file format (expected .yaml, .yml or .toml)")
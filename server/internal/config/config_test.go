@@ -0,0 +1,174 @@
+package config
+
+import (
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// validEnv holds the variables without a usable default.
+var validEnv = map[string]string{
+	"JWT_SECRET_KEY":  "secret",
+	"ALLOWED_ORIGINS": "http://localhost:3000",
+	"DB_DRIVER":       "sqlite",
+	"DB_NAME":         "app.db",
+}
+
+// load runs Load in an empty working directory with the given .env file, configuration files by name and
+// environment. Every variable Load reads is cleared first and restored afterwards, including those loaded from .env.
+func load(t *testing.T, dotenv string, files map[string]string, env map[string]string) (*Config, error) {
+	t.Helper()
+
+	names := []string{"CONFIG_FILE", "ADMIN_EMAILS"}
+	cfg := Default()
+	walkEnvFields(reflect.ValueOf(&cfg).Elem(), func(name string, field reflect.Value) {
+		names = append(names, name, name+"_FILE")
+	})
+	for _, name := range names {
+		t.Setenv(name, "")
+		os.Unsetenv(name)
+	}
+
+	dir := t.TempDir()
+	if dotenv != "" {
+		if err := os.WriteFile(filepath.Join(dir, ".env"), []byte(dotenv), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, value := range env {
+		t.Setenv(name, strings.ReplaceAll(value, "$DIR", dir))
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := os.Chdir(wd); err != nil {
+			t.Fatal(err)
+		}
+	})
+	return Load()
+}
+
+// withEnv returns validEnv with the given variables added.
+func withEnv(pairs ...string) map[string]string {
+	env := maps.Clone(validEnv)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		env[pairs[i]] = pairs[i+1]
+	}
+	return env
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	yamlFile := map[string]string{"config.yaml": "logging:\n  level: debug\ndatabase:\n  ping_interval: 10s\n"}
+	tomlFile := map[string]string{"config.toml": "[logging]\nlevel = \"debug\"\n\n[database]\nping_interval = \"10s\"\n"}
+	tests := []struct {
+		name   string
+		dotenv string
+		files  map[string]string
+		env    map[string]string
+		level  string
+		ping   time.Duration
+	}{
+		{"defaults without a .env file", "", nil, withEnv(), "info", 30 * time.Second},
+		{"YAML file over defaults", "", yamlFile, withEnv("CONFIG_FILE", "config.yaml"), "debug", 10 * time.Second},
+		{"TOML file over defaults", "", tomlFile, withEnv("CONFIG_FILE", "config.toml"), "debug", 10 * time.Second},
+		{".env over the file", "LOG_LEVEL=warn\n", yamlFile, withEnv("CONFIG_FILE", "config.yaml"), "warn", 10 * time.Second},
+		{"environment over .env", "LOG_LEVEL=warn\nDB_PING_INTERVAL=1m\n", yamlFile,
+			withEnv("CONFIG_FILE", "config.yaml", "LOG_LEVEL", "error"), "error", time.Minute},
+		{"required keys from .env", "JWT_SECRET_KEY=secret\nALLOWED_ORIGINS=http://localhost:3000\nDB_DRIVER=sqlite\nDB_NAME=app.db\n",
+			nil, nil, "info", 30 * time.Second},
+		{"value from a file named by _FILE", "", map[string]string{"level": "debug\n"},
+			withEnv("LOG_LEVEL_FILE", "$DIR/level"), "debug", 30 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := load(t, tt.dotenv, tt.files, tt.env)
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			if cfg.Logging.Level != tt.level || cfg.Database.PingInterval != tt.ping {
+				t.Fatalf("log level %q, ping interval %v, want %q, %v", cfg.Logging.Level, cfg.Database.PingInterval, tt.level, tt.ping)
+			}
+			if cfg.Auth.JWTSecretKey != "secret" || cfg.Database.Driver != "sqlite" {
+				t.Fatalf("required keys not loaded: %+v", cfg)
+			}
+		})
+	}
+}
+
+func TestLoadTypeErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		env     map[string]string
+		problem string
+	}{
+		{"duration", nil, withEnv("DB_PING_INTERVAL", "soon"), "DB_PING_INTERVAL: expected a duration"},
+		{"integer", nil, withEnv("DB_MAX_OPEN_CONNS", "many"), "DB_MAX_OPEN_CONNS: expected an integer"},
+		{"boolean", nil, withEnv("METRICS_ENABLED", "yes please"), "METRICS_ENABLED: expected true or false"},
+		{"list of integers", nil, withEnv("AVATAR_SIZES", "64, big"), "AVATAR_SIZES: expected a comma-separated list of integers"},
+		{"file value", map[string]string{"config.yaml": "database:\n  max_open_conns: many\n"},
+			withEnv("CONFIG_FILE", "config.yaml"), "config.yaml: "},
+		{"unknown file key", map[string]string{"config.toml": "[database]\nmax_connections = 5\n"},
+			withEnv("CONFIG_FILE", "config.toml"), "config.toml: unknown keys"},
+		{"unsupported file format", map[string]string{"config.json": "{}"},
+			withEnv("CONFIG_FILE", "config.json"), "config.json: "},
+		{"missing file", nil, withEnv("CONFIG_FILE", "missing.yaml"), "missing.yaml: "},
+		{"variable and _FILE", nil, withEnv("LOG_LEVEL", "debug", "LOG_LEVEL_FILE", "level"), "LOG_LEVEL and LOG_LEVEL_FILE must not both be set"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := load(t, "", tt.files, tt.env)
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("Load error %v, want a *ValidationError", err)
+			}
+			if len(validationErr.Problems) != 1 || !strings.HasPrefix(validationErr.Problems[0], tt.problem) {
+				t.Fatalf("problems %q, want one starting with %q", validationErr.Problems, tt.problem)
+			}
+		})
+	}
+}
+
+// TestLoadReport checks that every problem is reported at once.
+func TestLoadReport(t *testing.T) {
+	_, err := load(t, "", nil, map[string]string{"DB_DRIVER": "mysql", "DB_PING_INTERVAL": "soon", "LOG_FORMAT": "xml"})
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Load error %v, want a *ValidationError", err)
+	}
+
+	want := []string{
+		"DB_PING_INTERVAL: expected a duration such as 5s or 1m30s, got \"soon\"",
+		"ALLOWED_ORIGINS must list at least one origin",
+		"JWT_SECRET_KEY is required",
+		"DB_USERNAME is required",
+		"DB_PASSWORD is required",
+		"DB_IP is required",
+		"DB_NAME is required",
+		"LOG_FORMAT must be json or text, got \"xml\"",
+	}
+	if !reflect.DeepEqual(validationErr.Problems, want) {
+		t.Fatalf("problems:\n%s\nwant:\n%s", strings.Join(validationErr.Problems, "\n"), strings.Join(want, "\n"))
+	}
+	for _, problem := range want {
+		if !strings.Contains(err.Error(), "\n  - "+problem) {
+			t.Errorf("report does not list %q:\n%s", problem, err)
+		}
+	}
+}
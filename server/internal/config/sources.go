@@ -0,0 +1,140 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// loadFile decodes a YAML or TOML configuration file, chosen by extension, over cfg.
+// Unknown keys are rejected so typos do not go unnoticed.
+func loadFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		return nil
+	case ".toml":
+		meta, err := toml.Decode(string(data), cfg)
+		if err != nil {
+			return err
+		}
+		if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("unknown keys: %v", undecoded)
+		}
+		return nil
+	default:
+		return errUnsupportedFormat
+	}
+}
+
+// durationType is used to recognise time.Duration fields, which are int64 underneath.
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// applyEnv overrides every field tagged with `env` whose variable, or <NAME>_FILE variable, is set,
+// and returns a description of each value that could not be read or parsed.
+func applyEnv(cfg *Config) []string {
+	var problems []string
+	walkEnvFields(reflect.ValueOf(cfg).Elem(), func(name string, field reflect.Value) {
+		value, ok := os.LookupEnv(name)
+		if path := os.Getenv(name + "_FILE"); path != "" {
+			if ok {
+				problems = append(problems, fmt.Sprintf("%s and %s_FILE must not both be set", name, name))
+				return
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s_FILE: %v", name, err))
+				return
+			}
+			value, ok = strings.TrimRight(string(data), "\r\n"), true
+		}
+		if !ok {
+			return
+		}
+		if err := setField(field, value); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", name, err))
+		}
+	})
+	return problems
+}
+
+// walkEnvFields calls fn for every field tagged with `env` in v and its nested structs.
+func walkEnvFields(v reflect.Value, fn func(name string, field reflect.Value)) {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		if name := v.Type().Field(i).Tag.Get("env"); name != "" {
+			fn(name, field)
+		} else if field.Kind() == reflect.Struct {
+			walkEnvFields(field, fn)
+		}
+	}
+}
+
+// setField parses value according to the field's type and stores it.
+func setField(field reflect.Value, value string) error {
+	switch {
+	case field.Type() == durationType:
+		parsed, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("expected a duration such as 5s or 1m30s, got %q", value)
+		}
+		field.SetInt(int64(parsed))
+	case field.Kind() == reflect.String:
+		field.SetString(value)
+	case field.Kind() == reflect.Int:
+		parsed, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("expected an integer, got %q", value)
+		}
+		field.SetInt(int64(parsed))
+	case field.Kind() == reflect.Bool:
+		parsed, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("expected true or false, got %q", value)
+		}
+		field.SetBool(parsed)
+	case field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.String:
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		field.Set(reflect.ValueOf(items))
+	case field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.Int:
+		var items []int
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			parsed, err := strconv.Atoi(item)
+			if err != nil {
+				return fmt.Errorf("expected a comma-separated list of integers, got %q", value)
+			}
+			items = append(items, parsed)
+		}
+		field.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+	return nil
+}
@@ -0,0 +1,322 @@
+package controllers
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/attributes"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/audit"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/models"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/problem"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/repository"
+	"github.com/gofiber/fiber/v3"
+	"golang.org/x/text/unicode/norm"
+)
+
+// attributeAdminOnly explains why non-administrators cannot call the attribute administration endpoints.
+const attributeAdminOnly = "Only administrators may manage profile attributes."
+
+var errAttributeNotFound = problem.New(fiber.StatusNotFound, problem.CodeNotFound, "The attribute does not exist.")
+
+// ListAttributes returns the custom attribute definitions the authenticated user may see, so clients can
+// build profile forms from them.
+func (h *Handler) ListAttributes(c fiber.Ctx) error {
+	user, err := h.authenticatedUser(c)
+	if err != nil {
+		return err
+	}
+
+	defs, err := h.Attributes.ListDefinitions(c.UserContext())
+	if err != nil {
+		return problem.Internal("Failed to load the profile attributes.", err)
+	}
+
+	return c.JSON(fiber.Map{
+		"attributes": attributes.Visible(defs, h.audience(user)),
+	})
+}
+
+// CreateAttribute defines a new custom attribute. Only administrators may call it.
+func (h *Handler) CreateAttribute(c fiber.Ctx) error {
+	admin, err := h.authenticatedAdmin(c, attributeAdminOnly)
+	if err != nil {
+		return err
+	}
+
+	var req AttributeDefinitionRequest
+	if err := bindBody(c, &req); err != nil {
+		return err
+	}
+	def := definitionFromRequest(req)
+	if fields := attributes.CheckDefinition(&def); len(fields) > 0 {
+		return problem.Validation(fields...)
+	}
+
+	err = h.Attributes.CreateDefinition(c.UserContext(), &def)
+	if errors.Is(err, repository.ErrDuplicateKey) {
+		return problem.Validation(problem.FieldError{
+			Field:   "key",
+			Code:    problem.FieldTaken,
+			Message: "key is already defined",
+		})
+	}
+	if err != nil {
+		return problem.Internal("Failed to create the attribute.", err)
+	}
+
+	h.Audit.Record(c, audit.Entry{
+		Action:  audit.ActionAttributeCreate,
+		ActorId: uintPtr(admin.Id),
+		Detail:  def.Key,
+		Changes: audit.Diff(definitionFields(&models.AttributeDefinition{}), definitionFields(&def)),
+	})
+
+	return c.Status(fiber.StatusCreated).JSON(def)
+}
+
+// UpdateAttribute replaces the definition of the custom attribute named by the key in the URL.
+// The type cannot be changed, and stored values are only checked against changed rules when they are next updated.
+// Only administrators may call it.
+func (h *Handler) UpdateAttribute(c fiber.Ctx) error {
+	admin, err := h.authenticatedAdmin(c, attributeAdminOnly)
+	if err != nil {
+		return err
+	}
+
+	existing, err := h.Attributes.FindDefinition(c.UserContext(), c.Params("key"))
+	if errors.Is(err, repository.ErrNotFound) {
+		return errAttributeNotFound
+	}
+	if err != nil {
+		return problem.Internal("Failed to load the attribute.", err)
+	}
+
+	var req AttributeDefinitionRequest
+	if err := bindBody(c, &req); err != nil {
+		return err
+	}
+	if req.Key != "" && req.Key != existing.Key {
+		return problem.Validation(problem.FieldError{Field: "key", Code: problem.FieldReadOnly, Message: "key cannot be changed"})
+	}
+	if req.Type != existing.Type {
+		return problem.Validation(problem.FieldError{Field: "type", Code: problem.FieldReadOnly, Message: "type cannot be changed"})
+	}
+
+	def := definitionFromRequest(req)
+	def.Id, def.Key, def.CreatedAt = existing.Id, existing.Key, existing.CreatedAt
+	if fields := attributes.CheckDefinition(&def); len(fields) > 0 {
+		return problem.Validation(fields...)
+	}
+
+	err = h.Attributes.UpdateDefinition(c.UserContext(), &def)
+	if errors.Is(err, repository.ErrNotFound) {
+		return errAttributeNotFound
+	}
+	if err != nil {
+		return problem.Internal("Failed to update the attribute.", err)
+	}
+
+	if changes := audit.Diff(definitionFields(existing), definitionFields(&def)); len(changes) > 0 {
+		h.Audit.Record(c, audit.Entry{
+			Action:  audit.ActionAttributeUpdate,
+			ActorId: uintPtr(admin.Id),
+			Detail:  def.Key,
+			Changes: changes,
+		})
+	}
+
+	return c.JSON(def)
+}
+
+// DeleteAttribute removes the custom attribute named by the key in the URL together with every stored value.
+// Only administrators may call it.
+func (h *Handler) DeleteAttribute(c fiber.Ctx) error {
+	admin, err := h.authenticatedAdmin(c, attributeAdminOnly)
+	if err != nil {
+		return err
+	}
+
+	def, err := h.Attributes.FindDefinition(c.UserContext(), c.Params("key"))
+	if errors.Is(err, repository.ErrNotFound) {
+		return errAttributeNotFound
+	}
+	if err != nil {
+		return problem.Internal("Failed to load the attribute.", err)
+	}
+
+	err = h.Attributes.DeleteDefinition(c.UserContext(), def.Id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return errAttributeNotFound
+	}
+	if err != nil {
+		return problem.Internal("Failed to delete the attribute.", err)
+	}
+
+	h.Audit.Record(c, audit.Entry{
+		Action:  audit.ActionAttributeDelete,
+		ActorId: uintPtr(admin.Id),
+		Detail:  def.Key,
+	})
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetUserAttributes returns every custom attribute value of the user with the ID in the URL.
+// Only administrators may call it.
+func (h *Handler) GetUserAttributes(c fiber.Ctx) error {
+	if _, err := h.authenticatedAdmin(c, attributeAdminOnly); err != nil {
+		return err
+	}
+
+	target, err := h.userFromParams(c)
+	if err != nil {
+		return err
+	}
+
+	defs, err := h.Attributes.ListDefinitions(c.UserContext())
+	if err != nil {
+		return problem.Internal("Failed to load the profile attributes.", err)
+	}
+	values, err := h.Attributes.Values(c.UserContext(), target.Id)
+	if err != nil {
+		return problem.Internal("Failed to load the profile attributes.", err)
+	}
+
+	return c.JSON(fiber.Map{
+		"attributes": attributes.Values(defs, values, attributes.AudienceAdmin),
+	})
+}
+
+// PatchUserAttributes applies a JSON merge patch of custom attribute values by key to the user with the ID
+// in the URL, including attributes only administrators may see or change. Only administrators may call it.
+func (h *Handler) PatchUserAttributes(c fiber.Ctx) error {
+	admin, err := h.authenticatedAdmin(c, attributeAdminOnly)
+	if err != nil {
+		return err
+	}
+
+	target, err := h.userFromParams(c)
+	if err != nil {
+		return err
+	}
+
+	patch, err := bindAttributePatch(c)
+	if err != nil {
+		return err
+	}
+
+	defs, err := h.Attributes.ListDefinitions(c.UserContext())
+	if err != nil {
+		return problem.Internal("Failed to load the profile attributes.", err)
+	}
+	stored, err := h.Attributes.Values(c.UserContext(), target.Id)
+	if err != nil {
+		return problem.Internal("Failed to load the profile attributes.", err)
+	}
+	changes, fields := attributes.Apply(defs, stored, patch, attributes.AudienceAdmin)
+	if len(fields) > 0 {
+		return problem.Validation(fields...)
+	}
+
+	if len(changes.Set) > 0 || len(changes.Removed) > 0 {
+		// Save the user as well so its version, and with it the ETag seen by the user, changes
+		if err := h.Users.Update(c.UserContext(), target); err != nil {
+			return updateProblem(c, err)
+		}
+		if err := h.saveAttributes(c, target.Id, changes); err != nil {
+			return err
+		}
+
+		h.Audit.Record(c, audit.Entry{
+			Action:   audit.ActionProfileUpdate,
+			ActorId:  uintPtr(admin.Id),
+			TargetId: uintPtr(target.Id),
+			Changes:  audit.Diff(changes.Before, changes.After),
+		})
+		h.recordRevisions(c, target.Id, admin.Id, changes.Before, changes.After, nil)
+	}
+
+	values, err := h.Attributes.Values(c.UserContext(), target.Id)
+	if err != nil {
+		return problem.Internal("Failed to load the profile attributes.", err)
+	}
+	return c.JSON(fiber.Map{
+		"attributes": attributes.Values(defs, values, attributes.AudienceAdmin),
+	})
+}
+
+// saveAttributes stores validated attribute changes of the user.
+func (h *Handler) saveAttributes(c fiber.Ctx, userId uint, changes attributes.Changes) error {
+	if len(changes.Set) == 0 && len(changes.Removed) == 0 {
+		return nil
+	}
+	if err := h.Attributes.SetValues(c.UserContext(), userId, changes.Set, changes.Removed); err != nil {
+		return problem.Internal("Failed to save the profile attributes.", err)
+	}
+	return nil
+}
+
+// userFromParams loads the user with the ID in the :id URL parameter.
+func (h *Handler) userFromParams(c fiber.Ctx) (*models.User, error) {
+	id, ok := parseUserId(c.Params("id"))
+	if !ok {
+		return nil, errUserNotFound
+	}
+	user, err := h.Users.FindByID(c.UserContext(), id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, errUserNotFound
+	}
+	if err != nil {
+		return nil, problem.Internal("Failed to load the user.", err)
+	}
+	return user, nil
+}
+
+// definitionFromRequest builds an attribute definition from the request. Enum options are trimmed and
+// normalised like the values they are compared with.
+func definitionFromRequest(req AttributeDefinitionRequest) models.AttributeDefinition {
+	def := models.AttributeDefinition{
+		Key:        req.Key,
+		Label:      req.Label,
+		Type:       req.Type,
+		Required:   req.Required,
+		Visibility: req.Visibility,
+		EditableBy: req.EditableBy,
+		MinLength:  req.MinLength,
+		MaxLength:  req.MaxLength,
+		Pattern:    req.Pattern,
+		Min:        req.Min,
+		Max:        req.Max,
+	}
+	for _, option := range req.Options {
+		def.Options = append(def.Options, norm.NFC.String(strings.TrimSpace(option)))
+	}
+	return def
+}
+
+// definitionFields returns the fields of an attribute definition as comparable values, for auditing changes.
+func definitionFields(def *models.AttributeDefinition) map[string]any {
+	fields := map[string]any{
+		"key":         def.Key,
+		"label":       def.Label,
+		"type":        def.Type,
+		"required":    def.Required,
+		"visibility":  def.Visibility,
+		"editable_by": def.EditableBy,
+		"pattern":     def.Pattern,
+		"options":     strings.Join(def.Options, ","),
+	}
+	for name, value := range map[string]*int{"min_length": def.MinLength, "max_length": def.MaxLength} {
+		fields[name] = nil
+		if value != nil {
+			fields[name] = *value
+		}
+	}
+	for name, value := range map[string]*float64{"min": def.Min, "max": def.Max} {
+		fields[name] = nil
+		if value != nil {
+			fields[name] = *value
+		}
+	}
+	return fields
+}
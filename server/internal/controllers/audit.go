@@ -0,0 +1,126 @@
+package controllers
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/problem"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/repository"
+	"github.com/gofiber/fiber/v3"
+)
+
+// defaultPageSize and maxPageSize bound the number of entries returned per page.
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+)
+
+// pageFromQuery parses the limit and offset query parameters (limit defaults to 50, at most 500).
+func pageFromQuery(c fiber.Ctx) (limit, offset int, err error) {
+	limit = defaultPageSize
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			return 0, 0, fiber.ErrBadRequest
+		}
+		limit = min(parsed, maxPageSize)
+	}
+	if value := c.Query("offset"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			return 0, 0, fiber.ErrBadRequest
+		}
+		offset = parsed
+	}
+	return limit, offset, nil
+}
+
+// auditFilterFromQuery builds an audit filter from the common paging and time range query parameters:
+// - action: only return entries with this action
+// - since, until: RFC 3339 timestamps bounding the creation time
+// - limit, offset: paging (limit defaults to 50, at most 500)
+func auditFilterFromQuery(c fiber.Ctx) (repository.AuditFilter, error) {
+	filter := repository.AuditFilter{
+		Action: c.Query("action"),
+	}
+
+	if since := c.Query("since"); since != "" {
+		parsed, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			return filter, err
+		}
+		filter.Since = parsed
+	}
+	if until := c.Query("until"); until != "" {
+		parsed, err := time.Parse(time.RFC3339, until)
+		if err != nil {
+			return filter, err
+		}
+		filter.Until = parsed
+	}
+
+	var err error
+	filter.Limit, filter.Offset, err = pageFromQuery(c)
+	return filter, err
+}
+
+// ListAuditLogs returns audit log entries across all users. Only administrators may call it.
+// In addition to the common query parameters, entries can be filtered by actor_id, target_id and user_id.
+func (h *Handler) ListAuditLogs(c fiber.Ctx) error {
+	if _, err := h.authenticatedAdmin(c, "Only administrators may query the audit log."); err != nil {
+		return err
+	}
+
+	filter, err := auditFilterFromQuery(c)
+	if err != nil {
+		return errInvalidQuery
+	}
+
+	for param, target := range map[string]**uint{
+		"actor_id":  &filter.ActorId,
+		"target_id": &filter.TargetId,
+		"user_id":   &filter.UserId,
+	} {
+		if value := c.Query(param); value != "" {
+			id, ok := parseUserId(value)
+			if !ok {
+				return errInvalidQuery
+			}
+			*target = uintPtr(id)
+		}
+	}
+
+	entries, total, err := h.Audit.List(c, filter)
+	if err != nil {
+		return problem.Internal("Failed to query the audit log.", err)
+	}
+
+	return c.JSON(fiber.Map{
+		"entries": entries,
+		"total":   total,
+	})
+}
+
+// GetAccountActivity returns the audit log entries involving the authenticated user, either as actor or target.
+func (h *Handler) GetAccountActivity(c fiber.Ctx) error {
+	user, err := h.authenticatedUser(c)
+	if err != nil {
+		return err
+	}
+
+	filter, err := auditFilterFromQuery(c)
+	if err != nil {
+		return errInvalidQuery
+	}
+	filter.UserId = uintPtr(user.Id)
+
+	entries, total, err := h.Audit.List(c, filter)
+	if err != nil {
+		return problem.Internal("Failed to query the account activity.", err)
+	}
+
+	return c.JSON(fiber.Map{
+		"entries": entries,
+		"total":   total,
+	})
+}
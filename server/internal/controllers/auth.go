@@ -1,30 +1,32 @@
 package controllers
 
 import (
-	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/database"
+	"encoding/json"
+	"errors"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/attributes"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/audit"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/metrics"
 	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/models"
-	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/utils"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/privacy"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/problem"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/repository"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/tracing"
 	"github.com/gofiber/fiber/v3"
 	"github.com/golang-jwt/jwt/v5"
 )
 
-// parseJWT parses the JWT token from the cookie and returns the claims.
+// parseJWT parses the JWT token from the Authorization header or the cookie and returns the claims.
 // If the token is invalid or the secret key cannot be retrieved, an error is returned.
-func parseJWT(c fiber.Ctx) (*jwt.MapClaims, error) {
-	// Get the JWT token from the cookie
-	cookie := c.Cookies("jwt")
-
-	// Retrieve the secret key
-	secretKey, err := utils.GetSecretKey()
-	if err != nil {
-		return nil, err
-	}
+func (h *Handler) parseJWT(c fiber.Ctx) (*jwt.MapClaims, error) {
+	// Get the JWT token from the request
+	cookie := h.authToken(c)
 
 	// Parse the token with claims
-	token, err := jwt.ParseWithClaims(cookie, &jwt.MapClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(secretKey), nil
-	})
-
+	token, err := h.parseToken(cookie, &jwt.MapClaims{})
 	if err != nil {
 		return nil, err
 	}
@@ -34,85 +36,348 @@ func parseJWT(c fiber.Ctx) (*jwt.MapClaims, error) {
 	return claims, nil
 }
 
+// userIdFromClaims extracts the numeric user ID from the "id" claim, which is stored as a string.
+func userIdFromClaims(claims *jwt.MapClaims) (uint, bool) {
+	id, ok := (*claims)["id"].(string)
+	if !ok {
+		return 0, false
+	}
+	return parseUserId(id)
+}
+
+// sessionIdFromClaims returns the session ID from the "jti" claim, or "" for tokens issued without one.
+func sessionIdFromClaims(claims *jwt.MapClaims) string {
+	id, _ := (*claims)["jti"].(string)
+	return id
+}
+
 // GetUser retrieves the authenticated user from the database based on the JWT token.
-// If the token is invalid or the user is not found, an error is returned.
-func GetUser(c fiber.Ctx) error {
-	// Parse the JWT token and get claims
-	claims, err := parseJWT(c)
+// The response carries an ETag; if it matches the If-None-Match header, 304 Not Modified is returned instead.
+// If the token is invalid or the user is not found, a problem is returned.
+func (h *Handler) GetUser(c fiber.Ctx) error {
+	// Load the user identified by the JWT
+	user, err := h.authenticatedUser(c)
 	if err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"message": "unauthenticated",
-		})
+		return err
 	}
 
-	// Initialize user model
-	user := models.User{}
-	id := (*claims)["id"].(string)
-
-	// Query the database for the user
-	if err := database.DB.Where("id = ?", id).First(&user).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"message": "user not found",
-		})
+	// Let the client reuse its cached copy if neither the user nor the attribute definitions have changed
+	defs, err := h.Attributes.ListDefinitions(c.UserContext())
+	if err != nil {
+		return problem.Internal("Failed to load the profile attributes.", err)
+	}
+	setUserETag(c, user, defs)
+	if header := c.Get(fiber.HeaderIfNoneMatch); header != "" && etagMatches(header, userETag(user, defs), true) {
+		return c.SendStatus(fiber.StatusNotModified)
 	}
 
 	// Return the user data as JSON
-	return c.JSON(user)
+	response, err := h.userResponse(c, user, defs)
+	if err != nil {
+		return err
+	}
+	return c.JSON(response)
+}
+
+// userResponse is the JSON representation of the authenticated user, with the custom attribute values
+// they may see, the visibility of each field on their public profile and the URLs of the avatar thumbnails by size.
+type userResponse struct {
+	*models.User
+	Attributes        map[string]json.RawMessage `json:"attributes"`
+	ProfileVisibility map[string]string          `json:"profile_visibility"`
+	AvatarURLs        map[string]string          `json:"avatar_urls,omitempty"`
+}
+
+// userResponse returns the JSON representation of the user with the given attribute definitions.
+func (h *Handler) userResponse(c fiber.Ctx, user *models.User, defs []models.AttributeDefinition) (userResponse, error) {
+	response := userResponse{User: user, ProfileVisibility: privacy.Effective(user.ProfileVisibility)}
+
+	values, err := h.Attributes.Values(c.UserContext(), user.Id)
+	if err != nil {
+		return response, problem.Internal("Failed to load the profile attributes.", err)
+	}
+	response.Attributes = attributes.Values(defs, values, h.audience(user))
+
+	response.AvatarURLs = h.avatarURLs(user)
+	return response, nil
+}
+
+// sendUser sends the user with its ETag after a change.
+func (h *Handler) sendUser(c fiber.Ctx, user *models.User) error {
+	defs, err := h.Attributes.ListDefinitions(c.UserContext())
+	if err != nil {
+		return problem.Internal("Failed to load the profile attributes.", err)
+	}
+	response, err := h.userResponse(c, user, defs)
+	if err != nil {
+		return err
+	}
+	setUserETag(c, user, defs)
+	return c.JSON(response)
 }
 
 // UpdateUser updates the user's profile information, including name, email, and password.
-// If the token is invalid, the user is not found, or there is an error updating the user, an error is returned.
-func UpdateUser(c fiber.Ctx) error {
-	// Bind the request body to a map
-	var data map[string]string
-	if err := c.Bind().Body(&data); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid data"})
+// If the token is invalid, the user is not found, or there is an error updating the user, a problem is returned.
+// An If-Match header is honoured like in PatchUser.
+func (h *Handler) UpdateUser(c fiber.Ctx) error {
+	// Load the user identified by the JWT
+	user, err := h.authenticatedUser(c)
+	if err != nil {
+		return err
+	}
+
+	// Parse and validate the request body
+	var req UpdateUserRequest
+	if err := bindBody(c, &req); err != nil {
+		return err
+	}
+
+	if err := h.saveUser(c, user, req, nil); err != nil {
+		return err
 	}
 
-	// Parse the JWT token and get claims
-	claims, err := parseJWT(c)
+	// Return success message with updated user information
+	defs, err := h.Attributes.ListDefinitions(c.UserContext())
 	if err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"message": "unauthenticated",
-		})
+		return problem.Internal("Failed to load the profile attributes.", err)
+	}
+	setUserETag(c, user, defs)
+	return c.JSON(fiber.Map{
+		"message": "Profile updated successfully",
+		"name":    user.Name,
+		"email":   user.Email,
+	})
+}
+
+// PatchUser applies an RFC 7396 JSON merge patch to the user's profile and returns the updated user.
+// If the If-Match header does not list the current ETag, the user was changed since the client read it
+// and 412 Precondition Failed is returned without applying the patch.
+func (h *Handler) PatchUser(c fiber.Ctx) error {
+	// Load the user identified by the JWT
+	user, err := h.authenticatedUser(c)
+	if err != nil {
+		return err
 	}
 
-	// Extract user ID from claims
-	userID := (*claims)["id"].(string)
+	// Parse and validate the patch
+	var req UpdateUserRequest
+	if err := bindMergePatch(c, &req, h.userPatchKeys(c, user)); err != nil {
+		return err
+	}
 
-	// Query the database for the user
-	var user models.User
-	if err := database.DB.First(&user, userID).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	if err := h.saveUser(c, user, req, nil); err != nil {
+		return err
 	}
 
+	return h.sendUser(c, user)
+}
+
+// userPatchKeys returns the keys a merge patch resets when it removes an object member of the user:
+// every profile visibility setting, and the attributes with a value that the user may change.
+func (h *Handler) userPatchKeys(c fiber.Ctx, user *models.User) func(member string) ([]string, error) {
+	return func(member string) ([]string, error) {
+		switch member {
+		case "profile_visibility":
+			return slices.Sorted(maps.Keys(privacy.Defaults)), nil
+		case "attributes":
+			defs, err := h.Attributes.ListDefinitions(c.UserContext())
+			if err != nil {
+				return nil, problem.Internal("Failed to load the profile attributes.", err)
+			}
+			stored, err := h.Attributes.Values(c.UserContext(), user.Id)
+			if err != nil {
+				return nil, problem.Internal("Failed to load the profile attributes.", err)
+			}
+			hasValue := map[uint]bool{}
+			for _, value := range stored {
+				hasValue[value.AttributeId] = true
+			}
+			audience := h.audience(user)
+			var keys []string
+			for _, def := range defs {
+				if hasValue[def.Id] && attributes.CanView(def, audience) && attributes.CanEdit(def, audience) {
+					keys = append(keys, def.Key)
+				}
+			}
+			return keys, nil
+		}
+		return nil, nil
+	}
+}
+
+// saveUser applies the requested changes to the user, saves it and records the changes in the audit log and
+// the profile history. revertOf names the revision being reverted, if any.
+// The user is only saved if it still matches the If-Match header and was not changed concurrently.
+// Changing the password revokes every other session of the user.
+func (h *Handler) saveUser(c fiber.Ctx, user *models.User, req UpdateUserRequest, revertOf *uint) error {
+	if err := h.checkIfMatch(c, user); err != nil {
+		return err
+	}
+
+	// Validate the custom attributes against their definitions and the profile visibility settings
+	var fields []problem.FieldError
+	var attributeChanges attributes.Changes
+	if req.Attributes != nil {
+		defs, err := h.Attributes.ListDefinitions(c.UserContext())
+		if err != nil {
+			return problem.Internal("Failed to load the profile attributes.", err)
+		}
+		stored, err := h.Attributes.Values(c.UserContext(), user.Id)
+		if err != nil {
+			return problem.Internal("Failed to load the profile attributes.", err)
+		}
+		var attributeFields []problem.FieldError
+		attributeChanges, attributeFields = attributes.Apply(defs, stored, req.Attributes, h.audience(user))
+		fields = append(fields, attributeFields...)
+	}
+	visibility := user.ProfileVisibility
+	if req.ProfileVisibility != nil {
+		var visibilityFields []problem.FieldError
+		visibility, visibilityFields = privacy.Apply(user.ProfileVisibility, req.ProfileVisibility)
+		fields = append(fields, visibilityFields...)
+	}
+	if len(fields) > 0 {
+		return problem.Validation(fields...)
+	}
+
+	// Remember the current values so the change can be audited
+	before := profileFields(user)
+	maps.Copy(before, attributeChanges.Before)
+
 	// Update user name if provided
-	if name, ok := data["name"]; ok {
-		user.Name = name
+	if req.Name != nil {
+		user.Name = *req.Name
 	}
 	// Update user email if provided
-	if email, ok := data["email"]; ok {
-		user.Email = email
+	if req.Email != nil {
+		user.Email = *req.Email
+	}
+	// Update the optional profile fields if provided; empty values clear them
+	if req.DisplayName != nil {
+		user.DisplayName = *req.DisplayName
+	}
+	if req.Handle != nil {
+		user.Handle = nil
+		if *req.Handle != "" {
+			user.Handle = req.Handle
+		}
+	}
+	if req.Bio != nil {
+		user.Bio = *req.Bio
+	}
+	if req.Locale != nil {
+		user.Locale = *req.Locale
+	}
+	if req.Timezone != nil {
+		user.Timezone = *req.Timezone
+	}
+	if req.Phone != nil {
+		user.Phone = *req.Phone
 	}
+	user.ProfileVisibility = visibility
 
 	// Update user password if provided
-	if password, ok := data["password"]; ok && password != "" {
-		hashedPassword, err := user.HashPassword(password)
+	passwordChanged := false
+	if req.Password != nil && *req.Password != "" {
+		_, span := tracing.Start(c.UserContext(), "bcrypt.hash")
+		hashStart := time.Now()
+		hashedPassword, err := user.HashPassword(*req.Password)
+		metrics.ObservePasswordHash("hash", models.PasswordCost, hashStart)
+		span.End()
 		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to hash password"})
+			return problem.Internal("Failed to hash the password.", err)
 		}
 		user.Password = hashedPassword
+		passwordChanged = true
 	}
 
-	// Save the updated user to the database
-	if err := database.DB.Save(&user).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update user"})
+	// Save the updated user unless another request changed it in the meantime, then its attributes
+	if err := h.Users.Update(c.UserContext(), user); err != nil {
+		return updateProblem(c, err)
+	}
+	if err := h.saveAttributes(c, user.Id, attributeChanges); err != nil {
+		return err
 	}
 
-	// Return success message with updated user information
-	return c.JSON(fiber.Map{
-		"message": "Profile updated successfully",
-		"name":    user.Name,
-		"email":   user.Email,
-	})
+	// Record the changed profile fields in the profile history, and them and any password change in the audit log
+	after := profileFields(user)
+	maps.Copy(after, attributeChanges.After)
+	changes := audit.Diff(before, after)
+	if len(changes) > 0 {
+		h.Audit.Record(c, audit.Entry{
+			Action:   audit.ActionProfileUpdate,
+			ActorId:  uintPtr(user.Id),
+			TargetId: uintPtr(user.Id),
+			Changes:  changes,
+		})
+		h.recordRevisions(c, user.Id, user.Id, before, after, revertOf)
+	}
+	if passwordChanged {
+		h.Audit.Record(c, audit.Entry{
+			Action:   audit.ActionPasswordChange,
+			ActorId:  uintPtr(user.Id),
+			TargetId: uintPtr(user.Id),
+			Changes: map[string]audit.Change{
+				"password": {},
+			},
+		})
+
+		// Sign out every other session, which may have been opened with the old password
+		revoked, err := h.Sessions.RevokeOthers(c.UserContext(), user.Id, h.currentSessionId(c), time.Now())
+		if err != nil {
+			return problem.Internal("Failed to revoke the other sessions.", err)
+		}
+		if revoked > 0 {
+			h.Audit.Record(c, audit.Entry{
+				Action:   audit.ActionSessionRevoke,
+				ActorId:  uintPtr(user.Id),
+				TargetId: uintPtr(user.Id),
+				Detail:   "all other sessions after a password change",
+			})
+		}
+	}
+	return nil
+}
+
+// updateProblem returns the problem for an error from UserRepository.Update.
+func updateProblem(c fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, repository.ErrDuplicateEmail):
+		return errEmailInUse
+	case errors.Is(err, repository.ErrDuplicateHandle):
+		return problem.Validation(problem.FieldError{
+			Field:   "handle",
+			Code:    problem.FieldTaken,
+			Message: "handle is already taken",
+		})
+	case errors.Is(err, repository.ErrConflict) && c.Get(fiber.HeaderIfMatch) != "":
+		return errPreconditionFailed
+	case errors.Is(err, repository.ErrConflict):
+		return errEditConflict
+	case errors.Is(err, repository.ErrNotFound):
+		return errUserNotFound
+	}
+	return problem.Internal("Failed to update the user.", err)
+}
+
+// profileFields returns the editable profile fields of the user and their visibility, for auditing changes.
+func profileFields(user *models.User) map[string]any {
+	handle := ""
+	if user.Handle != nil {
+		handle = *user.Handle
+	}
+	fields := map[string]any{
+		"name":         user.Name,
+		"email":        user.Email,
+		"display_name": user.DisplayName,
+		"handle":       handle,
+		"bio":          user.Bio,
+		"locale":       user.Locale,
+		"timezone":     user.Timezone,
+		"phone":        user.Phone,
+	}
+	for field, level := range privacy.Effective(user.ProfileVisibility) {
+		fields["profile_visibility."+field] = level
+	}
+	return fields
 }
@@ -0,0 +1,202 @@
+package controllers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/audit"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/images"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/logging"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/models"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/problem"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/tracing"
+	"github.com/gofiber/fiber/v3"
+)
+
+// avatarURLs returns the URL of each avatar thumbnail of the user by size, or nil if the user has no avatar.
+func (h *Handler) avatarURLs(user *models.User) map[string]string {
+	thumbnails := user.AvatarThumbnails()
+	if thumbnails == nil {
+		return nil
+	}
+	urls := make(map[string]string, len(thumbnails))
+	for size, key := range thumbnails {
+		urls[strconv.Itoa(size)] = h.Blobs.URL(key)
+	}
+	return urls
+}
+
+// UploadAvatar replaces the user's avatar with the image in the "avatar" field of a multipart form.
+// The image is checked by its content, re-encoded without metadata and stored as square thumbnails in
+// each configured size; the thumbnails of the previous avatar are deleted. An If-Match header is honoured
+// like in PatchUser. The updated user is returned.
+func (h *Handler) UploadAvatar(c fiber.Ctx) error {
+	user, err := h.authenticatedUser(c)
+	if err != nil {
+		return err
+	}
+	if err := h.checkIfMatch(c, user); err != nil {
+		return err
+	}
+
+	data, err := h.readAvatarUpload(c)
+	if err != nil {
+		return err
+	}
+
+	// Decode the image and generate the thumbnails
+	_, span := tracing.Start(c.UserContext(), "avatar.thumbnails")
+	thumbnails, err := images.Thumbnails(data, h.Avatars.MaxDimension, h.Avatars.Sizes)
+	span.End()
+	switch {
+	case errors.Is(err, images.ErrUnsupportedFormat):
+		return problem.New(fiber.StatusUnsupportedMediaType, problem.CodeUnsupportedMedia, "The avatar must be a JPEG, PNG, GIF or WebP image.")
+	case errors.Is(err, images.ErrTooLarge):
+		return avatarFieldProblem(problem.FieldTooLong, "avatar must be at most "+strconv.Itoa(h.Avatars.MaxDimension)+" pixels wide and high")
+	case errors.Is(err, images.ErrInvalid):
+		return avatarFieldProblem(problem.FieldInvalid, "avatar is not a valid image")
+	case err != nil:
+		return problem.Internal("Failed to process the avatar.", err)
+	}
+
+	// Store the thumbnails under a new key, so cached copies of the previous avatar are never served for it
+	prefix, err := newAvatarKey(user.Id)
+	if err != nil {
+		return problem.Internal("Failed to store the avatar.", err)
+	}
+	var stored []string
+	for size, thumbnail := range thumbnails {
+		key := models.AvatarThumbnailKey(prefix, size)
+		if err := h.Blobs.Put(c.UserContext(), key, thumbnail, images.ContentType); err != nil {
+			h.deleteBlobs(c.UserContext(), stored)
+			return problem.Internal("Failed to store the avatar.", err)
+		}
+		stored = append(stored, key)
+	}
+
+	previous := user.AvatarThumbnails()
+	user.AvatarKey = prefix
+	user.AvatarSizes = joinSizes(h.Avatars.Sizes)
+	if err := h.Users.Update(c.UserContext(), user); err != nil {
+		h.deleteBlobs(c.UserContext(), stored)
+		return updateProblem(c, err)
+	}
+	h.deleteBlobs(c.UserContext(), mapValues(previous))
+
+	h.Audit.Record(c, audit.Entry{
+		Action:   audit.ActionAvatarUpdate,
+		ActorId:  uintPtr(user.Id),
+		TargetId: uintPtr(user.Id),
+	})
+
+	return h.sendUser(c, user)
+}
+
+// DeleteAvatar removes the user's avatar and its thumbnails and returns the updated user.
+func (h *Handler) DeleteAvatar(c fiber.Ctx) error {
+	user, err := h.authenticatedUser(c)
+	if err != nil {
+		return err
+	}
+	if err := h.checkIfMatch(c, user); err != nil {
+		return err
+	}
+
+	previous := user.AvatarThumbnails()
+	if previous != nil {
+		user.AvatarKey = ""
+		user.AvatarSizes = ""
+		if err := h.Users.Update(c.UserContext(), user); err != nil {
+			return updateProblem(c, err)
+		}
+		h.deleteBlobs(c.UserContext(), mapValues(previous))
+
+		h.Audit.Record(c, audit.Entry{
+			Action:   audit.ActionAvatarDelete,
+			ActorId:  uintPtr(user.Id),
+			TargetId: uintPtr(user.Id),
+		})
+	}
+
+	return h.sendUser(c, user)
+}
+
+// readAvatarUpload returns the contents of the uploaded avatar, enforcing the size limit.
+func (h *Handler) readAvatarUpload(c fiber.Ctx) ([]byte, error) {
+	if !strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEMultipartForm) {
+		return nil, problem.New(fiber.StatusUnsupportedMediaType, problem.CodeUnsupportedMedia, "The avatar must be uploaded as multipart/form-data.")
+	}
+	file, err := c.FormFile("avatar")
+	if err != nil {
+		return nil, avatarFieldProblem(problem.FieldRequired, "avatar is required")
+	}
+	if file.Size > int64(h.Avatars.MaxBytes) {
+		return nil, errAvatarTooLarge(h.Avatars.MaxBytes)
+	}
+
+	f, err := file.Open()
+	if err != nil {
+		return nil, problem.Internal("Failed to read the avatar.", err)
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, int64(h.Avatars.MaxBytes)+1))
+	if err != nil {
+		return nil, problem.Internal("Failed to read the avatar.", err)
+	}
+	if len(data) > h.Avatars.MaxBytes {
+		return nil, errAvatarTooLarge(h.Avatars.MaxBytes)
+	}
+	return data, nil
+}
+
+// deleteBlobs deletes the blobs, logging failures. Leftover blobs waste space but are otherwise harmless.
+func (h *Handler) deleteBlobs(ctx context.Context, keys []string) {
+	for _, key := range keys {
+		if err := h.Blobs.Delete(ctx, key); err != nil {
+			logging.FromContext(ctx).Warn("Could not delete blob", "key", key, "error", err)
+		}
+	}
+}
+
+// errAvatarTooLarge returns the problem for uploads over the size limit.
+func errAvatarTooLarge(maxBytes int) *problem.Problem {
+	return problem.New(fiber.StatusRequestEntityTooLarge, problem.CodePayloadTooLarge,
+		"The avatar must be at most "+strconv.Itoa(maxBytes)+" bytes.")
+}
+
+// avatarFieldProblem returns a validation problem for the avatar field.
+func avatarFieldProblem(code, message string) *problem.Problem {
+	return problem.Validation(problem.FieldError{Field: "avatar", Code: code, Message: message})
+}
+
+// newAvatarKey returns a new, unguessable storage key prefix for the user's avatar.
+func newAvatarKey(userId uint) (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return "avatars/" + strconv.FormatUint(uint64(userId), 10) + "/" + hex.EncodeToString(buf), nil
+}
+
+// joinSizes formats the thumbnail sizes for models.User.AvatarSizes.
+func joinSizes(sizes []int) string {
+	parts := make([]string, len(sizes))
+	for i, size := range sizes {
+		parts[i] = strconv.Itoa(size)
+	}
+	return strings.Join(parts, ",")
+}
+
+// mapValues returns the values of the map in no particular order.
+func mapValues(m map[int]string) []string {
+	values := make([]string, 0, len(m))
+	for _, value := range m {
+		values = append(values, value)
+	}
+	return values
+}
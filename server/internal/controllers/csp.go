@@ -0,0 +1,32 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/logging"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/problem"
+	"github.com/gofiber/fiber/v3"
+)
+
+// maxCSPReportSize bounds the size of a violation report body that is accepted and logged.
+const maxCSPReportSize = 16 * 1024
+
+// ReportCSPViolation receives Content Security Policy violation reports sent by browsers, both in the
+// legacy report-uri format (application/csp-report) and the Reporting API format (application/reports+json),
+// and writes them to the log.
+func (h *Handler) ReportCSPViolation(c fiber.Ctx) error {
+	body := c.Body()
+	if len(body) > maxCSPReportSize {
+		return problem.New(fiber.StatusRequestEntityTooLarge, problem.CodePayloadTooLarge, "The report is too large.")
+	}
+
+	// Log the report on a single line; malformed reports are rejected rather than logged verbatim
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, body); err != nil {
+		return problem.New(fiber.StatusBadRequest, problem.CodeInvalidRequest, "The report is not valid JSON.")
+	}
+	logging.FromContext(c.UserContext()).Warn("CSP violation report", "report", compact.String())
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
@@ -1,109 +1,86 @@
 package controllers
 
 import (
-	"fmt"
+	"errors"
 	"time"
 
-	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/database"
-	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/models"
-	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/utils"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/audit"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/logging"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/problem"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/repository"
 	"github.com/gofiber/fiber/v3"
 	"github.com/golang-jwt/jwt/v5"
 )
 
 // DeleteUser handles the deletion of a user profile.
-func DeleteUser(c fiber.Ctx) error {
-	// Retrieve the JWT token from the cookies
-	tokenString := c.Cookies("jwt")
+func (h *Handler) DeleteUser(c fiber.Ctx) error {
+	// Retrieve the JWT token from the request
+	tokenString := h.authToken(c)
 	if tokenString == "" {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Unauthorized. Token not found.",
-		})
-	}
-
-	// Get the secret key
-	secretKey, err := utils.GetSecretKey()
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to get secret key",
-		})
+		return errUnauthenticated
 	}
 
 	// Define the JWT claims structure
 	claims := jwt.MapClaims{}
 
 	// Parse the JWT token and validate it
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		return []byte(secretKey), nil
-	})
-
+	token, err := h.parseToken(tokenString, claims)
 	if err != nil || !token.Valid {
-		fmt.Println("Token Parsing Error:", err) // Debug
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Unauthorized. Invalid token.",
-		})
+		logging.FromContext(c.UserContext()).Debug("Rejected invalid token", "error", err)
+		return tokenProblem(err)
 	}
 
 	// Ensure token uses correct signing method
 	if token.Method != jwt.SigningMethodHS256 {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Unauthorized. Invalid token algorithm.",
-		})
+		return errUnauthenticated
 	}
 
 	// Check if the token is expired
 	if exp, ok := claims["exp"].(float64); ok {
 		if int64(exp) < time.Now().Unix() {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "Unauthorized. Token has expired.",
-			})
+			return tokenProblem(jwt.ErrTokenExpired)
 		}
 	}
 
 	// Extract user ID from the JWT claims
-	userID, ok := claims["id"].(string)
+	userId, ok := userIdFromClaims(&claims)
 	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Unauthorized. User ID not found.",
-		})
+		return errUnauthenticated
+	}
+	if err := h.checkSession(c, &claims); err != nil {
+		return err
 	}
 
-	// Perform the deletion operation in the database
-	err = deleteUserFromDatabase(userID)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to delete user profile",
-		})
+	// Remember the avatar so its thumbnails can be removed along with the user
+	var avatar map[int]string
+	if user, err := h.Users.FindByID(c.UserContext(), userId); err == nil {
+		avatar = user.AvatarThumbnails()
 	}
 
-	// Create a new cookie to overwrite the existing JWT cookie
-	cookie := fiber.Cookie{
-		Name:     "jwt",
-		Value:    "",                         // Empty value to clear the cookie
-		Expires:  time.Now().Add(-time.Hour), // Set expiration in the past to invalidate
-		HTTPOnly: true,                       // Prevent JavaScript access for security
-		Secure:   false,                      // Set to true if using HTTPS
+	// Perform the deletion operation in the repository
+	err = h.Users.Delete(c.UserContext(), userId)
+	if errors.Is(err, repository.ErrNotFound) {
+		return errUserNotFound
+	}
+	if err != nil {
+		return problem.Internal("Failed to delete the user profile.", err)
 	}
 
-	// Set the cookie in the response, effectively clearing it
-	c.Cookie(&cookie)
+	// Remove the avatar thumbnails from storage
+	h.deleteBlobs(c.UserContext(), mapValues(avatar))
+
+	// Record the deletion in the audit log
+	h.Audit.Record(c, audit.Entry{
+		Action:   audit.ActionDelete,
+		ActorId:  uintPtr(userId),
+		TargetId: uintPtr(userId),
+	})
+
+	// Overwrite the existing JWT cookie, effectively clearing it
+	h.clearAuthCookie(c)
 
 	// Return a success response
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
 		"message": "User profile deleted successfully",
 	})
 }
-
-
-// deleteUserFromDatabase deletes a user from the database using the provided userID.
-func deleteUserFromDatabase(userID string) error {
-	// Create a user instance to be deleted
-	var user models.User
-
-	// Find the user by ID and delete
-	if err := database.DB.Where("id = ?", userID).Delete(&user).Error; err != nil {
-		return err
-	}
-
-	return nil
-}
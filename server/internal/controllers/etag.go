@@ -0,0 +1,76 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"strings"
+
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/models"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/problem"
+	"github.com/gofiber/fiber/v3"
+)
+
+// userETag returns the strong entity tag of the user resource, which changes whenever the user is updated
+// or logs in, and whenever an attribute definition, which decides how the attribute values are shown, changes.
+func userETag(user *models.User, defs []models.AttributeDefinition) string {
+	var lastLogin int64
+	if user.LastLoginAt != nil {
+		lastLogin = user.LastLoginAt.Unix()
+	}
+	return fmt.Sprintf(`"%d-%d-%d-%x"`, user.Id, user.Version, lastLogin, definitionsHash(defs))
+}
+
+// definitionsHash returns a hash of the attribute definitions.
+func definitionsHash(defs []models.AttributeDefinition) uint32 {
+	hash := fnv.New32a()
+	// Writing to a hash cannot fail, and definitions always encode
+	_ = json.NewEncoder(hash).Encode(defs)
+	return hash.Sum32()
+}
+
+// setUserETag sets the ETag of the user resource. The response depends on the credentials, so shared caches
+// must not store it and browsers must revalidate it.
+func setUserETag(c fiber.Ctx, user *models.User, defs []models.AttributeDefinition) {
+	c.Set(fiber.HeaderETag, userETag(user, defs))
+	c.Set(fiber.HeaderCacheControl, "private, no-cache")
+	c.Vary(fiber.HeaderAuthorization, fiber.HeaderCookie)
+}
+
+// etagMatches reports whether the If-Match or If-None-Match header value lists the entity tag or "*".
+// Weak comparison is used when weak is true (If-None-Match), strong comparison otherwise (If-Match).
+func etagMatches(header, etag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if strings.HasPrefix(candidate, "W/") {
+			if !weak {
+				continue
+			}
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// checkIfMatch returns a 412 problem if the request carries an If-Match header that does not list
+// the user's current entity tag. Requests without If-Match are not checked.
+func (h *Handler) checkIfMatch(c fiber.Ctx, user *models.User) error {
+	header := c.Get(fiber.HeaderIfMatch)
+	if header == "" {
+		return nil
+	}
+	defs, err := h.Attributes.ListDefinitions(c.UserContext())
+	if err != nil {
+		return problem.Internal("Failed to load the profile attributes.", err)
+	}
+	if !etagMatches(header, userETag(user, defs), false) {
+		return errPreconditionFailed
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package controllers
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/models"
+)
+
+func TestUserETag(t *testing.T) {
+	login := time.Unix(1700000000, 0)
+	user := models.User{Id: 7, Version: 3}
+	defs := []models.AttributeDefinition{{Id: 1, Key: "nickname", Visibility: models.VisibilityPublic}}
+	if got := userETag(&user, nil); !strings.HasPrefix(got, `"7-3-0-`) {
+		t.Fatalf("userETag = %s", got)
+	}
+
+	tests := []struct {
+		name   string
+		change func(u *models.User, defs []models.AttributeDefinition) []models.AttributeDefinition
+	}{
+		{"update", func(u *models.User, defs []models.AttributeDefinition) []models.AttributeDefinition {
+			u.Version++
+			return defs
+		}},
+		{"login", func(u *models.User, defs []models.AttributeDefinition) []models.AttributeDefinition {
+			u.LastLoginAt = &login
+			return defs
+		}},
+		{"other user", func(u *models.User, defs []models.AttributeDefinition) []models.AttributeDefinition {
+			u.Id++
+			return defs
+		}},
+		{"definition changed", func(u *models.User, defs []models.AttributeDefinition) []models.AttributeDefinition {
+			changed := slices.Clone(defs)
+			changed[0].Visibility = models.VisibilityAdmin
+			return changed
+		}},
+		{"definition added", func(u *models.User, defs []models.AttributeDefinition) []models.AttributeDefinition {
+			return append(slices.Clone(defs), models.AttributeDefinition{Id: 2, Key: "team"})
+		}},
+		{"definition deleted", func(u *models.User, defs []models.AttributeDefinition) []models.AttributeDefinition {
+			return nil
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changed := user
+			changedDefs := tt.change(&changed, defs)
+			if userETag(&changed, changedDefs) == userETag(&user, defs) {
+				t.Fatalf("ETag %s did not change", userETag(&changed, changedDefs))
+			}
+		})
+	}
+}
+
+func TestETagMatches(t *testing.T) {
+	const etag = `"7-3-0"`
+	tests := []struct {
+		header string
+		weak   bool
+		want   bool
+	}{
+		{`"7-3-0"`, false, true},
+		{`"7-3-0"`, true, true},
+		{`"7-2-0"`, false, false},
+		{`*`, false, true},
+		{`*`, true, true},
+		{`"1-1-0", "7-3-0"`, false, true},
+		{` "1-1-0" ,"7-3-0" `, false, true},
+		{`"1-1-0", "2-2-0"`, true, false},
+		{`W/"7-3-0"`, true, true},
+		{`W/"7-3-0"`, false, false},
+		{`W/"1-1-0", "7-3-0"`, false, true},
+		{`7-3-0`, false, false},
+		{``, false, false},
+	}
+	for _, tt := range tests {
+		if got := etagMatches(tt.header, etag, tt.weak); got != tt.want {
+			t.Errorf("etagMatches(%q, weak %v) = %v, want %v", tt.header, tt.weak, got, tt.want)
+		}
+	}
+}
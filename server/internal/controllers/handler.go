@@ -0,0 +1,164 @@
+package controllers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/attributes"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/audit"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/config"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/models"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/problem"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/repository"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/secrets"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/storage"
+	"github.com/gofiber/fiber/v3"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Handler holds the dependencies shared by the HTTP handlers.
+// Each handler is a method on Handler so dependencies are injected rather than read from globals.
+type Handler struct {
+	Auth        config.AuthConfig                // Authentication cookie settings
+	JWTSecret   *secrets.Secret                  // Key used to sign and verify JWTs, reloaded when rotated
+	Users       repository.UserRepository        // Storage for user accounts
+	Audit       *audit.Recorder                  // Audit log for account events
+	Attributes  repository.AttributeRepository   // Custom profile attribute definitions and values
+	Revisions   repository.RevisionRepository    // History of profile field changes
+	Preferences repository.PreferencesRepository // UI and notification preferences
+	Sessions    repository.SessionRepository     // Sessions started by logging in
+	Blobs       storage.BlobStore                // Storage for uploaded files such as avatars
+	Avatars     config.AvatarConfig              // Avatar upload limits and thumbnail sizes
+}
+
+// NewHandler creates a Handler using the given auth settings, JWT signing secret, user, audit log, attribute,
+// revision, preferences and session repositories, and the blob store and settings for avatars.
+func NewHandler(auth config.AuthConfig, jwtSecret *secrets.Secret, users repository.UserRepository, auditLogs repository.AuditRepository,
+	attributes repository.AttributeRepository, revisions repository.RevisionRepository, preferences repository.PreferencesRepository,
+	sessions repository.SessionRepository, blobs storage.BlobStore, avatars config.AvatarConfig) *Handler {
+	return &Handler{
+		Auth:        auth,
+		JWTSecret:   jwtSecret,
+		Users:       users,
+		Audit:       audit.NewRecorder(auditLogs),
+		Attributes:  attributes,
+		Revisions:   revisions,
+		Preferences: preferences,
+		Sessions:    sessions,
+		Blobs:       blobs,
+		Avatars:     avatars,
+	}
+}
+
+// secretKey returns the key used to sign new JWTs.
+func (h *Handler) secretKey() (string, error) {
+	key := h.JWTSecret.Current()
+	if key == "" {
+		return "", errors.New("JWT secret key is not configured")
+	}
+	return key, nil
+}
+
+// parseToken parses and verifies a JWT with the current secret key. If the key was rotated recently,
+// tokens issued with the previous key are still accepted so existing sessions survive the rotation.
+func (h *Handler) parseToken(tokenString string, claims jwt.Claims) (*jwt.Token, error) {
+	keyFor := func(key string) jwt.Keyfunc {
+		return func(token *jwt.Token) (interface{}, error) {
+			return []byte(key), nil
+		}
+	}
+
+	secretKey, err := h.secretKey()
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := jwt.ParseWithClaims(tokenString, claims, keyFor(secretKey))
+	if errors.Is(err, jwt.ErrTokenSignatureInvalid) {
+		if previous := h.JWTSecret.Previous(); previous != "" {
+			return jwt.ParseWithClaims(tokenString, claims, keyFor(previous))
+		}
+	}
+	return token, err
+}
+
+// authenticatedUser parses the JWT and loads the matching user from the repository.
+// It returns a problem describing why the request is not authenticated otherwise.
+func (h *Handler) authenticatedUser(c fiber.Ctx) (*models.User, error) {
+	claims, err := h.parseJWT(c)
+	if err != nil {
+		return nil, tokenProblem(err)
+	}
+
+	id, ok := userIdFromClaims(claims)
+	if !ok {
+		return nil, errUnauthenticated
+	}
+	if err := h.checkSession(c, claims); err != nil {
+		return nil, err
+	}
+
+	user, err := h.Users.FindByID(c.UserContext(), id)
+	if err != nil {
+		return nil, errUserNotFound
+	}
+
+	return user, nil
+}
+
+// authenticatedAdmin is like authenticatedUser but also requires the user to be an administrator.
+// detail explains what only administrators may do.
+func (h *Handler) authenticatedAdmin(c fiber.Ctx, detail string) (*models.User, error) {
+	user, err := h.authenticatedUser(c)
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsAdmin() {
+		return nil, problem.New(fiber.StatusForbidden, problem.CodeForbidden, detail)
+	}
+	return user, nil
+}
+
+// audience returns who the user counts as when viewing or editing their own profile attributes.
+func (h *Handler) audience(user *models.User) attributes.Audience {
+	if user.IsAdmin() {
+		return attributes.AudienceAdmin
+	}
+	return attributes.AudienceOwner
+}
+
+// Problems shared by the handlers. The ErrorHandler copies them before adding request details.
+var (
+	errUnauthenticated    = problem.New(fiber.StatusUnauthorized, problem.CodeUnauthenticated, "Authentication is required.")
+	errSessionRevoked     = problem.New(fiber.StatusUnauthorized, problem.CodeSessionRevoked, "The session was logged out; log in again.")
+	errUserNotFound       = problem.New(fiber.StatusNotFound, problem.CodeUserNotFound, "The user does not exist.")
+	errEmailInUse         = problem.New(fiber.StatusBadRequest, problem.CodeEmailInUse, "The email address is already in use.")
+	errInvalidBody        = problem.New(fiber.StatusBadRequest, problem.CodeInvalidRequest, "The request body could not be parsed.")
+	errInvalidQuery       = problem.New(fiber.StatusBadRequest, problem.CodeInvalidRequest, "The query parameters are invalid.")
+	errPreconditionFailed = problem.New(fiber.StatusPreconditionFailed, problem.CodePreconditionFailed,
+		"The resource was changed since it was read; fetch it again and retry.")
+	errEditConflict = problem.New(fiber.StatusConflict, problem.CodeEditConflict,
+		"The resource was changed by another request at the same time; fetch it again and retry.")
+)
+
+// tokenProblem returns the problem for a JWT that could not be verified, telling expired tokens apart.
+func tokenProblem(err error) *problem.Problem {
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		return problem.New(fiber.StatusUnauthorized, problem.CodeTokenExpired, "The session has expired.")
+	}
+	return errUnauthenticated
+}
+
+// uintPtr returns a pointer to a copy of v, for populating optional audit fields.
+func uintPtr(v uint) *uint {
+	return &v
+}
+
+// parseUserId converts the string ID stored in the JWT claims to a numeric user ID.
+func parseUserId(id string) (uint, bool) {
+	parsed, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return uint(parsed), true
+}
@@ -0,0 +1,753 @@
+package controllers_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/config"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/controllers"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/models"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/problem"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/repository"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/routes"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/secrets"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/storage"
+	"github.com/gofiber/fiber/v3"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const testPassword = "correct horse"
+
+// testServer serves the application routes from a Handler backed by the in-memory repositories.
+type testServer struct {
+	t       *testing.T
+	app     *fiber.App
+	handler *controllers.Handler
+	users   *repository.MemoryUserRepository
+	secrets secrets.StaticProvider
+}
+
+// newTestServer returns a testServer with the default configuration and empty repositories.
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+
+	cfg := config.Default()
+	provider := secrets.StaticProvider{"JWT_SECRET_KEY": "test-secret"}
+	jwtSecret, err := secrets.Load(provider, "JWT_SECRET_KEY")
+	if err != nil {
+		t.Fatalf("load secret: %v", err)
+	}
+	blobs, err := storage.NewLocalStore(t.TempDir(), "/media")
+	if err != nil {
+		t.Fatalf("create blob store: %v", err)
+	}
+
+	users := repository.NewMemoryUserRepository()
+	handler := controllers.NewHandler(
+		cfg.Auth,
+		jwtSecret,
+		users,
+		repository.NewMemoryAuditRepository(),
+		repository.NewMemoryAttributeRepository(),
+		repository.NewMemoryRevisionRepository(),
+		repository.NewMemoryPreferencesRepository(),
+		repository.NewMemorySessionRepository(),
+		blobs,
+		cfg.Avatars,
+	)
+
+	app := fiber.New(fiber.Config{ErrorHandler: problem.ErrorHandler})
+	routes.Setup(app, handler)
+	return &testServer{t: t, app: app, handler: handler, users: users, secrets: provider}
+}
+
+// createUser stores a user with testPassword directly in the repository, hashed cheaply to keep tests fast.
+func (s *testServer) createUser(name, email string) *models.User {
+	s.t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte(testPassword), bcrypt.MinCost)
+	if err != nil {
+		s.t.Fatalf("hash password: %v", err)
+	}
+	user := &models.User{Name: name, Email: email, Password: hash}
+	if err := s.users.Create(context.Background(), user); err != nil {
+		s.t.Fatalf("create user: %v", err)
+	}
+	return user
+}
+
+// createAdmin stores an administrator with testPassword like createUser.
+func (s *testServer) createAdmin(name, email string) *models.User {
+	s.t.Helper()
+	user := s.createUser(name, email)
+	if err := s.users.SetRole(context.Background(), user.Id, models.RoleAdmin); err != nil {
+		s.t.Fatalf("grant administrator role: %v", err)
+	}
+	user.Role = models.RoleAdmin
+	return user
+}
+
+// login logs in with testPassword and returns the issued token.
+func (s *testServer) login(email string) string {
+	s.t.Helper()
+	resp, body := s.do(http.MethodPost, "/api/login", "", `{"email": "`+email+`", "password": "`+testPassword+`"}`)
+	if resp.StatusCode != http.StatusOK {
+		s.t.Fatalf("login %s: status %d: %s", email, resp.StatusCode, body)
+	}
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == "jwt" && cookie.Value != "" {
+			return cookie.Value
+		}
+	}
+	s.t.Fatalf("login %s: no token cookie", email)
+	return ""
+}
+
+// do sends a request with a JSON body, authenticated with the token if it is not empty, and returns the response
+// and its body. Extra headers are given as name, value pairs.
+func (s *testServer) do(method, path, token, body string, headers ...string) (*http.Response, string) {
+	s.t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	}
+	if token != "" {
+		req.Header.Set(fiber.HeaderAuthorization, "Bearer "+token)
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+
+	// Registration hashes with the production bcrypt cost, which takes longer than the default test timeout
+	resp, err := s.app.Test(req, 10*time.Second)
+	if err != nil {
+		s.t.Fatalf("%s %s: %v", method, path, err)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		s.t.Fatalf("%s %s: read body: %v", method, path, err)
+	}
+	return resp, string(data)
+}
+
+// expectProblem fails the test unless the response is a problem with the given status and code.
+func expectProblem(t *testing.T, resp *http.Response, body string, status int, code string) {
+	t.Helper()
+	var p problem.Problem
+	if err := json.Unmarshal([]byte(body), &p); err != nil {
+		t.Fatalf("decode problem: %v: %s", err, body)
+	}
+	if resp.StatusCode != status || p.Code != code {
+		t.Fatalf("got status %d code %q, want %d %q: %s", resp.StatusCode, p.Code, status, code, body)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	s := newTestServer(t)
+	s.createUser("Taken", "taken@example.com")
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		code   string
+	}{
+		{"valid", `{"name": " Ann ", "email": "ann@example.com", "password": "password1"}`, http.StatusCreated, ""},
+		{"duplicate email", `{"name": "Ann", "email": "TAKEN@example.com", "password": "password1"}`, http.StatusBadRequest, problem.CodeEmailInUse},
+		{"missing name", `{"email": "bob@example.com", "password": "password1"}`, http.StatusBadRequest, problem.CodeValidationFailed},
+		{"short password", `{"name": "Bob", "email": "bob@example.com", "password": "short"}`, http.StatusBadRequest, problem.CodeValidationFailed},
+		{"unknown field", `{"name": "Bob", "email": "bob@example.com", "password": "password1", "admin": true}`, http.StatusBadRequest, problem.CodeValidationFailed},
+		{"malformed", `{"name": `, http.StatusBadRequest, problem.CodeInvalidRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, body := s.do(http.MethodPost, "/api/register", "", tt.body)
+			if tt.code != "" {
+				expectProblem(t, resp, body, tt.status, tt.code)
+				return
+			}
+			if resp.StatusCode != tt.status {
+				t.Fatalf("got status %d, want %d: %s", resp.StatusCode, tt.status, body)
+			}
+		})
+	}
+
+	user, err := s.users.FindByEmail(context.Background(), "ann@example.com")
+	if err != nil {
+		t.Fatalf("registered user not stored: %v", err)
+	}
+	if user.Name != "Ann" || !user.CheckPassword("password1") {
+		t.Fatalf("registered user stored as %q with a wrong password hash", user.Name)
+	}
+}
+
+func TestLogin(t *testing.T) {
+	s := newTestServer(t)
+	s.createUser("Ann", "ann@example.com")
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		code   string
+	}{
+		{"valid", `{"email": "ann@example.com", "password": "` + testPassword + `"}`, http.StatusOK, ""},
+		{"wrong password", `{"email": "ann@example.com", "password": "wrong"}`, http.StatusUnauthorized, problem.CodeIncorrectPassword},
+		{"unknown user", `{"email": "bob@example.com", "password": "` + testPassword + `"}`, http.StatusNotFound, problem.CodeUserNotFound},
+		{"missing password", `{"email": "ann@example.com"}`, http.StatusBadRequest, problem.CodeValidationFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, body := s.do(http.MethodPost, "/api/login", "", tt.body)
+			if tt.code != "" {
+				expectProblem(t, resp, body, tt.status, tt.code)
+				return
+			}
+			if resp.StatusCode != tt.status {
+				t.Fatalf("got status %d, want %d: %s", resp.StatusCode, tt.status, body)
+			}
+		})
+	}
+
+	token := s.login("ann@example.com")
+	resp, body := s.do(http.MethodGet, "/api/user", token, "")
+	if resp.StatusCode != http.StatusOK || !strings.Contains(body, `"email":"ann@example.com"`) {
+		t.Fatalf("GET /api/user with the issued token: status %d: %s", resp.StatusCode, body)
+	}
+
+	resp, body = s.do(http.MethodGet, "/api/user", "not-a-token", "")
+	expectProblem(t, resp, body, http.StatusUnauthorized, problem.CodeUnauthenticated)
+}
+
+func TestUpdateUserIfMatch(t *testing.T) {
+	s := newTestServer(t)
+	s.createUser("Ann", "ann@example.com")
+	token := s.login("ann@example.com")
+
+	resp, _ := s.do(http.MethodGet, "/api/user", token, "")
+	etag := resp.Header.Get(fiber.HeaderETag)
+	if etag == "" {
+		t.Fatal("GET /api/user returned no ETag")
+	}
+
+	resp, body := s.do(http.MethodPatch, "/api/user", token, `{"name": "Annie"}`, fiber.HeaderIfMatch, etag)
+	if resp.StatusCode != http.StatusOK || !strings.Contains(body, `"name":"Annie"`) {
+		t.Fatalf("PATCH with the current ETag: status %d: %s", resp.StatusCode, body)
+	}
+	if resp.Header.Get(fiber.HeaderETag) == etag {
+		t.Fatal("ETag did not change after the update")
+	}
+
+	// The ETag read before the first update is now stale
+	resp, body = s.do(http.MethodPatch, "/api/user", token, `{"name": "Anne"}`, fiber.HeaderIfMatch, etag)
+	expectProblem(t, resp, body, http.StatusPreconditionFailed, problem.CodePreconditionFailed)
+
+	user, _ := s.users.FindByEmail(context.Background(), "ann@example.com")
+	if user.Name != "Annie" {
+		t.Fatalf("stale update was applied: name is %q", user.Name)
+	}
+}
+
+func TestPatchUser(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		status      int
+		code        string
+		check       func(t *testing.T, user *models.User)
+	}{
+		{
+			name:   "sets the members",
+			body:   `{"display_name": "Annie", "bio": " Hi "}`,
+			status: http.StatusOK,
+			check: func(t *testing.T, user *models.User) {
+				if user.DisplayName != "Annie" || user.Bio != "Hi" {
+					t.Errorf("display name %q, bio %q", user.DisplayName, user.Bio)
+				}
+			},
+		},
+		{
+			name:   "null removes optional fields",
+			body:   `{"bio": null, "handle": null, "phone": null}`,
+			status: http.StatusOK,
+			check: func(t *testing.T, user *models.User) {
+				if user.Bio != "" || user.Handle != nil || user.Phone != "" {
+					t.Errorf("bio %q, handle %v, phone %q", user.Bio, user.Handle, user.Phone)
+				}
+			},
+		},
+		{
+			name:   "omitted members are left unchanged",
+			body:   `{}`,
+			status: http.StatusOK,
+			check: func(t *testing.T, user *models.User) {
+				if user.Name != "Ann" || user.Bio != "Hello" || user.Handle == nil || *user.Handle != "ann" {
+					t.Errorf("user changed: %+v", user)
+				}
+			},
+		},
+		{
+			name:        "merge patch media type",
+			contentType: "application/merge-patch+json",
+			body:        `{"name": "Annie"}`,
+			status:      http.StatusOK,
+			check: func(t *testing.T, user *models.User) {
+				if user.Name != "Annie" {
+					t.Errorf("name %q", user.Name)
+				}
+			},
+		},
+		{"required fields cannot be removed", "", `{"name": null, "email": null}`, http.StatusBadRequest, problem.CodeValidationFailed, nil},
+		{"invalid member", "", `{"handle": "A!"}`, http.StatusBadRequest, problem.CodeValidationFailed, nil},
+		{"wrong type", "", `{"bio": 5}`, http.StatusBadRequest, problem.CodeValidationFailed, nil},
+		{"unknown member", "", `{"nickname": "ann"}`, http.StatusBadRequest, problem.CodeValidationFailed, nil},
+		{"not an object", "", `["bio"]`, http.StatusBadRequest, problem.CodeInvalidRequest, nil},
+		{"not JSON", "text/plain", `{"bio": "Hi"}`, http.StatusUnsupportedMediaType, problem.CodeUnsupportedMedia, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			user := s.createUser("Ann", "ann@example.com")
+			handle := "ann"
+			user.Handle, user.Bio, user.Phone = &handle, "Hello", "+359888123456"
+			if err := s.users.Update(context.Background(), user); err != nil {
+				t.Fatalf("update user: %v", err)
+			}
+			token := s.login("ann@example.com")
+			before, err := s.users.FindByEmail(context.Background(), "ann@example.com")
+			if err != nil {
+				t.Fatalf("find user: %v", err)
+			}
+
+			var headers []string
+			if tt.contentType != "" {
+				headers = []string{fiber.HeaderContentType, tt.contentType}
+			}
+			resp, body := s.do(http.MethodPatch, "/api/user", token, tt.body, headers...)
+			if tt.code != "" {
+				expectProblem(t, resp, body, tt.status, tt.code)
+			} else if resp.StatusCode != tt.status {
+				t.Fatalf("status %d, want %d: %s", resp.StatusCode, tt.status, body)
+			}
+
+			stored, err := s.users.FindByEmail(context.Background(), "ann@example.com")
+			if err != nil {
+				t.Fatalf("find user: %v", err)
+			}
+			if tt.check != nil {
+				tt.check(t, stored)
+			} else if stored.Version != before.Version {
+				t.Errorf("rejected patch changed the user: version %d, want %d", stored.Version, before.Version)
+			}
+		})
+	}
+}
+
+// TestPatchUserRemovesObjects removes the object members of the user, which resets each of their keys.
+func TestPatchUserRemovesObjects(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		visibility map[string]string
+		attributes []string // Keys of the remaining attribute values
+	}{
+		{"profile visibility", `{"profile_visibility": null}`, map[string]string{}, []string{"employee_id", "nickname"}},
+		{"attributes", `{"attributes": null}`, map[string]string{"email": "public"}, []string{"employee_id"}},
+		{"both", `{"attributes": null, "profile_visibility": null}`, map[string]string{}, []string{"employee_id"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s := newTestServer(t)
+			user := s.createUser("Ann", "ann@example.com")
+			user.ProfileVisibility = map[string]string{"email": "public"}
+			if err := s.users.Update(ctx, user); err != nil {
+				t.Fatalf("update user: %v", err)
+			}
+			// The user may only change the nickname; the employee ID stays
+			var values []models.UserAttribute
+			for _, def := range []models.AttributeDefinition{
+				{Key: "nickname", Label: "Nickname", Type: models.AttributeString, Visibility: models.VisibilityPublic, EditableBy: models.EditableByUser},
+				{Key: "employee_id", Label: "Employee ID", Type: models.AttributeString, Visibility: models.VisibilityPrivate, EditableBy: models.EditableByAdmin},
+			} {
+				if err := s.handler.Attributes.CreateDefinition(ctx, &def); err != nil {
+					t.Fatalf("create definition: %v", err)
+				}
+				values = append(values, models.UserAttribute{UserId: user.Id, AttributeId: def.Id, Value: `"` + def.Key + `"`})
+			}
+			if err := s.handler.Attributes.SetValues(ctx, user.Id, values, nil); err != nil {
+				t.Fatalf("set attribute values: %v", err)
+			}
+			token := s.login("ann@example.com")
+
+			resp, body := s.do(http.MethodPatch, "/api/user", token, tt.body)
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("status %d: %s", resp.StatusCode, body)
+			}
+
+			stored, err := s.users.FindByEmail(ctx, "ann@example.com")
+			if err != nil {
+				t.Fatalf("find user: %v", err)
+			}
+			if !maps.Equal(stored.ProfileVisibility, tt.visibility) {
+				t.Errorf("profile visibility %v, want %v", stored.ProfileVisibility, tt.visibility)
+			}
+			defs, err := s.handler.Attributes.ListDefinitions(ctx)
+			if err != nil {
+				t.Fatalf("list definitions: %v", err)
+			}
+			remaining, err := s.handler.Attributes.Values(ctx, user.Id)
+			if err != nil {
+				t.Fatalf("attribute values: %v", err)
+			}
+			var keys []string
+			for _, value := range remaining {
+				for _, def := range defs {
+					if def.Id == value.AttributeId {
+						keys = append(keys, def.Key)
+					}
+				}
+			}
+			slices.Sort(keys)
+			if !slices.Equal(keys, tt.attributes) {
+				t.Errorf("attribute values %v, want %v", keys, tt.attributes)
+			}
+		})
+	}
+}
+
+func TestGetUserIfNoneMatch(t *testing.T) {
+	s := newTestServer(t)
+	s.createUser("Ann", "ann@example.com")
+	token := s.login("ann@example.com")
+
+	resp, _ := s.do(http.MethodGet, "/api/user", token, "")
+	etag := resp.Header.Get(fiber.HeaderETag)
+	if resp.StatusCode != http.StatusOK || etag == "" {
+		t.Fatalf("GET /api/user: status %d, ETag %q", resp.StatusCode, etag)
+	}
+	if got := resp.Header.Get(fiber.HeaderCacheControl); got != "private, no-cache" {
+		t.Errorf("Cache-Control = %q", got)
+	}
+
+	tests := []struct {
+		header string
+		status int
+	}{
+		{etag, http.StatusNotModified},
+		{"W/" + etag, http.StatusNotModified},
+		{`"0-0-0", ` + etag, http.StatusNotModified},
+		{"*", http.StatusNotModified},
+		{`"0-0-0"`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		resp, body := s.do(http.MethodGet, "/api/user", token, "", fiber.HeaderIfNoneMatch, tt.header)
+		if resp.StatusCode != tt.status {
+			t.Errorf("If-None-Match %s: status %d, want %d", tt.header, resp.StatusCode, tt.status)
+		}
+		if tt.status == http.StatusNotModified && body != "" {
+			t.Errorf("If-None-Match %s: 304 with a body: %s", tt.header, body)
+		}
+	}
+}
+
+// TestUserETagAttributeDefinitions changes how the user's attribute values are shown, which changes the ETag.
+func TestUserETagAttributeDefinitions(t *testing.T) {
+	s := newTestServer(t)
+	s.createAdmin("Admin", "admin@example.com")
+	s.createUser("Ann", "ann@example.com")
+	adminToken := s.login("admin@example.com")
+	token := s.login("ann@example.com")
+
+	resp, body := s.do(http.MethodPost, "/api/admin/attributes", adminToken,
+		`{"key": "nickname", "label": "Nickname", "type": "string", "visibility": "private", "editable_by": "user"}`)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("POST /api/admin/attributes: status %d: %s", resp.StatusCode, body)
+	}
+	if resp, body := s.do(http.MethodPatch, "/api/user", token, `{"attributes": {"nickname": "Annie"}}`); resp.StatusCode != http.StatusOK {
+		t.Fatalf("PATCH /api/user: status %d: %s", resp.StatusCode, body)
+	}
+
+	tests := []struct {
+		method, path, body string
+	}{
+		{http.MethodPut, "/api/admin/attributes/nickname", `{"label": "Nickname", "type": "string", "visibility": "admin", "editable_by": "admin"}`},
+		{http.MethodDelete, "/api/admin/attributes/nickname", ""},
+	}
+	for _, tt := range tests {
+		resp, _ := s.do(http.MethodGet, "/api/user", token, "")
+		etag := resp.Header.Get(fiber.HeaderETag)
+
+		if resp, body := s.do(tt.method, tt.path, adminToken, tt.body); resp.StatusCode >= 300 {
+			t.Fatalf("%s %s: status %d: %s", tt.method, tt.path, resp.StatusCode, body)
+		}
+
+		resp, body := s.do(http.MethodGet, "/api/user", token, "", fiber.HeaderIfNoneMatch, etag)
+		if resp.StatusCode != http.StatusOK || resp.Header.Get(fiber.HeaderETag) == etag {
+			t.Errorf("after %s %s: If-None-Match with the old ETag: status %d, ETag %s: %s",
+				tt.method, tt.path, resp.StatusCode, resp.Header.Get(fiber.HeaderETag), body)
+		}
+		resp, body = s.do(http.MethodPatch, "/api/user", token, `{"bio": "Hi"}`, fiber.HeaderIfMatch, etag)
+		expectProblem(t, resp, body, http.StatusPreconditionFailed, problem.CodePreconditionFailed)
+	}
+}
+
+// racingUsers is a UserRepository in which another request updates the user just before every update.
+type racingUsers struct {
+	*repository.MemoryUserRepository
+}
+
+func (r racingUsers) Update(ctx context.Context, user *models.User) error {
+	other, err := r.MemoryUserRepository.FindByID(ctx, user.Id)
+	if err != nil {
+		return err
+	}
+	other.Bio = "changed concurrently"
+	if err := r.MemoryUserRepository.Update(ctx, other); err != nil {
+		return err
+	}
+	return r.MemoryUserRepository.Update(ctx, user)
+}
+
+func TestUpdateUserVersionConflict(t *testing.T) {
+	s := newTestServer(t)
+	s.createUser("Ann", "ann@example.com")
+	token := s.login("ann@example.com")
+	s.handler.Users = racingUsers{s.users}
+
+	resp, body := s.do(http.MethodPatch, "/api/user", token, `{"name": "Annie"}`)
+	expectProblem(t, resp, body, http.StatusConflict, problem.CodeEditConflict)
+
+	resp, body = s.do(http.MethodPut, "/api/user", token, `{"name": "Annie"}`, fiber.HeaderIfMatch, "*")
+	expectProblem(t, resp, body, http.StatusPreconditionFailed, problem.CodePreconditionFailed)
+
+	user, _ := s.users.FindByEmail(context.Background(), "ann@example.com")
+	if user.Name != "Ann" || user.Bio != "changed concurrently" {
+		t.Fatalf("conflicting update overwrote the concurrent one: name %q, bio %q", user.Name, user.Bio)
+	}
+}
+
+func TestDeleteUser(t *testing.T) {
+	s := newTestServer(t)
+	s.createUser("Ann", "ann@example.com")
+	s.createUser("Bob", "bob@example.com")
+	token := s.login("ann@example.com")
+
+	resp, body := s.do(http.MethodDelete, "/api/user", "", "")
+	expectProblem(t, resp, body, http.StatusUnauthorized, problem.CodeUnauthenticated)
+
+	resp, body = s.do(http.MethodDelete, "/api/user", token, "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("DELETE /api/user: status %d: %s", resp.StatusCode, body)
+	}
+	if _, err := s.users.FindByEmail(context.Background(), "ann@example.com"); err == nil {
+		t.Fatal("deleted user is still stored")
+	}
+	if _, err := s.users.FindByEmail(context.Background(), "bob@example.com"); err != nil {
+		t.Fatalf("other user was deleted too: %v", err)
+	}
+
+	resp, body = s.do(http.MethodDelete, "/api/user", token, "")
+	expectProblem(t, resp, body, http.StatusNotFound, problem.CodeUserNotFound)
+}
+
+func TestPasswordChangeRevokesOtherSessions(t *testing.T) {
+	s := newTestServer(t)
+	s.createUser("Ann", "ann@example.com")
+	s.createUser("Bob", "bob@example.com")
+	current := s.login("ann@example.com")
+	other := s.login("ann@example.com")
+	bob := s.login("bob@example.com")
+
+	// Other profile changes leave the sessions alone
+	resp, body := s.do(http.MethodPatch, "/api/user", current, `{"name": "Annie"}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("PATCH name: status %d: %s", resp.StatusCode, body)
+	}
+	if resp, body := s.do(http.MethodGet, "/api/user", other, ""); resp.StatusCode != http.StatusOK {
+		t.Fatalf("other session after a name change: status %d: %s", resp.StatusCode, body)
+	}
+
+	resp, body = s.do(http.MethodPatch, "/api/user", current, `{"password": "a much better passphrase"}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("PATCH password: status %d: %s", resp.StatusCode, body)
+	}
+
+	resp, body = s.do(http.MethodGet, "/api/user", other, "")
+	expectProblem(t, resp, body, http.StatusUnauthorized, problem.CodeSessionRevoked)
+	for name, token := range map[string]string{"current": current, "another user's": bob} {
+		if resp, body := s.do(http.MethodGet, "/api/user", token, ""); resp.StatusCode != http.StatusOK {
+			t.Errorf("%s session after the password change: status %d: %s", name, resp.StatusCode, body)
+		}
+	}
+
+	// The revoked session no longer counts as active
+	active, err := s.handler.Sessions.CountActive(context.Background(), time.Now())
+	if err != nil {
+		t.Fatalf("count active sessions: %v", err)
+	}
+	if active != 2 {
+		t.Errorf("%d active sessions, want 2", active)
+	}
+}
+
+func TestPublicProfile(t *testing.T) {
+	s := newTestServer(t)
+	ann := s.createUser("Ann Smith", "ann@example.com")
+	handle := "ann"
+	ann.Handle, ann.DisplayName, ann.Bio, ann.Phone = &handle, "Ann", "Hello", "+359888123456"
+	ann.ProfileVisibility = map[string]string{"email": "authenticated", "bio": "private"}
+	if err := s.users.Update(context.Background(), ann); err != nil {
+		t.Fatalf("update user: %v", err)
+	}
+	s.createUser("Bob", "bob@example.com")
+	token := s.login("bob@example.com")
+
+	tests := []struct {
+		name   string
+		path   string
+		token  string
+		fields []string
+	}{
+		{"anonymous", "/api/users/ann", "", []string{"handle", "display_name", "attributes"}},
+		{"signed in", "/api/users/ann", token, []string{"handle", "display_name", "email", "created_at", "attributes"}},
+		{"invalid token", "/api/users/ann", "not-a-token", []string{"handle", "display_name", "attributes"}},
+		{"handle in upper case", "/api/users/ANN", "", []string{"handle", "display_name", "attributes"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, body := s.do(http.MethodGet, tt.path, tt.token, "")
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("status %d: %s", resp.StatusCode, body)
+			}
+			var profile map[string]any
+			if err := json.Unmarshal([]byte(body), &profile); err != nil {
+				t.Fatalf("decode: %v: %s", err, body)
+			}
+			if len(profile) != len(tt.fields) {
+				t.Fatalf("profile %s, want the fields %v", body, tt.fields)
+			}
+			for _, field := range tt.fields {
+				if _, ok := profile[field]; !ok {
+					t.Fatalf("profile %s lacks %s", body, field)
+				}
+			}
+			if resp.Header.Get(fiber.HeaderCacheControl) != "private, no-cache" {
+				t.Errorf("Cache-Control = %q", resp.Header.Get(fiber.HeaderCacheControl))
+			}
+		})
+	}
+
+	resp, body := s.do(http.MethodGet, "/api/users/nobody", "", "")
+	expectProblem(t, resp, body, http.StatusNotFound, problem.CodeUserNotFound)
+}
+
+func TestAdminRole(t *testing.T) {
+	s := newTestServer(t)
+	s.createAdmin("Admin", "admin@example.com")
+	s.createUser("Ann", "ann@example.com")
+	adminToken := s.login("admin@example.com")
+	token := s.login("ann@example.com")
+
+	resp, body := s.do(http.MethodGet, "/api/admin/audit", adminToken, "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET /api/admin/audit as an administrator: status %d: %s", resp.StatusCode, body)
+	}
+	resp, body = s.do(http.MethodGet, "/api/admin/audit", token, "")
+	expectProblem(t, resp, body, http.StatusForbidden, problem.CodeForbidden)
+
+	// Neither the email address nor the request body can make a user an administrator
+	resp, body = s.do(http.MethodPatch, "/api/user", token, `{"role": "admin"}`)
+	expectProblem(t, resp, body, http.StatusBadRequest, problem.CodeValidationFailed)
+	resp, body = s.do(http.MethodPatch, "/api/user", token, `{"email": "root@example.com"}`)
+	if resp.StatusCode != http.StatusOK || !strings.Contains(body, `"role":"user"`) {
+		t.Fatalf("PATCH /api/user email: status %d: %s", resp.StatusCode, body)
+	}
+	resp, body = s.do(http.MethodGet, "/api/admin/audit", token, "")
+	expectProblem(t, resp, body, http.StatusForbidden, problem.CodeForbidden)
+
+	// Updates by the administrator keep the role
+	resp, body = s.do(http.MethodPatch, "/api/user", adminToken, `{"name": "Root"}`)
+	if resp.StatusCode != http.StatusOK || !strings.Contains(body, `"role":"admin"`) {
+		t.Fatalf("PATCH /api/user as an administrator: status %d: %s", resp.StatusCode, body)
+	}
+}
+
+func TestAttributeAdministration(t *testing.T) {
+	s := newTestServer(t)
+	s.createAdmin("Admin", "admin@example.com")
+	ann := s.createUser("Ann", "ann@example.com")
+	adminToken := s.login("admin@example.com")
+	token := s.login("ann@example.com")
+	definition := `{"key": "employee_id", "label": "Employee ID", "type": "string", "editable_by": "admin"}`
+	annAttributes := "/api/admin/users/" + strconv.FormatUint(uint64(ann.Id), 10) + "/attributes"
+
+	// Only administrators may call the attribute administration endpoints
+	for _, tt := range []struct {
+		method, path, body string
+	}{
+		{http.MethodPost, "/api/admin/attributes", definition},
+		{http.MethodPut, "/api/admin/attributes/employee_id", definition},
+		{http.MethodDelete, "/api/admin/attributes/employee_id", ""},
+		{http.MethodGet, annAttributes, ""},
+		{http.MethodPatch, annAttributes, `{"employee_id": "E1"}`},
+	} {
+		resp, body := s.do(tt.method, tt.path, token, tt.body)
+		expectProblem(t, resp, body, http.StatusForbidden, problem.CodeForbidden)
+	}
+
+	resp, body := s.do(http.MethodPost, "/api/admin/attributes", adminToken, definition)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("POST /api/admin/attributes: status %d: %s", resp.StatusCode, body)
+	}
+
+	// The attribute is read-only for the user but can be set by an administrator
+	resp, body = s.do(http.MethodPatch, "/api/user", token, `{"attributes": {"employee_id": "E1"}}`)
+	expectProblem(t, resp, body, http.StatusBadRequest, problem.CodeValidationFailed)
+	if !strings.Contains(body, `"code":"read_only"`) {
+		t.Fatalf("PATCH /api/user with an administrator attribute: %s", body)
+	}
+	resp, body = s.do(http.MethodPatch, annAttributes, adminToken, `{"employee_id": " E1 "}`)
+	if resp.StatusCode != http.StatusOK || !strings.Contains(body, `"employee_id":"E1"`) {
+		t.Fatalf("PATCH %s: status %d: %s", annAttributes, resp.StatusCode, body)
+	}
+	resp, body = s.do(http.MethodGet, "/api/user", token, "")
+	if resp.StatusCode != http.StatusOK || !strings.Contains(body, `"employee_id":"E1"`) {
+		t.Fatalf("GET /api/user after the administrator set an attribute: status %d: %s", resp.StatusCode, body)
+	}
+}
+
+func TestRotatedSigningKey(t *testing.T) {
+	s := newTestServer(t)
+	s.createUser("Ann", "ann@example.com")
+	token := s.login("ann@example.com")
+
+	s.secrets["JWT_SECRET_KEY"] = "rotated-secret"
+	if _, err := s.handler.JWTSecret.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+
+	// Tokens issued before the rotation are accepted until the previous key expires
+	resp, body := s.do(http.MethodGet, "/api/user", token, "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET /api/user with a token signed with the previous key: status %d: %s", resp.StatusCode, body)
+	}
+	newToken := s.login("ann@example.com")
+
+	s.handler.JWTSecret.SetPreviousTTL(0)
+	resp, body = s.do(http.MethodGet, "/api/user", token, "")
+	expectProblem(t, resp, body, http.StatusUnauthorized, problem.CodeUnauthenticated)
+	resp, body = s.do(http.MethodGet, "/api/user", newToken, "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET /api/user with a token issued after the rotation: status %d: %s", resp.StatusCode, body)
+	}
+}
@@ -1,91 +1,133 @@
 package controllers
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"strconv"
 	"time"
 
-	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/database"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/audit"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/logging"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/metrics"
 	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/models"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/problem"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/tracing"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/useragent"
 	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/utils"
 	"github.com/gofiber/fiber/v3"
 	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
 )
 
 // Login is an HTTP handler function that handles user login requests.
 // It expects a JSON request body with "email" and "password" fields.
 // If the email and password are valid, it generates a JWT token and sets it as a cookie in the response.
 // The token is valid for 24 hours. The function returns a JSON response with a "success" message, the user's name, and email.
-func Login(c fiber.Ctx) error {
-	// Declare a map to store the request body data
-	var data map[string]string
-
-	// Bind the request body to the data map
-	if err := c.Bind().Body(&data); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"message": "Invalid request body",
-		})
+func (h *Handler) Login(c fiber.Ctx) error {
+	// Parse the request body and check that email and password are provided
+	var req LoginRequest
+	if err := bindBody(c, &req); err != nil {
+		metrics.ObserveLogin(false, "invalid_request")
+		return err
 	}
 
-	// Check if email and password are provided
-	if data["email"] == "" || data["password"] == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"message": "Email and password are required",
-		})
-	}
-
-	// Declare a User model to store the retrieved user data
-	var user models.User
-	// Query the database for the user with the provided email
-	if err := database.DB.Where("email = ?", data["email"]).First(&user).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"message": "User not found",
+	// Query the repository for the user with the provided email
+	user, err := h.Users.FindByEmail(c.UserContext(), req.Email)
+	if err != nil {
+		metrics.ObserveLogin(false, "user_not_found")
+		h.Audit.Record(c, audit.Entry{
+			Action: audit.ActionLoginFailure,
+			Detail: "user not found",
 		})
+		return errUserNotFound
 	}
 
 	// Check if the provided password is correct
-	if !user.CheckPassword(data["password"]) {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"message": "Incorrect password",
+	_, span := tracing.Start(c.UserContext(), "bcrypt.compare")
+	compareStart := time.Now()
+	passwordMatches := user.CheckPassword(req.Password)
+	cost, _ := bcrypt.Cost(user.Password)
+	metrics.ObservePasswordHash("compare", cost, compareStart)
+	span.End()
+	if !passwordMatches {
+		metrics.ObserveLogin(false, "incorrect_password")
+		h.Audit.Record(c, audit.Entry{
+			Action:   audit.ActionLoginFailure,
+			TargetId: uintPtr(user.Id),
+			Detail:   "incorrect password",
 		})
+		return problem.New(fiber.StatusUnauthorized, problem.CodeIncorrectPassword, "The password is incorrect.")
 	}
 
 	// Set the token expiration time to 24 hours from now
 	expirationTime := time.Now().Add(24 * time.Hour).Unix()
 
+	// Identify the session, so changes made in it can be traced back to it
+	sessionId, err := newSessionId()
+	if err != nil {
+		metrics.ObserveLogin(false, "internal_error")
+		return problem.Internal("Failed to generate the session ID.", err)
+	}
+
 	// Create a new JWT token with claims
 	claims := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 		"id":  strconv.Itoa(int(user.Id)),
+		"jti": sessionId,
 		"exp": expirationTime,
 	})
 
 	// Get the secret key for signing the token
-	secretKey, err := utils.GetSecretKey()
+	secretKey, err := h.secretKey()
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"message": "Could not retrieve secret key",
-		})
+		metrics.ObserveLogin(false, "internal_error")
+		return problem.Internal("Could not retrieve the secret key.", err)
 	}
 
 	// Sign the token with the secret key
+	_, span = tracing.Start(c.UserContext(), "jwt.sign")
 	token, err := claims.SignedString([]byte(secretKey))
+	span.End()
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"message": "Failed to generate token",
-		})
+		metrics.ObserveLogin(false, "internal_error")
+		return problem.Internal("Failed to generate the token.", err)
 	}
 
-	// Create a cookie to store the JWT token
-	cookie := fiber.Cookie{
-		Name:     "jwt",
-		Value:    token,
-		Expires:  time.Now().Add(24 * time.Hour),
-		HTTPOnly: true,
-		Secure:   false,
-		SameSite: "Lax",
+	// Remember the session so the user can see where they are logged in and revoke it
+	agent := useragent.Parse(c.Get(fiber.HeaderUserAgent))
+	now := time.Now()
+	session := models.Session{
+		Id:         sessionId,
+		UserId:     user.Id,
+		IP:         utils.Truncate(c.IP(), models.MaxIPLength),
+		UserAgent:  utils.Truncate(c.Get(fiber.HeaderUserAgent), models.MaxUserAgentLength),
+		Browser:    agent.Browser,
+		OS:         agent.OS,
+		DeviceType: agent.DeviceType,
+		DeviceName: agent.DeviceName(),
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  time.Unix(expirationTime, 0),
+	}
+	if err := h.Sessions.Create(c.UserContext(), &session); err != nil {
+		metrics.ObserveLogin(false, "internal_error")
+		return problem.Internal("Failed to start the session.", err)
 	}
 
-	// Set the cookie in the response
-	c.Cookie(&cookie)
+	// Set the JWT token in the authentication cookie
+	h.setAuthCookie(c, token, time.Unix(expirationTime, 0))
+	metrics.ObserveLogin(true, "")
+
+	// Remember the time of the login on the profile
+	if err := h.Users.RecordLogin(c.UserContext(), user.Id, now); err != nil {
+		logging.FromContext(c.UserContext()).Warn("Could not record the login time", "error", err)
+	}
+
+	// Record the successful login in the audit log
+	h.Audit.Record(c, audit.Entry{
+		Action:   audit.ActionLoginSuccess,
+		ActorId:  uintPtr(user.Id),
+		TargetId: uintPtr(user.Id),
+	})
 
 	// Return a JSON response with success message, user name, and email
 	return c.JSON(fiber.Map{
@@ -94,3 +136,12 @@ func Login(c fiber.Ctx) error {
 		"email":   user.Email,
 	})
 }
+
+// newSessionId returns a random ID for a new session, stored in the "jti" claim of its token.
+func newSessionId() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
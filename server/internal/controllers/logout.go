@@ -3,22 +3,31 @@ package controllers
 import (
 	"time"
 
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/audit"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/logging"
 	"github.com/gofiber/fiber/v3"
 )
 
-// Logout clears the JWT cookie, logging the user out.
-func Logout(c fiber.Ctx) error {
-	// Create a new cookie to overwrite the existing JWT cookie
-	cookie := fiber.Cookie{
-		Name:     "jwt",
-		Value:    "",                         // Empty value to clear the cookie
-		Expires:  time.Now().Add(-time.Hour), // Set expiration in the past to invalidate
-		HTTPOnly: true,                       // Prevent JavaScript access for security
-		Secure:   false,                      // Set to true if using HTTPS
+// Logout clears the JWT cookie and revokes its session, logging the user out.
+func (h *Handler) Logout(c fiber.Ctx) error {
+	// Revoke the session and record the logout if the request carries a valid token
+	if claims, err := h.parseJWT(c); err == nil {
+		if id := sessionIdFromClaims(claims); id != "" {
+			if err := h.Sessions.Revoke(c.UserContext(), id, time.Now()); err != nil {
+				logging.FromContext(c.UserContext()).Error("Could not revoke the session", "error", err)
+			}
+		}
+		if userId, ok := userIdFromClaims(claims); ok {
+			h.Audit.Record(c, audit.Entry{
+				Action:   audit.ActionLogout,
+				ActorId:  uintPtr(userId),
+				TargetId: uintPtr(userId),
+			})
+		}
 	}
 
-	// Set the cookie in the response, effectively clearing it
-	c.Cookie(&cookie)
+	// Overwrite the existing JWT cookie, effectively clearing it
+	h.clearAuthCookie(c)
 
 	// Return a JSON response indicating successful logout
 	return c.JSON(fiber.Map{
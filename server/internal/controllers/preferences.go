@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/audit"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/models"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/preferences"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/problem"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/repository"
+	"github.com/gofiber/fiber/v3"
+)
+
+// GetPreferences returns the preferences of the authenticated user, with defaults filled in for every setting
+// they have not chosen.
+func (h *Handler) GetPreferences(c fiber.Ctx) error {
+	user, err := h.authenticatedUser(c)
+	if err != nil {
+		return err
+	}
+
+	document, err := h.loadPreferences(c, user.Id)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(preferences.Resolve(document, user.Locale))
+}
+
+// UpdatePreferences replaces the preferences of the authenticated user and returns them resolved.
+// Settings that are omitted take their defaults, and required security alerts cannot be disabled.
+func (h *Handler) UpdatePreferences(c fiber.Ctx) error {
+	user, err := h.authenticatedUser(c)
+	if err != nil {
+		return err
+	}
+
+	// Upgrade documents written against an older schema, then decode and validate them like any request body
+	if !strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEApplicationJSON) {
+		return problem.New(fiber.StatusUnsupportedMediaType, problem.CodeUnsupportedMedia, "The request body must be JSON.")
+	}
+	body, err := preferences.Upgrade(c.Body())
+	if errors.Is(err, preferences.ErrUnsupportedVersion) {
+		return problem.Validation(problem.FieldError{Field: "version", Code: problem.FieldInvalid, Message: "version is not a supported schema version"})
+	}
+	if err != nil {
+		return errInvalidBody
+	}
+	var req PreferencesRequest
+	if err := decodeBody(body, &req); err != nil {
+		return err
+	}
+
+	document := preferences.Document{
+		Version:            preferences.CurrentVersion,
+		Theme:              req.Theme,
+		Language:           req.Language,
+		EmailNotifications: req.EmailNotifications,
+		SecurityAlerts:     req.SecurityAlerts,
+	}
+	if fields := preferences.Check(&document); len(fields) > 0 {
+		return problem.Validation(fields...)
+	}
+
+	current, err := h.loadPreferences(c, user.Id)
+	if err != nil {
+		return err
+	}
+
+	encoded, err := json.Marshal(document)
+	if err != nil {
+		return problem.Internal("Failed to encode the preferences.", err)
+	}
+	err = h.Preferences.Save(c.UserContext(), &models.UserPreferences{
+		UserId:   user.Id,
+		Version:  document.Version,
+		Document: string(encoded),
+	})
+	if err != nil {
+		return problem.Internal("Failed to save the preferences.", err)
+	}
+
+	before := preferences.Resolve(current, user.Locale)
+	after := preferences.Resolve(document, user.Locale)
+	if changes := audit.Diff(preferences.Fields(before), preferences.Fields(after)); len(changes) > 0 {
+		h.Audit.Record(c, audit.Entry{
+			Action:   audit.ActionPreferencesUpdate,
+			ActorId:  uintPtr(user.Id),
+			TargetId: uintPtr(user.Id),
+			Changes:  changes,
+		})
+	}
+
+	return c.JSON(after)
+}
+
+// loadPreferences returns the stored preferences document of the user, upgraded to the current schema version,
+// or an empty document if they never saved any.
+func (h *Handler) loadPreferences(c fiber.Ctx, userId uint) (preferences.Document, error) {
+	stored, err := h.Preferences.Get(c.UserContext(), userId)
+	if errors.Is(err, repository.ErrNotFound) {
+		return preferences.Document{Version: preferences.CurrentVersion}, nil
+	}
+	if err != nil {
+		return preferences.Document{}, problem.Internal("Failed to load the preferences.", err)
+	}
+
+	document, err := preferences.Decode(stored.Document)
+	if err != nil {
+		return preferences.Document{}, problem.Internal("Failed to decode the preferences.", err)
+	}
+	return document, nil
+}
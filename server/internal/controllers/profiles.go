@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/attributes"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/models"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/privacy"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/problem"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/repository"
+	"github.com/gofiber/fiber/v3"
+)
+
+// publicProfile is the public projection of a user's profile. It is built field by field rather than by
+// embedding models.User, so fields that are added to users later never appear on public profiles by accident.
+// Fields the viewer may not see are omitted.
+type publicProfile struct {
+	Handle      string                     `json:"handle"`
+	Name        *string                    `json:"name,omitempty"`
+	DisplayName *string                    `json:"display_name,omitempty"`
+	Bio         *string                    `json:"bio,omitempty"`
+	AvatarURLs  map[string]string          `json:"avatar_urls,omitempty"`
+	Locale      *string                    `json:"locale,omitempty"`
+	Timezone    *string                    `json:"timezone,omitempty"`
+	Email       *string                    `json:"email,omitempty"`
+	Phone       *string                    `json:"phone,omitempty"`
+	CreatedAt   *time.Time                 `json:"created_at,omitempty"`
+	Attributes  map[string]json.RawMessage `json:"attributes"`
+}
+
+var errProfileNotFound = problem.New(fiber.StatusNotFound, problem.CodeUserNotFound, "No user has this handle.")
+
+// GetPublicProfile returns the public profile of the user with the handle in the URL. Authentication is optional:
+// anonymous visitors see the fields the user made public, signed-in users also see the fields visible to
+// authenticated users. Only custom attributes with public visibility are included.
+func (h *Handler) GetPublicProfile(c fiber.Ctx) error {
+	handle := strings.ToLower(strings.TrimSpace(c.Params("handle")))
+	user, err := h.Users.FindByHandle(c.UserContext(), handle)
+	if errors.Is(err, repository.ErrNotFound) {
+		return errProfileNotFound
+	}
+	if err != nil {
+		return problem.Internal("Failed to load the profile.", err)
+	}
+
+	// An invalid or expired token is treated like no token, as the page is public anyway
+	authenticated := false
+	if h.authToken(c) != "" {
+		_, err := h.authenticatedUser(c)
+		authenticated = err == nil
+	}
+
+	defs, err := h.Attributes.ListDefinitions(c.UserContext())
+	if err != nil {
+		return problem.Internal("Failed to load the profile attributes.", err)
+	}
+	values, err := h.Attributes.Values(c.UserContext(), user.Id)
+	if err != nil {
+		return problem.Internal("Failed to load the profile attributes.", err)
+	}
+
+	profile := h.publicProfile(user, authenticated)
+	profile.Attributes = attributes.Values(defs, values, attributes.AudiencePublic)
+
+	// The projection depends on whether the viewer is signed in
+	c.Set(fiber.HeaderCacheControl, "private, no-cache")
+	c.Vary(fiber.HeaderAuthorization, fiber.HeaderCookie)
+	return c.JSON(profile)
+}
+
+// publicProfile projects the user's profile onto the fields visible to a viewer who is signed in or not.
+func (h *Handler) publicProfile(user *models.User, authenticated bool) publicProfile {
+	visibility := privacy.Effective(user.ProfileVisibility)
+	visible := func(field string) bool {
+		return privacy.Visible(visibility[field], authenticated)
+	}
+	show := func(field, value string) *string {
+		if !visible(field) {
+			return nil
+		}
+		return &value
+	}
+
+	profile := publicProfile{
+		Handle:      *user.Handle,
+		Name:        show("name", user.Name),
+		DisplayName: show("display_name", user.DisplayName),
+		Bio:         show("bio", user.Bio),
+		Locale:      show("locale", user.Locale),
+		Timezone:    show("timezone", user.Timezone),
+		Email:       show("email", user.Email),
+		Phone:       show("phone", user.Phone),
+	}
+	if visible("created_at") {
+		profile.CreatedAt = &user.CreatedAt
+	}
+	if visible("avatar") {
+		profile.AvatarURLs = h.avatarURLs(user)
+	}
+	return profile
+}
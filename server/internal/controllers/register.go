@@ -1,67 +1,85 @@
 package controllers
 
 import (
-	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/database"
+	"errors"
+	"time"
+
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/audit"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/metrics"
 	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/models"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/problem"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/repository"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/tracing"
 	"github.com/gofiber/fiber/v3"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// registrationCost is the bcrypt cost of the passwords chosen at registration.
+const registrationCost = 14
+
 // Register creates a new user account. It expects a request body with the following fields:
 // - name: the user's name
 // - email: the user's email address
 // - password: the user's password
 //
-// If the email is already registered, it returns a 400 Bad Request error with a message.
-// If the password fails to hash, it returns a 500 Internal Server Error with a message.
+// If a field is missing or invalid, it returns a 400 validation_failed problem listing the fields.
+// If the email is already registered, it returns a 400 email_in_use problem.
+// If the password fails to hash, it returns a 500 internal_error problem.
 // Otherwise, it creates a new user in the database and returns a 201 Created response with the user's details (excluding the password).
-func Register(c fiber.Ctx) error {
-	var data map[string]string
-
-	// Parse the request body into a data map
-	if err := c.Bind().Body(&data); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"message": "Invalid request body",
-		})
-	}
-
-	// Validate required fields: name, email, and password
-	if data["name"] == "" || data["email"] == "" || data["password"] == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"message": "Name, email, and password are required",
-		})
+func (h *Handler) Register(c fiber.Ctx) error {
+	// Parse and validate the request body
+	var req RegisterRequest
+	if err := bindBody(c, &req); err != nil {
+		metrics.ObserveRegistration(false, "invalid_request")
+		return err
 	}
 
 	// Check if the email is already registered in the database
-	var existingUser models.User
-	if err := database.DB.Where("email = ?", data["email"]).First(&existingUser).Error; err == nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"message": "Email is already in use",
-		})
+	if _, err := h.Users.FindByEmail(c.UserContext(), req.Email); err == nil {
+		metrics.ObserveRegistration(false, "duplicate_email")
+		return errEmailInUse
 	}
 
 	// Hash the password using bcrypt for secure storage
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(data["password"]), 14)
+	_, span := tracing.Start(c.UserContext(), "bcrypt.hash")
+	hashStart := time.Now()
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), registrationCost)
+	metrics.ObservePasswordHash("hash", registrationCost, hashStart)
+	span.End()
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"message": "Failed to hash password",
-		})
+		metrics.ObserveRegistration(false, "internal_error")
+		return problem.Internal("Failed to hash the password.", err)
 	}
 
 	// Create a new user instance with the provided data
 	user := models.User{
-		Name:     data["name"],
-		Email:    data["email"],
+		Name:     req.Name,
+		Email:    req.Email,
 		Password: hashedPassword,
 	}
 
-	// Save the new user to the database
-	if err := database.DB.Create(&user).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"message": "Failed to create user",
-		})
+	// Save the new user
+	if err := h.Users.Create(c.UserContext(), &user); err != nil {
+		if errors.Is(err, repository.ErrDuplicateEmail) {
+			metrics.ObserveRegistration(false, "duplicate_email")
+			return errEmailInUse
+		}
+		metrics.ObserveRegistration(false, "internal_error")
+		return problem.Internal("Failed to create the user.", err)
 	}
 
+	// Record the registration in the audit log
+	metrics.ObserveRegistration(true, "")
+	h.Audit.Record(c, audit.Entry{
+		Action:   audit.ActionRegister,
+		ActorId:  uintPtr(user.Id),
+		TargetId: uintPtr(user.Id),
+		Changes: audit.Diff(map[string]any{}, map[string]any{
+			"name":  user.Name,
+			"email": user.Email,
+		}),
+	})
+
 	// Return a success response with the created user's details (excluding password)
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
 		"message": "User created successfully",
@@ -0,0 +1,230 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/problem"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/validation"
+	"github.com/gofiber/fiber/v3"
+)
+
+// RegisterRequest is the body of POST /api/register. Passwords are limited to 72 bytes because bcrypt
+// ignores anything longer; they are neither trimmed nor normalised, so they are used exactly as typed.
+type RegisterRequest struct {
+	Name     string `json:"name" validate:"trim,nfc,required,nocontrol,max=100"`
+	Email    string `json:"email" validate:"trim,nfc,required,email,max=254"`
+	Password string `json:"password" validate:"required,min=8,maxbytes=72"`
+}
+
+// LoginRequest is the body of POST /api/login. Passwords are only checked against the stored hash,
+// so accounts created before the length bounds were introduced can still log in.
+type LoginRequest struct {
+	Email    string `json:"email" validate:"trim,nfc,required"`
+	Password string `json:"password" validate:"required"`
+}
+
+// UpdateUserRequest is the body of PUT and PATCH /api/user. Omitted fields are left unchanged, as is the password
+// if it is empty. The optional profile fields are cleared by sending an empty string (or null in a merge patch).
+type UpdateUserRequest struct {
+	Name     *string `json:"name" validate:"trim,nfc,required,nocontrol,max=100"`
+	Email    *string `json:"email" validate:"trim,nfc,required,email,max=254"`
+	Password *string `json:"password" validate:"min=8,maxbytes=72"`
+
+	DisplayName *string `json:"display_name" validate:"trim,nfc,nocontrol,max=100"`
+	Handle      *string `json:"handle" validate:"trim,lower,handle"`
+	Bio         *string `json:"bio" validate:"trim,nfc,max=500"`
+	Locale      *string `json:"locale" validate:"trim,locale"`
+	Timezone    *string `json:"timezone" validate:"trim,timezone"`
+	Phone       *string `json:"phone" validate:"phone"`
+
+	// Attributes holds custom attribute values by key, validated against their definitions. Omitted
+	// attributes are left unchanged; null or an empty string removes a value.
+	Attributes map[string]json.RawMessage `json:"attributes"`
+
+	// ProfileVisibility sets who may see each field on the public profile: "public", "authenticated" or
+	// "private". Omitted fields are left unchanged; null resets a field to its default.
+	ProfileVisibility map[string]*string `json:"profile_visibility"`
+}
+
+// AttributeDefinitionRequest is the body of POST /api/admin/attributes and PUT /api/admin/attributes/:key.
+// In PUT requests the key is taken from the URL. Rules that do not apply to the type must be omitted.
+type AttributeDefinitionRequest struct {
+	Key        string   `json:"key" validate:"trim,lower"`
+	Label      string   `json:"label" validate:"trim,nfc,required,nocontrol,max=100"`
+	Type       string   `json:"type" validate:"trim,lower,required"`
+	Required   bool     `json:"required"`
+	Visibility string   `json:"visibility" validate:"trim,lower"`
+	EditableBy string   `json:"editable_by" validate:"trim,lower"`
+	MinLength  *int     `json:"min_length"`
+	MaxLength  *int     `json:"max_length"`
+	Pattern    string   `json:"pattern" validate:"max=255"`
+	Min        *float64 `json:"min"`
+	Max        *float64 `json:"max"`
+	Options    []string `json:"options"`
+}
+
+// PreferencesRequest is the body of PUT /api/user/preferences. It replaces the stored preferences; omitted settings
+// take their defaults. Documents of an older schema version are upgraded before they are decoded.
+type PreferencesRequest struct {
+	Version            int             `json:"version"`
+	Theme              string          `json:"theme" validate:"trim,lower"`
+	Language           string          `json:"language" validate:"trim,locale"`
+	EmailNotifications map[string]bool `json:"email_notifications"`
+	SecurityAlerts     map[string]bool `json:"security_alerts"`
+}
+
+// mimeMergePatch is the media type of RFC 7396 JSON merge patches.
+const mimeMergePatch = "application/merge-patch+json"
+
+// bindBody decodes the JSON request body into dst, rejecting unknown fields and values of the wrong type,
+// and then normalises and validates it. It returns a problem describing every invalid field.
+func bindBody(c fiber.Ctx, dst any) error {
+	if !strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEApplicationJSON) {
+		return problem.New(fiber.StatusUnsupportedMediaType, problem.CodeUnsupportedMedia, "The request body must be JSON.")
+	}
+	return decodeBody(c.Body(), dst)
+}
+
+// bindMergePatch decodes an RFC 7396 JSON merge patch into dst like bindBody. Members that are omitted are
+// left unchanged. Members set to null remove optional fields: string fields arrive in dst as empty strings,
+// and object fields as a null for each of the keys the target document has in them, as returned by keys,
+// so every key is removed. Required fields cannot be removed.
+func bindMergePatch(c fiber.Ctx, dst any, keys func(member string) ([]string, error)) error {
+	contentType := c.Get(fiber.HeaderContentType)
+	if !strings.HasPrefix(contentType, mimeMergePatch) && !strings.HasPrefix(contentType, fiber.MIMEApplicationJSON) {
+		return problem.New(fiber.StatusUnsupportedMediaType, problem.CodeUnsupportedMedia, "The request body must be a JSON merge patch.")
+	}
+
+	body := c.Body()
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(body, &members); err == nil {
+		var fields []problem.FieldError
+		removed := false
+		for name, value := range members {
+			if string(value) != "null" {
+				continue
+			}
+			if validation.IsRequired(dst, name) {
+				fields = append(fields, problem.FieldError{
+					Field:   name,
+					Code:    problem.FieldRequired,
+					Message: name + " cannot be removed",
+				})
+				continue
+			}
+			switch validation.FieldKind(dst, name) {
+			case reflect.String:
+				members[name] = json.RawMessage(`""`)
+			case reflect.Map:
+				memberKeys, err := keys(name)
+				if err != nil {
+					return err
+				}
+				nulls := make(map[string]json.RawMessage, len(memberKeys))
+				for _, key := range memberKeys {
+					nulls[key] = json.RawMessage("null")
+				}
+				if members[name], err = json.Marshal(nulls); err != nil {
+					return problem.Internal("Failed to apply the merge patch.", err)
+				}
+			default:
+				continue
+			}
+			removed = true
+		}
+		if len(fields) > 0 {
+			sort.Slice(fields, func(i, j int) bool { return fields[i].Field < fields[j].Field })
+			return problem.Validation(fields...)
+		}
+		if removed {
+			if body, err = json.Marshal(members); err != nil {
+				return problem.Internal("Failed to apply the merge patch.", err)
+			}
+		}
+	}
+	return decodeBody(body, dst)
+}
+
+// bindAttributePatch decodes a JSON merge patch of attribute values by key. Validation against the
+// attribute definitions is left to attributes.Apply.
+func bindAttributePatch(c fiber.Ctx) (map[string]json.RawMessage, error) {
+	contentType := c.Get(fiber.HeaderContentType)
+	if !strings.HasPrefix(contentType, mimeMergePatch) && !strings.HasPrefix(contentType, fiber.MIMEApplicationJSON) {
+		return nil, problem.New(fiber.StatusUnsupportedMediaType, problem.CodeUnsupportedMedia, "The request body must be a JSON merge patch.")
+	}
+	var patch map[string]json.RawMessage
+	if err := json.Unmarshal(c.Body(), &patch); err != nil || patch == nil {
+		return nil, errInvalidBody
+	}
+	return patch, nil
+}
+
+// decodeBody decodes and validates a JSON request body.
+func decodeBody(body []byte, dst any) error {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(dst); err != nil {
+		if field := decodeFieldError(err); field != nil {
+			return problem.Validation(*field)
+		}
+		return errInvalidBody
+	}
+	if decoder.More() {
+		return errInvalidBody
+	}
+
+	if fields := validation.Struct(dst); len(fields) > 0 {
+		return problem.Validation(fields...)
+	}
+	return nil
+}
+
+// decodeFieldError returns the field error for JSON decoding errors caused by a single field,
+// or nil if the body is malformed as a whole.
+func decodeFieldError(err error) *problem.FieldError {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return &problem.FieldError{
+			Field:   typeErr.Field,
+			Code:    problem.FieldInvalid,
+			Message: fmt.Sprintf("%s must be a %s", typeErr.Field, jsonTypeName(typeErr.Type.String())),
+		}
+	}
+
+	// encoding/json has no error type for unknown fields
+	if name, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		name = strings.Trim(name, `"`)
+		return &problem.FieldError{
+			Field:   name,
+			Code:    problem.FieldUnknown,
+			Message: name + " is not a known field",
+		}
+	}
+	return nil
+}
+
+// jsonTypeName describes a Go type by its JSON equivalent.
+func jsonTypeName(goType string) string {
+	switch strings.TrimPrefix(goType, "*") {
+	case "string":
+		return "string"
+	case "bool":
+		return "boolean"
+	case "int", "int64", "uint", "uint64", "float64":
+		return "number"
+	}
+	switch {
+	case strings.HasPrefix(goType, "map["):
+		return "object"
+	case strings.HasPrefix(goType, "[]"):
+		return "array"
+	default:
+		return "valid value"
+	}
+}
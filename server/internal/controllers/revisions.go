@@ -0,0 +1,144 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/logging"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/models"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/problem"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/repository"
+	"github.com/gofiber/fiber/v3"
+)
+
+var errRevisionNotFound = problem.New(fiber.StatusNotFound, problem.CodeNotFound, "The revision does not exist.")
+
+// ListRevisions returns the history of the authenticated user's profile changes, newest first.
+// The query parameters field, limit and offset narrow down the result.
+func (h *Handler) ListRevisions(c fiber.Ctx) error {
+	user, err := h.authenticatedUser(c)
+	if err != nil {
+		return err
+	}
+
+	filter := repository.RevisionFilter{UserId: user.Id, Field: c.Query("field")}
+	filter.Limit, filter.Offset, err = pageFromQuery(c)
+	if err != nil {
+		return errInvalidQuery
+	}
+
+	revisions, total, err := h.Revisions.List(c.UserContext(), filter)
+	if err != nil {
+		return problem.Internal("Failed to load the profile history.", err)
+	}
+
+	return c.JSON(fiber.Map{
+		"revisions": revisions,
+		"total":     total,
+	})
+}
+
+// RevertRevision restores the field changed by the revision with the ID in the URL to its value before that
+// change and returns the updated user. The old value goes through the same validation as a PATCH /api/user,
+// so it is rejected if it is no longer valid or, like a handle or email, has since been taken by another user.
+// An If-Match header is honoured like in PatchUser.
+func (h *Handler) RevertRevision(c fiber.Ctx) error {
+	user, err := h.authenticatedUser(c)
+	if err != nil {
+		return err
+	}
+
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return errRevisionNotFound
+	}
+	revision, err := h.Revisions.Find(c.UserContext(), uint(id))
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && revision.UserId != user.Id) {
+		return errRevisionNotFound
+	}
+	if err != nil {
+		return problem.Internal("Failed to load the revision.", err)
+	}
+
+	// Validate the old value exactly like a request setting it
+	body, err := revertPatch(revision)
+	if err != nil {
+		return problem.Internal("Failed to restore the revision.", err)
+	}
+	var req UpdateUserRequest
+	if err := decodeBody(body, &req); err != nil {
+		return err
+	}
+
+	if err := h.saveUser(c, user, req, &revision.Id); err != nil {
+		return err
+	}
+
+	return h.sendUser(c, user)
+}
+
+// revertPatch returns the update request body that sets the field changed by the revision to its old value.
+func revertPatch(revision *models.ProfileRevision) ([]byte, error) {
+	if key, ok := strings.CutPrefix(revision.Field, "attributes."); ok {
+		value := json.RawMessage("null")
+		if revision.OldValue != "" {
+			value = json.RawMessage(revision.OldValue)
+		}
+		return json.Marshal(map[string]any{"attributes": map[string]json.RawMessage{key: value}})
+	}
+	if field, ok := strings.CutPrefix(revision.Field, "profile_visibility."); ok {
+		return json.Marshal(map[string]any{"profile_visibility": map[string]string{field: revision.OldValue}})
+	}
+	return json.Marshal(map[string]string{revision.Field: revision.OldValue})
+}
+
+// recordRevisions stores a revision for every field whose value differs between before and after, made by the
+// actor in the current session. Failures are logged rather than returned, as the change itself has been saved.
+func (h *Handler) recordRevisions(c fiber.Ctx, userId, actorId uint, before, after map[string]any, revertOf *uint) {
+	sessionId := ""
+	if claims, err := h.parseJWT(c); err == nil {
+		sessionId = sessionIdFromClaims(claims)
+	}
+
+	var revisions []models.ProfileRevision
+	for field, newValue := range after {
+		oldValue := before[field]
+		if oldValue == newValue {
+			continue
+		}
+		revisions = append(revisions, models.ProfileRevision{
+			UserId:    userId,
+			Field:     field,
+			OldValue:  revisionValue(field, oldValue),
+			NewValue:  revisionValue(field, newValue),
+			ActorId:   uintPtr(actorId),
+			SessionId: sessionId,
+			RevertOf:  revertOf,
+		})
+	}
+	sort.Slice(revisions, func(i, j int) bool { return revisions[i].Field < revisions[j].Field })
+
+	if err := h.Revisions.Append(c.UserContext(), revisions); err != nil {
+		logging.FromContext(c.UserContext()).Error("Could not record the profile revisions", "user_id", userId, "error", err)
+	}
+}
+
+// revisionValue formats a field value for a revision: custom attribute values as JSON, "" if unset,
+// and other fields as they are.
+func revisionValue(field string, value any) string {
+	if strings.HasPrefix(field, "attributes.") {
+		if value == nil {
+			return ""
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return ""
+		}
+		return string(encoded)
+	}
+	text, _ := value.(string)
+	return text
+}
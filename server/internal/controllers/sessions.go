@@ -0,0 +1,163 @@
+package controllers
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/audit"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/logging"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/models"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/problem"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/repository"
+	"github.com/gofiber/fiber/v3"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// sessionTouchInterval is how often the last use of a session is written to the database.
+const sessionTouchInterval = time.Minute
+
+var errSessionNotFound = problem.New(fiber.StatusNotFound, problem.CodeNotFound, "The session does not exist.")
+
+// sessionResponse is a session as returned to its user, marking the session the request was made in.
+type sessionResponse struct {
+	models.Session
+	Current bool `json:"current"`
+}
+
+// checkSession rejects tokens whose session was logged out or revoked, and records that the session was used.
+// Tokens issued before sessions were recorded have no stored session and are accepted until they expire.
+func (h *Handler) checkSession(c fiber.Ctx, claims *jwt.MapClaims) error {
+	id := sessionIdFromClaims(claims)
+	if id == "" {
+		return nil
+	}
+	session, err := h.Sessions.Find(c.UserContext(), id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return problem.Internal("Failed to load the session.", err)
+	}
+	if session.RevokedAt != nil {
+		return errSessionRevoked
+	}
+
+	if now := time.Now(); now.Sub(session.LastSeenAt) >= sessionTouchInterval {
+		if err := h.Sessions.Touch(c.UserContext(), id, now); err != nil {
+			logging.FromContext(c.UserContext()).Warn("Could not record the session use", "error", err)
+		}
+	}
+	return nil
+}
+
+// currentSessionId returns the ID of the session the request was made in, or "" if it has none.
+func (h *Handler) currentSessionId(c fiber.Ctx) string {
+	claims, err := h.parseJWT(c)
+	if err != nil {
+		return ""
+	}
+	return sessionIdFromClaims(claims)
+}
+
+// ListSessions returns the active sessions of the authenticated user, newest first, marking the current one.
+// With all=true, sessions that were logged out, revoked or have expired are included as well, as a login history.
+// The query parameters limit and offset page through the result.
+func (h *Handler) ListSessions(c fiber.Ctx) error {
+	user, err := h.authenticatedUser(c)
+	if err != nil {
+		return err
+	}
+
+	filter := repository.SessionFilter{UserId: user.Id, ActiveAt: time.Now()}
+	if all := c.Query("all"); all != "" {
+		includeEnded, err := strconv.ParseBool(all)
+		if err != nil {
+			return errInvalidQuery
+		}
+		if includeEnded {
+			filter.ActiveAt = time.Time{}
+		}
+	}
+	filter.Limit, filter.Offset, err = pageFromQuery(c)
+	if err != nil {
+		return errInvalidQuery
+	}
+
+	sessions, total, err := h.Sessions.List(c.UserContext(), filter)
+	if err != nil {
+		return problem.Internal("Failed to load the sessions.", err)
+	}
+
+	current := h.currentSessionId(c)
+	response := make([]sessionResponse, len(sessions))
+	for i, session := range sessions {
+		response[i] = sessionResponse{Session: session, Current: session.Id == current}
+	}
+
+	return c.JSON(fiber.Map{
+		"sessions": response,
+		"total":    total,
+	})
+}
+
+// RevokeSession revokes the session with the ID in the URL, so its token can no longer be used. Revoking the
+// current session logs the user out like Logout.
+func (h *Handler) RevokeSession(c fiber.Ctx) error {
+	user, err := h.authenticatedUser(c)
+	if err != nil {
+		return err
+	}
+
+	session, err := h.Sessions.Find(c.UserContext(), c.Params("id"))
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && session.UserId != user.Id) {
+		return errSessionNotFound
+	}
+	if err != nil {
+		return problem.Internal("Failed to load the session.", err)
+	}
+
+	if err := h.Sessions.Revoke(c.UserContext(), session.Id, time.Now()); err != nil {
+		return problem.Internal("Failed to revoke the session.", err)
+	}
+
+	h.Audit.Record(c, audit.Entry{
+		Action:   audit.ActionSessionRevoke,
+		ActorId:  uintPtr(user.Id),
+		TargetId: uintPtr(user.Id),
+		Detail:   session.Id,
+	})
+
+	if session.Id == h.currentSessionId(c) {
+		h.clearAuthCookie(c)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// RevokeOtherSessions signs the authenticated user out everywhere else by revoking every active session except
+// the current one, and returns how many sessions it revoked.
+func (h *Handler) RevokeOtherSessions(c fiber.Ctx) error {
+	user, err := h.authenticatedUser(c)
+	if err != nil {
+		return err
+	}
+
+	revoked, err := h.Sessions.RevokeOthers(c.UserContext(), user.Id, h.currentSessionId(c), time.Now())
+	if err != nil {
+		return problem.Internal("Failed to revoke the sessions.", err)
+	}
+
+	if revoked > 0 {
+		h.Audit.Record(c, audit.Entry{
+			Action:   audit.ActionSessionRevoke,
+			ActorId:  uintPtr(user.Id),
+			TargetId: uintPtr(user.Id),
+			Detail:   "all other sessions",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"revoked": revoked,
+	})
+}
@@ -1,71 +1,79 @@
 package database
 
 import (
-	"fmt"
-	"log"
-	"os"
+	"log/slog"
+	"time"
 
-	"github.com/joho/godotenv"
-	"gorm.io/driver/mysql"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/config"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/logging"
 	"gorm.io/gorm"
 )
 
-// DB is a global variable that holds the database connection
-var DB *gorm.DB
+// maxConnectBackoff caps the delay between connection attempts.
+const maxConnectBackoff = 30 * time.Second
 
-// envLoaded is a flag to track whether the environment variables have been loaded
-var envLoaded = false
-
-// LoadEnv loads the environment variables from the .env file.
-// If the environment variables have already been loaded, this function does nothing.
-func LoadEnv() {
-	if !envLoaded {
-		err := godotenv.Load()
+// Connect establishes a connection to the configured database, configures the connection pool and returns it.
+// If password is not nil it supplies the database password for every new connection, so rotated passwords are picked up.
+// Failed connection attempts are retried with exponential backoff; if every attempt fails, the function will log a fatal error.
+func Connect(settings config.DatabaseConfig, password func() string) *gorm.DB {
+	// Open a connection to the database, retrying with backoff
+	var connection *gorm.DB
+	var dialector gorm.Dialector
+	var err error
+	backoff := settings.ConnectBackoff
+	for attempt := 1; ; attempt++ {
+		// Pick the driver and build its connection settings; failed attempts close the previous connection pool
+		dialector, err = newDialector(settings, password)
 		if err != nil {
-			log.Fatalf("Error loading .env file: %v", err)
+			logging.Fatal("Database configuration error", "error", err)
 		}
-		envLoaded = true
-	}
-}
-
-// getCredentials retrieves the database connection credentials from the environment variables.
-// If any of the required environment variables are missing, an error is returned.
-func getCredentials() (string, string, string, string, error) {
-	LoadEnv()
 
-	// Retrieve database credentials from environment variables
-	username := os.Getenv("DB_USERNAME")
-	password := os.Getenv("DB_PASSWORD")
-	ip := os.Getenv("DB_IP")
-	database := os.Getenv("DB_NAME")
-
-	// Check if any required credentials are missing
-	if username == "" || password == "" || ip == "" || database == "" {
-		return "", "", "", "", fmt.Errorf("missing required database environment variables")
+		connection, err = gorm.Open(dialector, &gorm.Config{
+			TranslateError: true,         // Report driver-specific errors such as duplicate keys as gorm errors
+			Logger:         slogLogger{}, // Log through slog with the request logger
+		})
+		if err == nil {
+			break
+		}
+		if connection != nil {
+			// Release the pool created by the failed attempt
+			if sqlDB, dbErr := connection.DB(); dbErr == nil {
+				sqlDB.Close()
+			}
+		}
+		if attempt >= settings.ConnectAttempts {
+			logging.Fatal("Could not connect to the database", "attempts", attempt, "error", err)
+		}
+		slog.Warn("Could not connect to the database, retrying", "attempt", attempt, "max_attempts", settings.ConnectAttempts, "retry_in", backoff, "error", err)
+		time.Sleep(backoff)
+		backoff = min(backoff*2, maxConnectBackoff)
 	}
 
-	return username, password, ip, database, nil
-}
-
-// Connect establishes a connection to the database using the credentials retrieved from the environment variables.
-// If any error occurs during the connection process, the function will log a fatal error.
-func Connect() {
-	// Get database credentials
-	username, password, ip, database, err := getCredentials()
+	// Configure the connection pool
+	sqlDB, err := connection.DB()
 	if err != nil {
-		log.Fatalf("Database credentials error: %v", err)
+		logging.Fatal("Could not configure the database connection", "error", err)
 	}
+	sqlDB.SetMaxOpenConns(settings.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(settings.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(settings.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(settings.ConnMaxIdleTime)
 
-	// Construct the Data Source Name (DSN) string
-	dsn := fmt.Sprintf("%s:%s@tcp(%s)/%s?charset=utf8mb4&parseTime=True&loc=Local", username, password, ip, database)
+	// SQLite allows a single writer, and every connection to an in-memory database sees a separate database
+	if dialector.Name() == DriverSQLite {
+		sqlDB.SetMaxOpenConns(1)
+	}
 
-	// Open a connection to the database
-	connection, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
-	if err != nil {
-		log.Fatalf("Could not connect to the database: %v", err)
+	// Bound every query by the configured timeout
+	if err := registerQueryTimeout(connection, settings.QueryTimeout); err != nil {
+		logging.Fatal("Could not register the query timeout", "error", err)
+	}
+
+	// Trace every statement as a child of the request span
+	if err := registerTracing(connection); err != nil {
+		logging.Fatal("Could not register database tracing", "error", err)
 	}
 
-	// Assign the connection to the global DB variable
-	DB = connection
-	log.Println("Successfully connected to the database.")
+	slog.Info("Successfully connected to the database", "driver", dialector.Name())
+	return connection
 }
@@ -0,0 +1,117 @@
+package database
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// HealthCheck periodically pings the database and remembers the outcome of the last ping.
+type HealthCheck struct {
+	db       *gorm.DB
+	interval time.Duration
+	timeout  time.Duration
+
+	mu        sync.RWMutex
+	lastErr   error
+	lastCheck time.Time
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// StartHealthCheck pings the database immediately and then every interval until Stop is called.
+func StartHealthCheck(db *gorm.DB, interval time.Duration) *HealthCheck {
+	h := &HealthCheck{
+		db:       db,
+		interval: interval,
+		timeout:  min(interval, 5*time.Second),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	if h.timeout <= 0 {
+		h.timeout = 5 * time.Second
+	}
+
+	h.Check(context.Background())
+	go h.run()
+	return h
+}
+
+// run pings the database every interval until Stop is called.
+func (h *HealthCheck) run() {
+	defer close(h.done)
+	if h.interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			h.Check(context.Background())
+		case <-h.stop:
+			return
+		}
+	}
+}
+
+// Check pings the database now, records the result and returns the ping error, if any.
+// State changes between healthy and unhealthy are logged.
+func (h *HealthCheck) Check(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	err := ping(ctx, h.db)
+
+	h.mu.Lock()
+	wasHealthy := h.lastCheck.IsZero() || h.lastErr == nil
+	h.lastErr = err
+	h.lastCheck = time.Now()
+	h.mu.Unlock()
+
+	if err != nil && wasHealthy {
+		slog.Error("Database health check failed", "error", err)
+	} else if err == nil && !wasHealthy {
+		slog.Info("Database health check recovered")
+	}
+	return err
+}
+
+// HealthStatus is the outcome of the most recent database ping.
+type HealthStatus struct {
+	Healthy   bool      // Whether the ping succeeded
+	Err       error     // The ping error, if any
+	CheckedAt time.Time // When the ping ran
+}
+
+// Status returns the outcome of the most recent ping.
+func (h *HealthCheck) Status() HealthStatus {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return HealthStatus{
+		Healthy:   h.lastErr == nil,
+		Err:       h.lastErr,
+		CheckedAt: h.lastCheck,
+	}
+}
+
+// Stop ends the periodic pings and waits for the background goroutine to exit.
+func (h *HealthCheck) Stop() {
+	close(h.stop)
+	<-h.done
+}
+
+// ping checks that the database is reachable.
+func ping(ctx context.Context, db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
@@ -0,0 +1,64 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/logging"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// slowQueryThreshold is the duration above which statements are logged as slow.
+const slowQueryThreshold = 200 * time.Millisecond
+
+// slogLogger writes gorm's log output through the request logger in the statement context,
+// so database errors carry the request ID. Statements are logged at debug level, slow ones as warnings.
+// Bound values are never logged; SQL is logged with placeholders.
+type slogLogger struct{}
+
+// LogMode is ignored; the level is taken from the slog handler.
+func (l slogLogger) LogMode(gormlogger.LogLevel) gormlogger.Interface {
+	return l
+}
+
+// Info logs a gorm informational message.
+func (slogLogger) Info(ctx context.Context, msg string, args ...any) {
+	logging.FromContext(ctx).InfoContext(ctx, fmt.Sprintf(msg, args...))
+}
+
+// Warn logs a gorm warning.
+func (slogLogger) Warn(ctx context.Context, msg string, args ...any) {
+	logging.FromContext(ctx).WarnContext(ctx, fmt.Sprintf(msg, args...))
+}
+
+// Error logs a gorm error.
+func (slogLogger) Error(ctx context.Context, msg string, args ...any) {
+	logging.FromContext(ctx).ErrorContext(ctx, fmt.Sprintf(msg, args...))
+}
+
+// ParamsFilter drops the bound values so they never reach the log (passwords, emails, ...).
+func (slogLogger) ParamsFilter(_ context.Context, sql string, _ ...any) (string, []any) {
+	return sql, nil
+}
+
+// Trace logs a finished statement. Missing records are expected (e.g. looking up an unknown email) and are not errors.
+func (slogLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	logger := logging.FromContext(ctx)
+	elapsed := time.Since(begin)
+
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound):
+		sql, rows := fc()
+		logger.ErrorContext(ctx, "database query failed", "error", err, "sql", sql, "rows", rows, "duration", elapsed)
+	case elapsed > slowQueryThreshold:
+		sql, rows := fc()
+		logger.WarnContext(ctx, "slow database query", "sql", sql, "rows", rows, "duration", elapsed)
+	case logger.Enabled(ctx, slog.LevelDebug):
+		sql, rows := fc()
+		logger.DebugContext(ctx, "database query", "sql", sql, "rows", rows, "duration", elapsed)
+	}
+}
@@ -0,0 +1,55 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// queryCancelKey stores the cancel function of a query's timeout context in the gorm statement settings,
+// queryParentKey the statement context the timeout was derived from.
+const (
+	queryCancelKey = "database:query_timeout_cancel"
+	queryParentKey = "database:query_timeout_parent"
+)
+
+// registerQueryTimeout bounds every create, query, update, delete and raw statement by the given timeout.
+// The timeout is derived from the statement context, so queries issued with the request context
+// (db.WithContext(c.UserContext())) are also cancelled when the request is.
+func registerQueryTimeout(db *gorm.DB, timeout time.Duration) error {
+	if timeout <= 0 {
+		return nil
+	}
+
+	before := func(tx *gorm.DB) {
+		ctx, cancel := context.WithTimeout(tx.Statement.Context, timeout)
+		tx.Statement.Settings.Store(queryParentKey, tx.Statement.Context)
+		tx.Statement.Settings.Store(queryCancelKey, cancel)
+		tx.Statement.Context = ctx
+	}
+	after := func(tx *gorm.DB) {
+		if cancel, ok := tx.Statement.Settings.LoadAndDelete(queryCancelKey); ok {
+			cancel.(context.CancelFunc)()
+		}
+		// Restore the original context, as a statement built once may run several queries (e.g. Count then Find)
+		if parent, ok := tx.Statement.Settings.LoadAndDelete(queryParentKey); ok {
+			tx.Statement.Context = parent.(context.Context)
+		}
+	}
+
+	callbacks := db.Callback()
+	return errors.Join(
+		callbacks.Create().Before("*").Register("database:query_timeout_before_create", before),
+		callbacks.Create().After("*").Register("database:query_timeout_after_create", after),
+		callbacks.Query().Before("*").Register("database:query_timeout_before_query", before),
+		callbacks.Query().After("*").Register("database:query_timeout_after_query", after),
+		callbacks.Update().Before("*").Register("database:query_timeout_before_update", before),
+		callbacks.Update().After("*").Register("database:query_timeout_after_update", after),
+		callbacks.Delete().Before("*").Register("database:query_timeout_before_delete", before),
+		callbacks.Delete().After("*").Register("database:query_timeout_after_delete", after),
+		callbacks.Raw().Before("*").Register("database:query_timeout_before_raw", before),
+		callbacks.Raw().After("*").Register("database:query_timeout_after_raw", after),
+	)
+}
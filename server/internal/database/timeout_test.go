@@ -0,0 +1,101 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type timeoutRow struct {
+	Id   uint
+	Name string
+}
+
+// openTimeoutDB opens a SQLite database with the query timeout registered and a few rows to query.
+func openTimeoutDB(t *testing.T, timeout time.Duration) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if err := registerQueryTimeout(db, timeout); err != nil {
+		t.Fatalf("registerQueryTimeout: %v", err)
+	}
+	if err := db.AutoMigrate(&timeoutRow{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if err := db.Create(&[]timeoutRow{{Name: "a"}, {Name: "b"}, {Name: "c"}}).Error; err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	return db
+}
+
+// A statement built once and used for Count and then Find must not run the Find with the context the
+// Count's timeout already cancelled.
+func TestQueryTimeoutCountThenFind(t *testing.T) {
+	db := openTimeoutDB(t, time.Second)
+
+	query := db.WithContext(context.Background()).Model(&timeoutRow{}).Where("name <> ?", "b")
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		t.Fatalf("Count: %v", err)
+	}
+	var rows []timeoutRow
+	if err := query.Order("id").Limit(10).Find(&rows).Error; err != nil {
+		t.Fatalf("Find after Count: %v", err)
+	}
+
+	if total != 2 || len(rows) != 2 {
+		t.Fatalf("got total %d and %d rows, want 2 and 2", total, len(rows))
+	}
+}
+
+func TestQueryTimeoutRestoresContext(t *testing.T) {
+	db := openTimeoutDB(t, time.Second)
+
+	type key struct{}
+	ctx := context.WithValue(context.Background(), key{}, "request")
+	query := db.WithContext(ctx).Model(&timeoutRow{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		t.Fatalf("Count: %v", err)
+	}
+	if query.Statement.Context != ctx {
+		t.Fatal("statement context was not restored after the query")
+	}
+	if err := query.Statement.Context.Err(); err != nil {
+		t.Fatalf("statement context is done after the query: %v", err)
+	}
+}
+
+func TestQueryTimeoutCancelledParent(t *testing.T) {
+	db := openTimeoutDB(t, time.Second)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var rows []timeoutRow
+	if err := db.WithContext(ctx).Find(&rows).Error; err == nil {
+		t.Fatal("query with a cancelled request context succeeded")
+	}
+}
+
+func TestQueryTimeoutDisabled(t *testing.T) {
+	db := openTimeoutDB(t, 0)
+
+	for _, name := range []string{
+		"database:query_timeout_before_query",
+		"database:query_timeout_after_query",
+	} {
+		if db.Callback().Query().Get(name) != nil {
+			t.Errorf("callback %s registered with a zero timeout", name)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package database
+
+import (
+	"errors"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// querySpanKey stores the span of a statement in the gorm statement settings.
+const querySpanKey = "database:query_span"
+
+// registerTracing creates a client span for every create, query, update, delete and raw statement,
+// as a child of the span in the statement context (db.WithContext(c.UserContext())).
+func registerTracing(db *gorm.DB) error {
+	tracer := otel.Tracer("github.com/dobromirpetrov00/go_react_jwtauth/server/internal/database")
+	system := db.Dialector.Name()
+
+	before := func(operation string) func(tx *gorm.DB) {
+		return func(tx *gorm.DB) {
+			// The statement context is left untouched, as statements built once may run several queries
+			_, span := tracer.Start(tx.Statement.Context, "gorm."+operation,
+				trace.WithSpanKind(trace.SpanKindClient),
+				trace.WithAttributes(
+					semconv.DBSystemKey.String(system),
+					semconv.DBOperationName(operation),
+				),
+			)
+			tx.Statement.Settings.Store(querySpanKey, span)
+		}
+	}
+	after := func(tx *gorm.DB) {
+		value, ok := tx.Statement.Settings.LoadAndDelete(querySpanKey)
+		if !ok {
+			return
+		}
+		span := value.(trace.Span)
+		defer span.End()
+
+		// The SQL is recorded with placeholders, never with the bound values
+		span.SetAttributes(
+			semconv.DBQueryText(tx.Statement.SQL.String()),
+			attribute.Int64("db.rows_affected", tx.Statement.RowsAffected),
+		)
+		if tx.Statement.Table != "" {
+			span.SetAttributes(semconv.DBCollectionName(tx.Statement.Table))
+		}
+		if err := tx.Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+	}
+
+	callbacks := db.Callback()
+	return errors.Join(
+		callbacks.Create().Before("*").Register("database:tracing_before_create", before("create")),
+		callbacks.Create().After("*").Register("database:tracing_after_create", after),
+		callbacks.Query().Before("*").Register("database:tracing_before_query", before("query")),
+		callbacks.Query().After("*").Register("database:tracing_after_query", after),
+		callbacks.Update().Before("*").Register("database:tracing_before_update", before("update")),
+		callbacks.Update().After("*").Register("database:tracing_after_update", after),
+		callbacks.Delete().Before("*").Register("database:tracing_before_delete", before("delete")),
+		callbacks.Delete().After("*").Register("database:tracing_after_delete", after),
+		callbacks.Raw().Before("*").Register("database:tracing_before_raw", before("raw")),
+		callbacks.Raw().After("*").Register("database:tracing_after_raw", after),
+	)
+}
@@ -0,0 +1,51 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/database"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/migrations"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/secrets"
+	"gorm.io/gorm"
+)
+
+// DatabaseCheck pings the database through its health check, which also records the result.
+func DatabaseCheck(dbHealth *database.HealthCheck) Check {
+	return Check{
+		Name: "database",
+		Run:  dbHealth.Check,
+	}
+}
+
+// MigrationsCheck fails while the database has pending migrations. It only reads the schema_migrations
+// table, which the startup schema check creates.
+func MigrationsCheck(db *gorm.DB) Check {
+	return Check{
+		Name: "migrations",
+		Run: func(ctx context.Context) error {
+			pending, err := migrations.ReadPending(db.WithContext(ctx))
+			if err != nil {
+				return err
+			}
+			if len(pending) > 0 {
+				return fmt.Errorf("%d pending migration(s), starting with %d (%s)", len(pending), pending[0].Version, pending[0].Name)
+			}
+			return nil
+		},
+	}
+}
+
+// SecretCheck fails while the secret, such as the JWT signing key, has no value.
+func SecretCheck(name string, secret *secrets.Secret) Check {
+	return Check{
+		Name: name,
+		Run: func(context.Context) error {
+			if secret == nil || secret.Current() == "" {
+				return errors.New("not loaded")
+			}
+			return nil
+		},
+	}
+}
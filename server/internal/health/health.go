@@ -0,0 +1,114 @@
+// Package health serves the liveness (/healthz) and readiness (/readyz) probes used by the orchestrator.
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/logging"
+	"github.com/gofiber/fiber/v3"
+)
+
+// checkTimeout bounds the time all readiness checks may take together.
+const checkTimeout = 3 * time.Second
+
+// errShuttingDown is reported by the readiness probe once the server has started to shut down.
+var errShuttingDown = errors.New("server is shutting down")
+
+// Check is a named readiness check. It returns nil when the dependency it checks is usable.
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// CheckResult is the outcome of a single readiness check. The probe is unauthenticated,
+// so why a check failed is only logged, not returned.
+type CheckResult struct {
+	Status   string `json:"status"` // "ok" or "failing"
+	Duration string `json:"duration"`
+}
+
+// Probe answers liveness and readiness requests. Readiness fails once shutdown has begun,
+// so the orchestrator stops sending traffic before the server stops accepting it.
+type Probe struct {
+	checks       []Check
+	shuttingDown atomic.Bool
+}
+
+// NewProbe returns a probe that runs the given checks on every readiness request.
+func NewProbe(checks ...Check) *Probe {
+	return &Probe{checks: checks}
+}
+
+// SetShuttingDown makes every following readiness request fail.
+func (p *Probe) SetShuttingDown() {
+	p.shuttingDown.Store(true)
+}
+
+// Liveness reports that the process is up and serving requests. It checks no dependencies,
+// so a database outage does not get the process restarted.
+func (p *Probe) Liveness(c fiber.Ctx) error {
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.JSON(fiber.Map{
+		"status": "ok",
+	})
+}
+
+// Readiness runs every check concurrently and reports the outcome of each,
+// with status 503 if any of them failed or the server is shutting down.
+// The errors of failing checks are logged.
+func (p *Probe) Readiness(c fiber.Ctx) error {
+	logger := logging.FromContext(c.UserContext())
+	ctx, cancel := context.WithTimeout(c.UserContext(), checkTimeout)
+	defer cancel()
+
+	results := make(map[string]CheckResult, len(p.checks)+1)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range p.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			err := check.Run(ctx)
+			result := newCheckResult(err, time.Since(start))
+			if err != nil {
+				logger.Warn("Readiness check failed", "check", check.Name, "error", err)
+			}
+			mu.Lock()
+			results[check.Name] = result
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	if p.shuttingDown.Load() {
+		results["shutdown"] = newCheckResult(errShuttingDown, 0)
+	}
+
+	status, code := "ok", fiber.StatusOK
+	for _, result := range results {
+		if result.Status != "ok" {
+			status, code = "unavailable", fiber.StatusServiceUnavailable
+			break
+		}
+	}
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.Status(code).JSON(fiber.Map{
+		"status": status,
+		"checks": results,
+	})
+}
+
+// newCheckResult describes the outcome of a check that returned err after the given duration.
+func newCheckResult(err error, duration time.Duration) CheckResult {
+	result := CheckResult{Status: "ok", Duration: duration.Round(time.Microsecond).String()}
+	if err != nil {
+		result.Status = "failing"
+	}
+	return result
+}
@@ -0,0 +1,148 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/migrations"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/secrets"
+	"github.com/glebarez/sqlite"
+	"github.com/gofiber/fiber/v3"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// readiness is a decoded readiness response.
+type readiness struct {
+	Status string                     `json:"status"`
+	Checks map[string]json.RawMessage `json:"checks"`
+}
+
+// checkStatus returns the status of the named check in the response.
+func (r readiness) checkStatus(t *testing.T, name string) string {
+	t.Helper()
+	var result CheckResult
+	if err := json.Unmarshal(r.Checks[name], &result); err != nil {
+		t.Fatalf("check %s: %v", name, err)
+	}
+	return result.Status
+}
+
+// ready requests the readiness probe and returns the status code, the raw body and the decoded response.
+func ready(t *testing.T, probe *Probe) (int, string, readiness) {
+	t.Helper()
+	app := fiber.New()
+	app.Get("/readyz", probe.Readiness)
+
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/readyz", nil))
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result readiness
+	if err := json.Unmarshal(body, &result); err != nil {
+		t.Fatalf("decode %s: %v", body, err)
+	}
+	return resp.StatusCode, string(body), result
+}
+
+func TestReadiness(t *testing.T) {
+	ok := Check{Name: "ok", Run: func(context.Context) error { return nil }}
+	failing := Check{Name: "failing", Run: func(context.Context) error {
+		return errors.New("dial tcp 10.0.0.5:5432: password authentication failed for user app")
+	}}
+
+	tests := []struct {
+		name         string
+		checks       []Check
+		shuttingDown bool
+		wantCode     int
+		wantStatus   string
+		wantChecks   map[string]string
+	}{
+		{"no checks", nil, false, fiber.StatusOK, "ok", map[string]string{}},
+		{"all ok", []Check{ok}, false, fiber.StatusOK, "ok", map[string]string{"ok": "ok"}},
+		{"one failing", []Check{ok, failing}, false, fiber.StatusServiceUnavailable, "unavailable", map[string]string{"ok": "ok", "failing": "failing"}},
+		{"shutting down", []Check{ok}, true, fiber.StatusServiceUnavailable, "unavailable", map[string]string{"ok": "ok", "shutdown": "failing"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			probe := NewProbe(tt.checks...)
+			if tt.shuttingDown {
+				probe.SetShuttingDown()
+			}
+
+			code, body, result := ready(t, probe)
+			if code != tt.wantCode || result.Status != tt.wantStatus {
+				t.Fatalf("got %d %q, want %d %q", code, result.Status, tt.wantCode, tt.wantStatus)
+			}
+			if len(result.Checks) != len(tt.wantChecks) {
+				t.Fatalf("checks %s, want %v", body, tt.wantChecks)
+			}
+			for name, want := range tt.wantChecks {
+				if got := result.checkStatus(t, name); got != want {
+					t.Errorf("check %s = %q, want %q", name, got, want)
+				}
+			}
+			for _, leak := range []string{"10.0.0.5", "password", "shutting down", "error"} {
+				if strings.Contains(body, leak) {
+					t.Errorf("response %s reveals %q", body, leak)
+				}
+			}
+		})
+	}
+}
+
+func TestMigrationsCheck(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	check := MigrationsCheck(db)
+
+	if err := check.Run(context.Background()); err == nil {
+		t.Fatal("check passed without a schema_migrations table")
+	}
+	if db.Migrator().HasTable(&migrations.SchemaMigration{}) {
+		t.Fatal("check created the schema_migrations table")
+	}
+
+	if _, err := migrations.Pending(db); err != nil {
+		t.Fatalf("Pending: %v", err)
+	}
+	if err := check.Run(context.Background()); err == nil || !strings.Contains(err.Error(), "pending migration") {
+		t.Fatalf("check with pending migrations: %v", err)
+	}
+
+	if _, err := migrations.Up(db); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if err := check.Run(context.Background()); err != nil {
+		t.Fatalf("check after Up: %v", err)
+	}
+}
+
+func TestSecretCheck(t *testing.T) {
+	if err := SecretCheck("jwt", nil).Run(context.Background()); err == nil {
+		t.Error("check passed without a secret")
+	}
+
+	provider := secrets.StaticProvider{"KEY": "value"}
+	secret, err := secrets.Load(provider, "KEY")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if err := SecretCheck("jwt", secret).Run(context.Background()); err != nil {
+		t.Errorf("check with a loaded secret: %v", err)
+	}
+}
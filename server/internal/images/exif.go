@@ -0,0 +1,101 @@
+package images
+
+import (
+	"encoding/binary"
+	"image"
+)
+
+// exifOrientation returns the EXIF orientation (1 to 8) of a JPEG image, or 1 if it has none.
+// Only the JPEG APP1 segment is inspected; the other formats carry no orientation in practice.
+func exifOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+
+	// Walk the segments up to the start of the image data
+	for pos := 2; pos+4 <= len(data); {
+		if data[pos] != 0xFF {
+			return 1
+		}
+		marker := data[pos+1]
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		if marker == 0xDA || length < 2 || pos+2+length > len(data) {
+			return 1
+		}
+		segment := data[pos+4 : pos+2+length]
+		if marker == 0xE1 && len(segment) > 6 && string(segment[:6]) == "Exif\x00\x00" {
+			return tiffOrientation(segment[6:])
+		}
+		pos += 2 + length
+	}
+	return 1
+}
+
+// tiffOrientation reads the orientation tag (0x0112) from the first IFD of a TIFF structure.
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < entries; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			if value := int(order.Uint16(tiff[entry+8:])); value >= 1 && value <= 8 {
+				return value
+			}
+			return 1
+		}
+	}
+	return 1
+}
+
+// orient returns the square image transformed so it displays upright for the given EXIF orientation.
+func orient(src *image.RGBA, orientation int) *image.RGBA {
+	if orientation <= 1 || orientation > 8 {
+		return src
+	}
+
+	n := src.Bounds().Dx()
+	dst := image.NewRGBA(src.Bounds())
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			// Source coordinates of the destination pixel (x, y)
+			var sx, sy int
+			switch orientation {
+			case 2: // Mirrored horizontally
+				sx, sy = n-1-x, y
+			case 3: // Rotated 180°
+				sx, sy = n-1-x, n-1-y
+			case 4: // Mirrored vertically
+				sx, sy = x, n-1-y
+			case 5: // Mirrored along the main diagonal
+				sx, sy = y, x
+			case 6: // Needs a 90° clockwise turn
+				sx, sy = y, n-1-x
+			case 7: // Mirrored along the anti-diagonal
+				sx, sy = n-1-y, n-1-x
+			case 8: // Needs a 90° counter-clockwise turn
+				sx, sy = n-1-y, x
+			}
+			dst.SetRGBA(x, y, src.RGBAAt(sx, sy))
+		}
+	}
+	return dst
+}
@@ -0,0 +1,93 @@
+// Package images turns uploaded pictures into square JPEG thumbnails.
+// Uploads are decoded and re-encoded, so EXIF and other metadata (such as GPS positions) never reach storage;
+// the EXIF orientation is applied first so photos taken with a rotated camera stay upright.
+package images
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif" // Register the decoders of the accepted formats
+	"image/jpeg"
+	_ "image/png"
+	"net/http"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// ContentType is the media type of the generated thumbnails.
+const ContentType = "image/jpeg"
+
+// jpegQuality is the quality the thumbnails are encoded with.
+const jpegQuality = 85
+
+var (
+	// ErrUnsupportedFormat is returned for uploads that are not JPEG, PNG, GIF or WebP images.
+	ErrUnsupportedFormat = errors.New("images: unsupported format")
+	// ErrTooLarge is returned for images wider or taller than the allowed dimension.
+	ErrTooLarge = errors.New("images: image is too large")
+	// ErrInvalid is returned for images that cannot be decoded.
+	ErrInvalid = errors.New("images: image cannot be decoded")
+)
+
+// supportedTypes lists the accepted formats by the media type sniffed from their first bytes.
+var supportedTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// Thumbnails decodes the image and returns a square JPEG thumbnail for each size, cropped from the centre.
+// The format is detected from the content rather than the file name or declared type, and the dimensions
+// are checked before the image is decoded, so small files that expand to huge bitmaps are rejected cheaply.
+func Thumbnails(data []byte, maxDimension int, sizes []int) (map[int][]byte, error) {
+	if !supportedTypes[http.DetectContentType(data)] {
+		return nil, ErrUnsupportedFormat
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	if cfg.Width > maxDimension || cfg.Height > maxDimension {
+		return nil, ErrTooLarge
+	}
+	if cfg.Width == 0 || cfg.Height == 0 {
+		return nil, ErrInvalid
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	crop := centerSquare(src.Bounds())
+	orientation := exifOrientation(data)
+
+	thumbnails := make(map[int][]byte, len(sizes))
+	for _, size := range sizes {
+		// Flatten transparent images onto white, as JPEG has no alpha channel
+		dst := image.NewRGBA(image.Rect(0, 0, size, size))
+		draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+		draw.CatmullRom.Scale(dst, dst.Bounds(), src, crop, draw.Over, nil)
+
+		// Cropping the centre square commutes with rotating and flipping, so the cheap small image is oriented
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, orient(dst, orientation), &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return nil, err
+		}
+		thumbnails[size] = buf.Bytes()
+	}
+	return thumbnails, nil
+}
+
+// centerSquare returns the largest square in the centre of the bounds.
+func centerSquare(bounds image.Rectangle) image.Rectangle {
+	side := min(bounds.Dx(), bounds.Dy())
+	x := bounds.Min.X + (bounds.Dx()-side)/2
+	y := bounds.Min.Y + (bounds.Dy()-side)/2
+	return image.Rect(x, y, x+side, y+side)
+}
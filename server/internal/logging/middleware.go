@@ -0,0 +1,99 @@
+package logging
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// HeaderRequestID is the header carrying the request ID, accepted from callers and echoed in responses.
+const HeaderRequestID = "X-Request-ID"
+
+// maxRequestIDLength bounds the length of request IDs accepted from callers.
+const maxRequestIDLength = 128
+
+// requestIDKey stores the request ID in the request locals.
+const requestIDKey = "logging:request_id"
+
+// Middleware assigns every request an ID, taken from a valid X-Request-ID header or generated, and
+// returns it in the response. Handlers find a logger tagged with the request ID (and trace ID, when traced)
+// in the request context, and an access log line is written once the response is complete.
+func Middleware() fiber.Handler {
+	return func(c fiber.Ctx) error {
+		start := time.Now()
+
+		requestID := c.Get(HeaderRequestID)
+		if !validRequestID(requestID) {
+			requestID = newRequestID()
+		} else {
+			// Fiber reuses the request buffers, so copy the header value kept beyond this request
+			requestID = strings.Clone(requestID)
+		}
+		c.Locals(requestIDKey, requestID)
+		c.Set(HeaderRequestID, requestID)
+
+		logger := slog.Default().With("request_id", requestID)
+		if spanContext := trace.SpanContextFromContext(c.UserContext()); spanContext.IsValid() {
+			logger = logger.With("trace_id", spanContext.TraceID().String())
+		}
+		c.SetUserContext(WithLogger(c.UserContext(), logger))
+
+		// Let the error handler write the response now so the access log shows the final status code
+		if err := c.Next(); err != nil {
+			if handlerErr := c.App().ErrorHandler(c, err); handlerErr != nil {
+				_ = c.SendStatus(fiber.StatusInternalServerError)
+			}
+		}
+
+		status := c.Response().StatusCode()
+		level := slog.LevelInfo
+		if status >= fiber.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		logger.LogAttrs(c.UserContext(), level, "request",
+			slog.String("method", c.Method()),
+			slog.String("path", c.Path()),
+			slog.String("route", c.Route().Path),
+			slog.Int("status", status),
+			slog.Duration("duration", time.Since(start)),
+			slog.Int("bytes", len(c.Response().Body())),
+			slog.String("ip", c.IP()),
+			slog.String("user_agent", c.Get(fiber.HeaderUserAgent)),
+		)
+		return nil
+	}
+}
+
+// RequestID returns the ID of the current request, or "" outside the middleware.
+func RequestID(c fiber.Ctx) string {
+	requestID, _ := c.Locals(requestIDKey).(string)
+	return requestID
+}
+
+// validRequestID reports whether a caller-supplied request ID is safe to log and echo:
+// non-empty, bounded in length, and limited to letters, digits and "-", "_", ".", ":".
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID returns a random 128-bit request ID.
+func newRequestID() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,82 @@
+package logging
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+func TestValidRequestID(t *testing.T) {
+	tests := []struct {
+		id   string
+		want bool
+	}{
+		{"", false},
+		{"abc-123", true},
+		{"A.b_c:d", true},
+		{"4bf92f3577b34da6a3ce929d0e0e4736", true},
+		{strings.Repeat("a", maxRequestIDLength), true},
+		{strings.Repeat("a", maxRequestIDLength+1), false},
+		{"has space", false},
+		{"line\nbreak", false},
+		{`quote"`, false},
+		{"ünïcode", false},
+	}
+	for _, tt := range tests {
+		if got := validRequestID(tt.id); got != tt.want {
+			t.Errorf("validRequestID(%q) = %v, want %v", tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestMiddlewareRequestID(t *testing.T) {
+	app := fiber.New()
+	app.Use(Middleware())
+	app.Get("/", func(c fiber.Ctx) error {
+		if FromContext(c.UserContext()) == nil {
+			t.Error("no logger in the request context")
+		}
+		return c.SendString(RequestID(c))
+	})
+	app.Get("/fail", func(c fiber.Ctx) error {
+		return fiber.ErrTeapot
+	})
+
+	tests := []struct {
+		name   string
+		path   string
+		header string
+		keep   bool
+		status int
+	}{
+		{"valid header kept", "/", "client-id-1", true, fiber.StatusOK},
+		{"invalid header replaced", "/", "bad id", false, fiber.StatusOK},
+		{"missing header generated", "/", "", false, fiber.StatusOK},
+		{"error response", "/fail", "client-id-2", true, fiber.StatusTeapot},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(fiber.MethodGet, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set(HeaderRequestID, tt.header)
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status %d, want %d", resp.StatusCode, tt.status)
+			}
+
+			id := resp.Header.Get(HeaderRequestID)
+			if tt.keep && id != tt.header {
+				t.Fatalf("request ID %q, want %q", id, tt.header)
+			}
+			if !tt.keep && (id == tt.header || len(id) != 32 || !validRequestID(id)) {
+				t.Fatalf("request ID %q, want a generated ID", id)
+			}
+		})
+	}
+}
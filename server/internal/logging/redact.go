@@ -0,0 +1,56 @@
+package logging
+
+import (
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// redacted replaces secret values in log records.
+const redacted = "[REDACTED]"
+
+// secretKeyParts marks attributes whose values must never be logged when their key contains one of them.
+var secretKeyParts = []string{"password", "token", "secret", "authorization", "cookie", "jwt", "passphrase"}
+
+var (
+	// emailPattern matches email addresses inside free-form strings such as error messages.
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	// jwtPattern matches JSON Web Tokens inside free-form strings.
+	jwtPattern = regexp.MustCompile(`eyJ[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]+`)
+)
+
+// redactAttr removes secrets and email addresses from log attributes. It is used as slog.HandlerOptions.ReplaceAttr.
+func redactAttr(_ []string, attr slog.Attr) slog.Attr {
+	key := strings.ToLower(attr.Key)
+	for _, part := range secretKeyParts {
+		if strings.Contains(key, part) {
+			return slog.String(attr.Key, redacted)
+		}
+	}
+
+	if attr.Value.Kind() == slog.KindString {
+		return slog.String(attr.Key, RedactString(attr.Value.String()))
+	}
+	if attr.Value.Kind() == slog.KindAny {
+		if err, ok := attr.Value.Any().(error); ok {
+			return slog.String(attr.Key, RedactString(err.Error()))
+		}
+	}
+	return attr
+}
+
+// RedactString masks email addresses and tokens in s, keeping the first character and the domain of
+// email addresses (a***@example.com) so log lines remain useful for debugging.
+func RedactString(s string) string {
+	s = jwtPattern.ReplaceAllString(s, redacted)
+	return emailPattern.ReplaceAllStringFunc(s, MaskEmail)
+}
+
+// MaskEmail masks the local part of an email address.
+func MaskEmail(email string) string {
+	local, domain, ok := strings.Cut(email, "@")
+	if !ok || local == "" {
+		return redacted
+	}
+	return local[:1] + "***@" + domain
+}
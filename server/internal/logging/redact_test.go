@@ -0,0 +1,46 @@
+package logging
+
+import (
+	"errors"
+	"log/slog"
+	"testing"
+)
+
+func TestRedactAttr(t *testing.T) {
+	tests := []struct {
+		name string
+		attr slog.Attr
+		want string
+	}{
+		{"password key", slog.String("password", "hunter2"), redacted},
+		{"key containing a secret part", slog.String("X-CSRF-Token", "abc"), redacted},
+		{"non-string secret", slog.Int("jwt_length", 42), redacted},
+		{"email in a message", slog.String("msg", "user ann@example.com logged in"), "user a***@example.com logged in"},
+		{"token in a message", slog.String("detail", "bad token eyJhbGciOi.eyJzdWIiOjF9.c2lnbmF0dXJl"), "bad token " + redacted},
+		{"email in an error", slog.Any("error", errors.New("duplicate ann@example.com")), "duplicate a***@example.com"},
+		{"plain string", slog.String("route", "/api/user"), "/api/user"},
+		{"number", slog.Int("status", 200), "200"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := redactAttr(nil, tt.attr)
+			if got.Key != tt.attr.Key || got.Value.String() != tt.want {
+				t.Fatalf("redactAttr(%v) = %v, want %s=%s", tt.attr, got, tt.attr.Key, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaskEmail(t *testing.T) {
+	tests := map[string]string{
+		"ann@example.com": "a***@example.com",
+		"a@b.io":          "a***@b.io",
+		"@example.com":    redacted,
+		"not an email":    redacted,
+	}
+	for email, want := range tests {
+		if got := MaskEmail(email); got != want {
+			t.Errorf("MaskEmail(%q) = %q, want %q", email, got, want)
+		}
+	}
+}
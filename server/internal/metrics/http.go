@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/middleware/adaptor"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// unmatchedRoute labels requests that did not match any route, keeping the label set bounded.
+const unmatchedRoute = "unmatched"
+
+// unmatchedKey marks requests in the request locals that no route handled.
+const unmatchedKey = "metrics:unmatched"
+
+// Middleware counts and times every request by method, route pattern and final status code.
+// Requests that matched no route are labelled "unmatched" if Unmatched is registered after the routes.
+func Middleware() fiber.Handler {
+	return func(c fiber.Ctx) error {
+		start := time.Now()
+		err := c.Next()
+
+		route := c.Route().Path
+		if unmatched, _ := c.Locals(unmatchedKey).(bool); unmatched {
+			route = unmatchedRoute
+		}
+
+		// Let the error handler write the response now so the status code is final
+		if err != nil {
+			if handlerErr := c.App().ErrorHandler(c, err); handlerErr != nil {
+				_ = c.SendStatus(fiber.StatusInternalServerError)
+			}
+		}
+
+		// Fiber reuses the request buffers, so copy the strings kept as label values
+		method, route := strings.Clone(c.Method()), strings.Clone(route)
+		status := strconv.Itoa(c.Response().StatusCode())
+		httpRequests.WithLabelValues(method, route, status).Inc()
+		httpDuration.WithLabelValues(method, route, status).Observe(time.Since(start).Seconds())
+		return nil
+	}
+}
+
+// Unmatched marks requests that reached the end of the stack without a route handling them, for Middleware.
+// It must be registered after every route. It does not rely on the routing error, which inner middleware such
+// as the access log may already have turned into a response; Fiber still answers with 404 or 405 as usual.
+func Unmatched() fiber.Handler {
+	return func(c fiber.Ctx) error {
+		c.Locals(unmatchedKey, true)
+		return c.Next()
+	}
+}
+
+// Handler serves the registered metrics in the Prometheus exposition format.
+func Handler() fiber.Handler {
+	return adaptor.HTTPHandler(promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry}))
+}
@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// requestCount returns how many requests were counted with the labels.
+func requestCount(t *testing.T, method, route, status string) float64 {
+	t.Helper()
+	var metric dto.Metric
+	if err := httpRequests.WithLabelValues(method, route, status).Write(&metric); err != nil {
+		t.Fatal(err)
+	}
+	return metric.GetCounter().GetValue()
+}
+
+func TestMiddlewareRoutes(t *testing.T) {
+	app := fiber.New()
+	app.Use(Middleware())
+	// Like the logging middleware, write the error response and hide the error from outer middleware
+	app.Use(func(c fiber.Ctx) error {
+		if err := c.Next(); err != nil {
+			return c.App().ErrorHandler(c, err)
+		}
+		return nil
+	})
+	app.Use("/api", func(c fiber.Ctx) error { return c.Next() })
+	app.Get("/api/items/:id", func(c fiber.Ctx) error {
+		if c.Params("id") == "missing" {
+			return fiber.ErrNotFound
+		}
+		return c.SendString("item")
+	})
+	app.Get("/", func(c fiber.Ctx) error { return c.SendString("home") })
+	app.Use(Unmatched())
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		route  string
+		status string
+	}{
+		{"matched", fiber.MethodGet, "/api/items/1", "/api/items/:id", "200"},
+		{"not found by the handler", fiber.MethodGet, "/api/items/missing", "/api/items/:id", "404"},
+		{"root route", fiber.MethodGet, "/", "/", "200"},
+		{"unmatched path", fiber.MethodGet, "/nope", unmatchedRoute, "404"},
+		{"unmatched path under middleware", fiber.MethodGet, "/api/nope", unmatchedRoute, "404"},
+		{"unmatched method", fiber.MethodPost, "/", unmatchedRoute, "405"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := requestCount(t, tt.method, tt.route, tt.status)
+			resp, err := app.Test(httptest.NewRequest(tt.method, tt.path, nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := resp.Status[:3]; got != tt.status {
+				t.Fatalf("status %s, want %s", got, tt.status)
+			}
+			if got := requestCount(t, tt.method, tt.route, tt.status) - before; got != 1 {
+				t.Fatalf("counted %v requests with route %q and status %s, want 1", got, tt.route, tt.status)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+// Package metrics defines the Prometheus metrics exported on /metrics: HTTP traffic, authentication events,
+// password hashing time, database pool statistics and active sessions.
+package metrics
+
+import (
+	"database/sql"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+// Registry holds every metric exported by the server.
+var Registry = prometheus.NewRegistry()
+
+var (
+	// httpRequests counts handled HTTP requests by method, route pattern and status code.
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "Number of HTTP requests handled, by method, route and status code.",
+	}, []string{"method", "route", "status"})
+
+	// httpDuration observes the time taken to handle HTTP requests.
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Time taken to handle HTTP requests, by method, route and status code.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// loginAttempts counts logins by result and failure reason.
+	loginAttempts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "auth_login_attempts_total",
+		Help: "Number of login attempts, by result (success or failure) and failure reason.",
+	}, []string{"result", "reason"})
+
+	// registrations counts registrations by result.
+	registrations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "auth_registrations_total",
+		Help: "Number of registration attempts, by result (success or failure) and failure reason.",
+	}, []string{"result", "reason"})
+
+	// passwordHashDuration observes the time bcrypt takes to hash or compare a password.
+	// The cost is a label, as every step doubles the time.
+	passwordHashDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "auth_password_hash_duration_seconds",
+		Help:    "Time taken by bcrypt, by operation (hash or compare) and cost.",
+		Buckets: []float64{.01, .025, .05, .1, .25, .5, 1, 2, 4},
+	}, []string{"operation", "cost"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpDuration,
+		loginAttempts,
+		registrations,
+		passwordHashDuration,
+	)
+}
+
+// RegisterDB exports the connection pool statistics of the named database.
+func RegisterDB(db *sql.DB, name string) error {
+	return Registry.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+// ObserveLogin counts a login attempt. The reason is empty for successful logins.
+func ObserveLogin(success bool, reason string) {
+	loginAttempts.WithLabelValues(result(success), reason).Inc()
+}
+
+// ObserveRegistration counts a registration attempt. The reason is empty for successful registrations.
+func ObserveRegistration(success bool, reason string) {
+	registrations.WithLabelValues(result(success), reason).Inc()
+}
+
+// ObservePasswordHash records the time taken by a bcrypt operation of the given cost that started at start.
+func ObservePasswordHash(operation string, cost int, start time.Time) {
+	passwordHashDuration.WithLabelValues(operation, strconv.Itoa(cost)).Observe(time.Since(start).Seconds())
+}
+
+// result returns the result label for a success flag.
+func result(success bool) string {
+	if success {
+		return "success"
+	}
+	return "failure"
+}
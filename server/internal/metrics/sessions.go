@@ -0,0 +1,37 @@
+package metrics
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// sessionCountTimeout bounds the time the active sessions query may take during a scrape.
+const sessionCountTimeout = 2 * time.Second
+
+// ActiveSessionCounter counts the sessions that are neither revoked nor expired at the given time,
+// such as repository.SessionRepository.CountActive.
+type ActiveSessionCounter func(ctx context.Context, at time.Time) (int64, error)
+
+// RegisterActiveSessions exports the auth_active_sessions gauge, read from the stored sessions on every scrape,
+// so it covers the sessions of every replica and drops revoked sessions immediately. If the count fails,
+// the gauge reports NaN and the error is logged.
+func RegisterActiveSessions(count ActiveSessionCounter) error {
+	return Registry.Register(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "auth_active_sessions",
+		Help: "Number of sessions that have neither expired nor been logged out or revoked.",
+	}, func() float64 {
+		ctx, cancel := context.WithTimeout(context.Background(), sessionCountTimeout)
+		defer cancel()
+
+		active, err := count(ctx, time.Now())
+		if err != nil {
+			slog.Warn("Could not count the active sessions", "error", err)
+			return math.NaN()
+		}
+		return float64(active)
+	}))
+}
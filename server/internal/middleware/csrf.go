@@ -0,0 +1,112 @@
+// Package middleware contains the Fiber middleware installed in front of the application routes.
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"strings"
+	"time"
+
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/config"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/problem"
+	"github.com/gofiber/fiber/v3"
+)
+
+// csrfTokenBytes is the number of random bytes in a CSRF token.
+const csrfTokenBytes = 32
+
+// CSRF implements double-submit cookie protection for cookie-authenticated, state-changing requests.
+// The client fetches a token from the Issue handler, which also stores it in an HttpOnly cookie,
+// and echoes it in a request header; the two must match. Requests authenticated with a Bearer token
+// are exempt, because browsers never attach that header on their own.
+type CSRF struct {
+	settings config.CSRFConfig
+	cookie   config.CookieConfig
+}
+
+// NewCSRF creates the CSRF protection. The token cookie uses the same security attributes as the authentication cookie.
+func NewCSRF(settings config.CSRFConfig, cookie config.CookieConfig) *CSRF {
+	cookie.Name = settings.CookieName
+	return &CSRF{settings: settings, cookie: cookie}
+}
+
+// Protect rejects state-changing requests whose CSRF header does not match the CSRF cookie.
+func (m *CSRF) Protect(c fiber.Ctx) error {
+	if !m.settings.Enabled || isSafeMethod(c.Method()) || hasBearerToken(c) {
+		return c.Next()
+	}
+
+	cookieToken := c.Cookies(m.cookie.FullName())
+	headerToken := c.Get(m.settings.HeaderName)
+	if cookieToken == "" || headerToken == "" || subtle.ConstantTimeCompare([]byte(cookieToken), []byte(headerToken)) != 1 {
+		return problem.New(fiber.StatusForbidden, problem.CodeInvalidCSRFToken, "The CSRF token is missing or invalid.")
+	}
+
+	return c.Next()
+}
+
+// Issue returns the caller's CSRF token as {"csrf_token": "..."}, creating and storing a new one
+// in the CSRF cookie if the request does not carry one yet.
+func (m *CSRF) Issue(c fiber.Ctx) error {
+	token := c.Cookies(m.cookie.FullName())
+	if !isWellFormedToken(token) {
+		var err error
+		token, err = newCSRFToken()
+		if err != nil {
+			return problem.Internal("Failed to generate a CSRF token.", err)
+		}
+	}
+
+	// (Re)set the cookie so its lifetime is extended along with the session
+	c.Cookie(&fiber.Cookie{
+		Name:        m.cookie.FullName(),
+		Value:       token,
+		Path:        m.cookie.Path,
+		Domain:      m.cookie.Domain,
+		Expires:     time.Now().Add(24 * time.Hour),
+		HTTPOnly:    true, // The client reads the token from the response body, never from the cookie
+		Secure:      m.cookie.Secure,
+		SameSite:    m.cookie.SameSite,
+		Partitioned: m.cookie.Partitioned,
+	})
+
+	// Prevent proxies and browsers from caching the token
+	c.Set(fiber.HeaderCacheControl, "no-store")
+
+	return c.JSON(fiber.Map{
+		"csrf_token": token,
+		"header":     m.settings.HeaderName,
+	})
+}
+
+// isSafeMethod reports whether the HTTP method must not change state and therefore needs no CSRF check.
+func isSafeMethod(method string) bool {
+	switch method {
+	case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions, fiber.MethodTrace:
+		return true
+	default:
+		return false
+	}
+}
+
+// hasBearerToken reports whether the request is authenticated with an Authorization: Bearer header.
+func hasBearerToken(c fiber.Ctx) bool {
+	scheme, token, ok := strings.Cut(c.Get(fiber.HeaderAuthorization), " ")
+	return ok && strings.EqualFold(scheme, "Bearer") && strings.TrimSpace(token) != ""
+}
+
+// newCSRFToken returns a new random, URL-safe CSRF token.
+func newCSRFToken() (string, error) {
+	buf := make([]byte, csrfTokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// isWellFormedToken reports whether the token looks like one produced by newCSRFToken.
+func isWellFormedToken(token string) bool {
+	decoded, err := base64.RawURLEncoding.DecodeString(token)
+	return err == nil && len(decoded) == csrfTokenBytes
+}
@@ -0,0 +1,183 @@
+package middleware
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/config"
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/problem"
+	"github.com/gofiber/fiber/v3"
+)
+
+// csrfApp returns an app with the CSRF token handler at /api/csrf and a protected route at /api/action.
+func csrfApp(csrf *CSRF) *fiber.App {
+	app := fiber.New(fiber.Config{ErrorHandler: problem.ErrorHandler})
+	app.Get("/api/csrf", csrf.Issue)
+	app.Use(csrf.Protect)
+	app.All("/api/action", func(c fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+	return app
+}
+
+// newTestCSRF returns the CSRF protection with the default configuration.
+func newTestCSRF() *CSRF {
+	cfg := config.Default()
+	return NewCSRF(cfg.CSRF, cfg.Auth.Cookie)
+}
+
+func TestCSRFProtect(t *testing.T) {
+	token, err := newCSRFToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := newCSRFToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		method   string
+		cookie   string
+		header   string
+		auth     string
+		disabled bool
+		status   int
+	}{
+		{"matching token", http.MethodPost, token, token, "", false, http.StatusNoContent},
+		{"matching token on DELETE", http.MethodDelete, token, token, "", false, http.StatusNoContent},
+		{"missing header", http.MethodPost, token, "", "", false, http.StatusForbidden},
+		{"missing cookie", http.MethodPut, "", token, "", false, http.StatusForbidden},
+		{"missing both", http.MethodPatch, "", "", "", false, http.StatusForbidden},
+		{"mismatched token", http.MethodPost, token, other, "", false, http.StatusForbidden},
+		{"prefix of the token", http.MethodPost, token, token[:10], "", false, http.StatusForbidden},
+		{"GET is safe", http.MethodGet, "", "", "", false, http.StatusNoContent},
+		{"HEAD is safe", http.MethodHead, "", "", "", false, http.StatusNoContent},
+		{"OPTIONS is safe", http.MethodOptions, "", "", "", false, http.StatusNoContent},
+		{"bearer token", http.MethodPost, "", "", "Bearer abc.def.ghi", false, http.StatusNoContent},
+		{"lower-case bearer scheme", http.MethodPost, "", "", "bearer abc.def.ghi", false, http.StatusNoContent},
+		{"empty bearer token", http.MethodPost, "", "", "Bearer ", false, http.StatusForbidden},
+		{"basic authentication", http.MethodPost, "", "", "Basic YW5uOnB3", false, http.StatusForbidden},
+		{"disabled", http.MethodPost, "", "", "", true, http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			csrf := newTestCSRF()
+			csrf.settings.Enabled = !tt.disabled
+			app := csrfApp(csrf)
+
+			req := httptest.NewRequest(tt.method, "/api/action", nil)
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: "csrf_token", Value: tt.cookie})
+			}
+			if tt.header != "" {
+				req.Header.Set("X-CSRF-Token", tt.header)
+			}
+			if tt.auth != "" {
+				req.Header.Set(fiber.HeaderAuthorization, tt.auth)
+			}
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("request: %v", err)
+			}
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status %d, want %d", resp.StatusCode, tt.status)
+			}
+			if tt.status == http.StatusForbidden {
+				body, _ := io.ReadAll(resp.Body)
+				if !strings.Contains(string(body), `"code":"`+problem.CodeInvalidCSRFToken+`"`) {
+					t.Fatalf("body %s lacks the %s code", body, problem.CodeInvalidCSRFToken)
+				}
+			}
+		})
+	}
+}
+
+func TestCSRFIssue(t *testing.T) {
+	existing, err := newCSRFToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		hostPrefix bool
+		cookie     string
+		reused     bool
+	}{
+		{"no cookie", false, "", false},
+		{"well-formed cookie", false, existing, true},
+		{"malformed cookie", false, "not-a-token", false},
+		{"short cookie", false, existing[:20], false},
+		{"host prefix", true, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Default()
+			cfg.Auth.Cookie.HostPrefix = tt.hostPrefix
+			csrf := NewCSRF(cfg.CSRF, cfg.Auth.Cookie)
+			app := csrfApp(csrf)
+			cookieName := csrf.cookie.FullName()
+
+			req := httptest.NewRequest(http.MethodGet, "/api/csrf", nil)
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: cookieName, Value: tt.cookie})
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("request: %v", err)
+			}
+
+			var body struct {
+				Token  string `json:"csrf_token"`
+				Header string `json:"header"`
+			}
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if !isWellFormedToken(body.Token) || body.Header != "X-CSRF-Token" {
+				t.Fatalf("response %+v", body)
+			}
+			if (body.Token == tt.cookie) != tt.reused {
+				t.Errorf("token %q, cookie %q: reused %v, want %v", body.Token, tt.cookie, body.Token == tt.cookie, tt.reused)
+			}
+			if got := resp.Header.Get(fiber.HeaderCacheControl); got != "no-store" {
+				t.Errorf("Cache-Control = %q", got)
+			}
+
+			var cookie *http.Cookie
+			for _, c := range resp.Cookies() {
+				if c.Name == cookieName {
+					cookie = c
+				}
+			}
+			if cookie == nil {
+				t.Fatalf("no %s cookie set", cookieName)
+			}
+			if cookie.Value != body.Token || !cookie.HttpOnly || !cookie.Secure || cookie.Path != "/" {
+				t.Errorf("cookie %+v", cookie)
+			}
+			if tt.hostPrefix && cookieName != "__Host-csrf_token" {
+				t.Errorf("cookie name %q", cookieName)
+			}
+
+			// The issued token passes the protection when echoed in the header
+			req = httptest.NewRequest(http.MethodPost, "/api/action", nil)
+			req.AddCookie(&http.Cookie{Name: cookieName, Value: cookie.Value})
+			req.Header.Set(body.Header, body.Token)
+			resp, err = app.Test(req)
+			if err != nil {
+				t.Fatalf("request: %v", err)
+			}
+			if resp.StatusCode != http.StatusNoContent {
+				t.Fatalf("protected request with the issued token: status %d", resp.StatusCode)
+			}
+		})
+	}
+}
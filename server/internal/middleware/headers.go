@@ -0,0 +1,129 @@
+package middleware
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/config"
+	"github.com/gofiber/fiber/v3"
+)
+
+// omitHeader is the header value that disables a header, e.g. in a route override.
+const omitHeader = "-"
+
+// cspReportGroup is the reporting endpoint group named in the report-to directive.
+const cspReportGroup = "csp-endpoint"
+
+// routeHeaders holds the headers sent for requests under a route prefix.
+type routeHeaders struct {
+	prefix  string
+	headers map[string]string
+}
+
+// SecurityHeaders returns middleware that sets the configured security headers on every response.
+// Requests under a prefix listed in the route overrides get the headers merged with the longest matching override.
+func SecurityHeaders(settings config.HeadersConfig) fiber.Handler {
+	defaults := buildSecurityHeaders(settings, settings.SecurityHeaders)
+
+	routes := make([]routeHeaders, 0, len(settings.Routes))
+	for prefix, override := range settings.Routes {
+		routes = append(routes, routeHeaders{
+			prefix:  strings.TrimSuffix(prefix, "/"),
+			headers: buildSecurityHeaders(settings, mergeSecurityHeaders(settings.SecurityHeaders, override)),
+		})
+	}
+	// Check the most specific prefixes first
+	sort.Slice(routes, func(i, j int) bool {
+		return len(routes[i].prefix) > len(routes[j].prefix)
+	})
+
+	return func(c fiber.Ctx) error {
+		headers := defaults
+		for _, route := range routes {
+			if matchesPrefix(c.Path(), route.prefix) {
+				headers = route.headers
+				break
+			}
+		}
+
+		for name, value := range headers {
+			c.Set(name, value)
+		}
+		return c.Next()
+	}
+}
+
+// matchesPrefix reports whether the path is the prefix itself or lies below it.
+func matchesPrefix(path, prefix string) bool {
+	return path == prefix || strings.HasPrefix(path, prefix+"/") || prefix == ""
+}
+
+// mergeSecurityHeaders returns the defaults with every non-empty value of the override applied.
+func mergeSecurityHeaders(defaults, override config.SecurityHeaders) config.SecurityHeaders {
+	merged := defaults
+	for _, field := range []struct {
+		target *string
+		value  string
+	}{
+		{&merged.ContentSecurityPolicy, override.ContentSecurityPolicy},
+		{&merged.FrameAncestors, override.FrameAncestors},
+		{&merged.ContentTypeOptions, override.ContentTypeOptions},
+		{&merged.ReferrerPolicy, override.ReferrerPolicy},
+		{&merged.PermissionsPolicy, override.PermissionsPolicy},
+		{&merged.CrossOriginOpenerPolicy, override.CrossOriginOpenerPolicy},
+		{&merged.CrossOriginEmbedderPolicy, override.CrossOriginEmbedderPolicy},
+		{&merged.CrossOriginResourcePolicy, override.CrossOriginResourcePolicy},
+	} {
+		if field.value != "" {
+			*field.target = field.value
+		}
+	}
+	return merged
+}
+
+// buildSecurityHeaders turns the header values into the response headers to send.
+func buildSecurityHeaders(settings config.HeadersConfig, values config.SecurityHeaders) map[string]string {
+	headers := map[string]string{}
+	set := func(name, value string) {
+		if value != "" && value != omitHeader {
+			headers[name] = value
+		}
+	}
+
+	// Assemble the content security policy from the base policy, frame-ancestors and the reporting directives
+	var directives []string
+	if csp := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(values.ContentSecurityPolicy), ";")); csp != "" && csp != omitHeader {
+		directives = append(directives, csp)
+	}
+	if values.FrameAncestors != "" && values.FrameAncestors != omitHeader {
+		directives = append(directives, "frame-ancestors "+values.FrameAncestors)
+	}
+	if len(directives) > 0 {
+		if settings.CSPReportURI != "" {
+			directives = append(directives, "report-uri "+settings.CSPReportURI, "report-to "+cspReportGroup)
+			set("Reporting-Endpoints", cspReportGroup+`="`+settings.CSPReportURI+`"`)
+		}
+		if settings.CSPReportOnly {
+			set(fiber.HeaderContentSecurityPolicyReportOnly, strings.Join(directives, "; "))
+		} else {
+			set(fiber.HeaderContentSecurityPolicy, strings.Join(directives, "; "))
+		}
+	}
+
+	// Older browsers only understand X-Frame-Options
+	switch values.FrameAncestors {
+	case "'none'":
+		set(fiber.HeaderXFrameOptions, "DENY")
+	case "'self'":
+		set(fiber.HeaderXFrameOptions, "SAMEORIGIN")
+	}
+
+	set(fiber.HeaderXContentTypeOptions, values.ContentTypeOptions)
+	set(fiber.HeaderReferrerPolicy, values.ReferrerPolicy)
+	set(fiber.HeaderPermissionsPolicy, values.PermissionsPolicy)
+	set("Cross-Origin-Opener-Policy", values.CrossOriginOpenerPolicy)
+	set("Cross-Origin-Embedder-Policy", values.CrossOriginEmbedderPolicy)
+	set(fiber.HeaderCrossOriginResourcePolicy, values.CrossOriginResourcePolicy)
+
+	return headers
+}
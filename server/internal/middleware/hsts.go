@@ -0,0 +1,14 @@
+package middleware
+
+import "github.com/gofiber/fiber/v3"
+
+// HSTS returns middleware that sends the given Strict-Transport-Security header value on every response.
+// An empty value disables the header, e.g. when the server does not listen over HTTPS.
+func HSTS(value string) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if value != "" {
+			c.Set(fiber.HeaderStrictTransportSecurity, value)
+		}
+		return c.Next()
+	}
+}
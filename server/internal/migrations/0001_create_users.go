@@ -0,0 +1,30 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// user0001 is the users table as of migration 1.
+// Migrations keep their own copy of the schema so later model changes do not alter past migrations.
+type user0001 struct {
+	Id       uint
+	Name     string
+	Email    string `gorm:"unique;size:255"`
+	Password []byte
+}
+
+func (user0001) TableName() string {
+	return "users"
+}
+
+func init() {
+	register(Migration{
+		Version: 1,
+		Name:    "create users",
+		Up: func(tx *gorm.DB) error {
+			// AutoMigrate keeps this migration safe for databases where the table was created by hand
+			return tx.AutoMigrate(&user0001{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("users")
+		},
+	})
+}
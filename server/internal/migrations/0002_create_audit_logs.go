@@ -0,0 +1,38 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// auditLog0002 is the audit_logs table as of migration 2.
+type auditLog0002 struct {
+	Id        uint
+	ActorId   *uint  `gorm:"index"`
+	TargetId  *uint  `gorm:"index"`
+	Action    string `gorm:"size:64;index"`
+	IP        string `gorm:"size:64"`
+	UserAgent string `gorm:"size:512"`
+	Detail    string
+	Changes   []byte
+	CreatedAt time.Time `gorm:"index"`
+}
+
+func (auditLog0002) TableName() string {
+	return "audit_logs"
+}
+
+func init() {
+	register(Migration{
+		Version: 2,
+		Name:    "create audit logs",
+		Up: func(tx *gorm.DB) error {
+			// AutoMigrate keeps this migration safe for databases where the table was created automatically on startup
+			return tx.AutoMigrate(&auditLog0002{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("audit_logs")
+		},
+	})
+}
@@ -0,0 +1,33 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// Email addresses must be unique regardless of case. MySQL's default collation already compares
+// case-insensitively, so the existing unique index suffices there; PostgreSQL and SQLite need
+// an additional expression or collation index.
+func init() {
+	register(Migration{
+		Version: 3,
+		Name:    "case-insensitive unique emails",
+		Up: func(tx *gorm.DB) error {
+			switch Dialect(tx) {
+			case "postgres":
+				return tx.Exec("CREATE UNIQUE INDEX idx_users_email_lower ON users (LOWER(email))").Error
+			case "sqlite":
+				return tx.Exec("CREATE UNIQUE INDEX idx_users_email_nocase ON users (email COLLATE NOCASE)").Error
+			default:
+				return nil
+			}
+		},
+		Down: func(tx *gorm.DB) error {
+			switch Dialect(tx) {
+			case "postgres":
+				return tx.Exec("DROP INDEX IF EXISTS idx_users_email_lower").Error
+			case "sqlite":
+				return tx.Exec("DROP INDEX IF EXISTS idx_users_email_nocase").Error
+			default:
+				return nil
+			}
+		},
+	})
+}
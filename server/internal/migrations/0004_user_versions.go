@@ -0,0 +1,44 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// user0004 holds the columns added to the users table by migration 4.
+// Version is incremented on every update and backs the ETag of the user resource.
+type user0004 struct {
+	Version   uint `gorm:"not null;default:1"`
+	UpdatedAt *time.Time
+}
+
+func (user0004) TableName() string {
+	return "users"
+}
+
+func init() {
+	register(Migration{
+		Version: 4,
+		Name:    "add user versions",
+		Up: func(tx *gorm.DB) error {
+			for _, column := range []string{"Version", "UpdatedAt"} {
+				if !tx.Migrator().HasColumn(&user0004{}, column) {
+					if err := tx.Migrator().AddColumn(&user0004{}, column); err != nil {
+						return err
+					}
+				}
+			}
+			return tx.Exec("UPDATE users SET updated_at = ? WHERE updated_at IS NULL", time.Now()).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			// Plain ALTER TABLE rather than the migrator, which rebuilds SQLite tables without their indexes
+			for _, column := range []string{"updated_at", "version"} {
+				if err := tx.Exec("ALTER TABLE users DROP COLUMN " + column).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+}
@@ -0,0 +1,61 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// user0005 holds the profile columns added to the users table by migration 5.
+type user0005 struct {
+	DisplayName string  `gorm:"size:100"`
+	Handle      *string `gorm:"size:30;uniqueIndex:idx_users_handle"`
+	Bio         string
+	Locale      string `gorm:"size:35"`
+	Timezone    string `gorm:"size:64"`
+	Phone       string `gorm:"size:20"`
+	CreatedAt   *time.Time
+	LastLoginAt *time.Time
+}
+
+func (user0005) TableName() string {
+	return "users"
+}
+
+// profileColumns0005 lists the fields of user0005 in the order the columns are added.
+var profileColumns0005 = []string{"DisplayName", "Handle", "Bio", "Locale", "Timezone", "Phone", "CreatedAt", "LastLoginAt"}
+
+func init() {
+	register(Migration{
+		Version: 5,
+		Name:    "add user profiles",
+		Up: func(tx *gorm.DB) error {
+			for _, column := range profileColumns0005 {
+				if !tx.Migrator().HasColumn(&user0005{}, column) {
+					if err := tx.Migrator().AddColumn(&user0005{}, column); err != nil {
+						return err
+					}
+				}
+			}
+			if !tx.Migrator().HasIndex(&user0005{}, "idx_users_handle") {
+				if err := tx.Migrator().CreateIndex(&user0005{}, "idx_users_handle"); err != nil {
+					return err
+				}
+			}
+			// The registration time of existing users is unknown; use their last update instead
+			return tx.Exec("UPDATE users SET created_at = updated_at WHERE created_at IS NULL").Error
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropIndex(&user0005{}, "idx_users_handle"); err != nil {
+				return err
+			}
+			// Plain ALTER TABLE rather than the migrator, which rebuilds SQLite tables without their indexes
+			for _, column := range []string{"last_login_at", "created_at", "phone", "timezone", "locale", "bio", "handle", "display_name"} {
+				if err := tx.Exec("ALTER TABLE users DROP COLUMN " + column).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+}
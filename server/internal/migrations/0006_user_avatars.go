@@ -0,0 +1,39 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// user0006 holds the avatar columns added to the users table by migration 6.
+type user0006 struct {
+	AvatarKey   string `gorm:"size:255"`
+	AvatarSizes string `gorm:"size:64"`
+}
+
+func (user0006) TableName() string {
+	return "users"
+}
+
+func init() {
+	register(Migration{
+		Version: 6,
+		Name:    "add user avatars",
+		Up: func(tx *gorm.DB) error {
+			for _, column := range []string{"AvatarKey", "AvatarSizes"} {
+				if !tx.Migrator().HasColumn(&user0006{}, column) {
+					if err := tx.Migrator().AddColumn(&user0006{}, column); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			// Plain ALTER TABLE rather than the migrator, which rebuilds SQLite tables without their indexes
+			for _, column := range []string{"avatar_sizes", "avatar_key"} {
+				if err := tx.Exec("ALTER TABLE users DROP COLUMN " + column).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+}
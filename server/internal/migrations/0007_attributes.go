@@ -0,0 +1,55 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// attributeDefinition0007 is the attribute_definitions table as of migration 7.
+type attributeDefinition0007 struct {
+	Id         uint
+	Key        string `gorm:"size:64;uniqueIndex:idx_attribute_definitions_key"`
+	Label      string `gorm:"size:100"`
+	Type       string `gorm:"size:16"`
+	Required   bool
+	Visibility string `gorm:"size:16"`
+	EditableBy string `gorm:"size:16"`
+	MinLength  *int
+	MaxLength  *int
+	Pattern    string `gorm:"size:255"`
+	Min        *float64
+	Max        *float64
+	Options    string `gorm:"type:text"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+func (attributeDefinition0007) TableName() string {
+	return "attribute_definitions"
+}
+
+// userAttribute0007 is the user_attributes table as of migration 7.
+type userAttribute0007 struct {
+	UserId      uint `gorm:"primaryKey;autoIncrement:false"`
+	AttributeId uint `gorm:"primaryKey;autoIncrement:false;index"`
+	Value       string
+	UpdatedAt   time.Time
+}
+
+func (userAttribute0007) TableName() string {
+	return "user_attributes"
+}
+
+func init() {
+	register(Migration{
+		Version: 7,
+		Name:    "create custom attributes",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&attributeDefinition0007{}, &userAttribute0007{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("user_attributes", "attribute_definitions")
+		},
+	})
+}
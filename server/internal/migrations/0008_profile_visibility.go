@@ -0,0 +1,29 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// user0008 holds the profile visibility column added to the users table by migration 8.
+type user0008 struct {
+	ProfileVisibility string `gorm:"type:text"`
+}
+
+func (user0008) TableName() string {
+	return "users"
+}
+
+func init() {
+	register(Migration{
+		Version: 8,
+		Name:    "add profile visibility",
+		Up: func(tx *gorm.DB) error {
+			if tx.Migrator().HasColumn(&user0008{}, "ProfileVisibility") {
+				return nil
+			}
+			return tx.Migrator().AddColumn(&user0008{}, "ProfileVisibility")
+		},
+		Down: func(tx *gorm.DB) error {
+			// Plain ALTER TABLE rather than the migrator, which rebuilds SQLite tables without their indexes
+			return tx.Exec("ALTER TABLE users DROP COLUMN profile_visibility").Error
+		},
+	})
+}
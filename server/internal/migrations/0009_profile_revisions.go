@@ -0,0 +1,37 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// profileRevision0009 is the profile_revisions table as of migration 9.
+type profileRevision0009 struct {
+	Id        uint
+	UserId    uint   `gorm:"index:idx_profile_revisions_user"`
+	Field     string `gorm:"size:100"`
+	OldValue  string
+	NewValue  string
+	ActorId   *uint
+	SessionId string `gorm:"size:64"`
+	RevertOf  *uint
+	CreatedAt time.Time `gorm:"index:idx_profile_revisions_user"`
+}
+
+func (profileRevision0009) TableName() string {
+	return "profile_revisions"
+}
+
+func init() {
+	register(Migration{
+		Version: 9,
+		Name:    "create profile revisions",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&profileRevision0009{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("profile_revisions")
+		},
+	})
+}
@@ -0,0 +1,32 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// userPreferences0010 is the user_preferences table as of migration 10.
+type userPreferences0010 struct {
+	UserId    uint   `gorm:"primaryKey;autoIncrement:false"`
+	Version   int    `gorm:"not null"`
+	Document  string `gorm:"type:text"`
+	UpdatedAt time.Time
+}
+
+func (userPreferences0010) TableName() string {
+	return "user_preferences"
+}
+
+func init() {
+	register(Migration{
+		Version: 10,
+		Name:    "create user preferences",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&userPreferences0010{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("user_preferences")
+		},
+	})
+}
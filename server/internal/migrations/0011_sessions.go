@@ -0,0 +1,40 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// session0011 is the sessions table as of migration 11.
+type session0011 struct {
+	Id         string `gorm:"primaryKey;size:64"`
+	UserId     uint   `gorm:"index"`
+	IP         string `gorm:"size:64"`
+	UserAgent  string `gorm:"size:512"`
+	Browser    string `gorm:"size:64"`
+	OS         string `gorm:"size:64"`
+	DeviceType string `gorm:"size:16"`
+	DeviceName string `gorm:"size:128"`
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	ExpiresAt  time.Time `gorm:"index"`
+	RevokedAt  *time.Time
+}
+
+func (session0011) TableName() string {
+	return "sessions"
+}
+
+func init() {
+	register(Migration{
+		Version: 11,
+		Name:    "create sessions",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&session0011{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("sessions")
+		},
+	})
+}
@@ -0,0 +1,29 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// user0012 holds the role column added to the users table by migration 12.
+type user0012 struct {
+	Role string `gorm:"size:16;not null;default:user"`
+}
+
+func (user0012) TableName() string {
+	return "users"
+}
+
+func init() {
+	register(Migration{
+		Version: 12,
+		Name:    "add user roles",
+		Up: func(tx *gorm.DB) error {
+			if tx.Migrator().HasColumn(&user0012{}, "Role") {
+				return nil
+			}
+			return tx.Migrator().AddColumn(&user0012{}, "Role")
+		},
+		Down: func(tx *gorm.DB) error {
+			// Plain ALTER TABLE rather than the migrator, which rebuilds SQLite tables without their indexes
+			return tx.Exec("ALTER TABLE users DROP COLUMN role").Error
+		},
+	})
+}
@@ -0,0 +1,199 @@
+// Package migrations manages the database schema through ordered, reversible migrations.
+// Each migration lives in its own file, named after its version, and registers itself with register.
+// Migrations use the gorm migrator where possible so they run on every supported dialect,
+// and switch on Dialect where the SQL has to differ.
+// Applied versions are tracked in the schema_migrations table.
+package migrations
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Migration is a single reversible schema change.
+type Migration struct {
+	Version uint                    // Unique, increasing version number
+	Name    string                  // Short description of the change
+	Up      func(tx *gorm.DB) error // Applies the change
+	Down    func(tx *gorm.DB) error // Reverts the change
+}
+
+// SchemaMigration is a row of the schema_migrations table recording an applied migration.
+type SchemaMigration struct {
+	Version   uint   `gorm:"primaryKey;autoIncrement:false"`
+	Name      string `gorm:"size:255"`
+	AppliedAt time.Time
+}
+
+// Status describes whether a known migration has been applied.
+type Status struct {
+	Version   uint
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// registry holds every known migration, kept sorted by version.
+var registry []Migration
+
+// register adds a migration to the registry. It is called from the init function of each migration file.
+func register(m Migration) {
+	for _, existing := range registry {
+		if existing.Version == m.Version {
+			panic(fmt.Sprintf("migrations: duplicate version %d", m.Version))
+		}
+	}
+	registry = append(registry, m)
+	sort.Slice(registry, func(i, j int) bool {
+		return registry[i].Version < registry[j].Version
+	})
+}
+
+// All returns every known migration in version order.
+func All() []Migration {
+	return append([]Migration(nil), registry...)
+}
+
+// Dialect returns the name of the database dialect a migration runs against ("mysql", "postgres" or "sqlite"),
+// for migrations that need dialect-specific SQL.
+func Dialect(tx *gorm.DB) string {
+	return tx.Dialector.Name()
+}
+
+// ensureTable creates the schema_migrations table if it does not exist yet.
+func ensureTable(db *gorm.DB) error {
+	return db.AutoMigrate(&SchemaMigration{})
+}
+
+// applied returns the applied migrations keyed by version, creating the schema_migrations table if needed.
+func applied(db *gorm.DB) (map[uint]SchemaMigration, error) {
+	if err := ensureTable(db); err != nil {
+		return nil, fmt.Errorf("could not create schema_migrations table: %w", err)
+	}
+	return readApplied(db)
+}
+
+// readApplied returns the applied migrations keyed by version without changing the schema.
+// It fails if the schema_migrations table does not exist.
+func readApplied(db *gorm.DB) (map[uint]SchemaMigration, error) {
+	var rows []SchemaMigration
+	if err := db.Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("could not read schema_migrations table: %w", err)
+	}
+
+	result := make(map[uint]SchemaMigration, len(rows))
+	for _, row := range rows {
+		result[row.Version] = row
+	}
+	return result, nil
+}
+
+// Pending returns the migrations that have not been applied yet, in version order.
+// It creates the schema_migrations table if it does not exist yet.
+func Pending(db *gorm.DB) ([]Migration, error) {
+	done, err := applied(db)
+	if err != nil {
+		return nil, err
+	}
+	return pendingFrom(done), nil
+}
+
+// ReadPending is like Pending but only reads the schema_migrations table, so it is cheap enough for
+// the readiness probe. It fails if the table does not exist, that is before Pending or Up first ran.
+func ReadPending(db *gorm.DB) ([]Migration, error) {
+	done, err := readApplied(db)
+	if err != nil {
+		return nil, err
+	}
+	return pendingFrom(done), nil
+}
+
+// pendingFrom returns the registered migrations missing from done, in version order.
+func pendingFrom(done map[uint]SchemaMigration) []Migration {
+	var pending []Migration
+	for _, m := range registry {
+		if _, ok := done[m.Version]; !ok {
+			pending = append(pending, m)
+		}
+	}
+	return pending
+}
+
+// StatusReport returns the status of every known migration in version order.
+func StatusReport(db *gorm.DB) ([]Status, error) {
+	done, err := applied(db)
+	if err != nil {
+		return nil, err
+	}
+
+	report := make([]Status, 0, len(registry))
+	for _, m := range registry {
+		row, ok := done[m.Version]
+		report = append(report, Status{
+			Version:   m.Version,
+			Name:      m.Name,
+			Applied:   ok,
+			AppliedAt: row.AppliedAt,
+		})
+	}
+	return report, nil
+}
+
+// Up applies every pending migration in version order and returns the applied migrations.
+// Each migration runs in its own transaction together with its schema_migrations row.
+func Up(db *gorm.DB) ([]Migration, error) {
+	pending, err := Pending(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, m := range pending {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&SchemaMigration{
+				Version:   m.Version,
+				Name:      m.Name,
+				AppliedAt: time.Now().UTC(),
+			}).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("migration %d (%s) failed: %w", m.Version, m.Name, err)
+		}
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+// Down reverts the given number of most recently applied migrations and returns the reverted migrations.
+func Down(db *gorm.DB, steps int) ([]Migration, error) {
+	done, err := applied(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var reverted []Migration
+	for i := len(registry) - 1; i >= 0 && len(reverted) < steps; i-- {
+		m := registry[i]
+		if _, ok := done[m.Version]; !ok {
+			continue
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Down(tx); err != nil {
+				return err
+			}
+			return tx.Delete(&SchemaMigration{}, m.Version).Error
+		})
+		if err != nil {
+			return reverted, fmt.Errorf("reverting migration %d (%s) failed: %w", m.Version, m.Name, err)
+		}
+		reverted = append(reverted, m)
+	}
+	return reverted, nil
+}
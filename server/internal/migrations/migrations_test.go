@@ -0,0 +1,150 @@
+package migrations
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openDB opens an empty SQLite database.
+func openDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	return db
+}
+
+// versions returns the versions of the given migrations.
+func versions(ms []Migration) []uint {
+	result := make([]uint, len(ms))
+	for i, m := range ms {
+		result[i] = m.Version
+	}
+	return result
+}
+
+func TestRegistryOrder(t *testing.T) {
+	all := All()
+	if len(all) == 0 {
+		t.Fatal("no migrations registered")
+	}
+	for i, m := range all {
+		if m.Version != uint(i+1) {
+			t.Errorf("migration %d has version %d; versions must start at 1 without gaps", i, m.Version)
+		}
+		if m.Name == "" || m.Up == nil || m.Down == nil {
+			t.Errorf("migration %d is incomplete", m.Version)
+		}
+	}
+}
+
+func TestUpDown(t *testing.T) {
+	db := openDB(t)
+	all := All()
+
+	done, err := Up(db)
+	if err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if len(done) != len(all) {
+		t.Fatalf("Up applied %v, want all %d migrations", versions(done), len(all))
+	}
+	if done, err := Up(db); err != nil || len(done) != 0 {
+		t.Fatalf("second Up applied %v, error %v; want nothing", versions(done), err)
+	}
+
+	// Revert the migrations one at a time, checking each reverts the newest applied one and that the
+	// reverted migration applies again cleanly, then revert the rest.
+	for i := len(all) - 1; i >= 0; i-- {
+		t.Run(all[i].Name, func(t *testing.T) {
+			reverted, err := Down(db, 1)
+			if err != nil {
+				t.Fatalf("Down: %v", err)
+			}
+			if len(reverted) != 1 || reverted[0].Version != all[i].Version {
+				t.Fatalf("Down reverted %v, want [%d]", versions(reverted), all[i].Version)
+			}
+			pending, err := Pending(db)
+			if err != nil {
+				t.Fatalf("Pending: %v", err)
+			}
+			if len(pending) != len(all)-i || pending[0].Version != all[i].Version {
+				t.Fatalf("pending %v after reverting %d", versions(pending), all[i].Version)
+			}
+
+			if done, err := Up(db); err != nil || len(done) != len(all)-i {
+				t.Fatalf("Up after Down applied %v, error %v", versions(done), err)
+			}
+			if _, err := Down(db, len(all)-i); err != nil {
+				t.Fatalf("Down again: %v", err)
+			}
+		})
+	}
+
+	if reverted, err := Down(db, 1); err != nil || len(reverted) != 0 {
+		t.Fatalf("Down with nothing applied reverted %v, error %v", versions(reverted), err)
+	}
+	for _, table := range []string{"users", "audit_logs", "sessions"} {
+		if db.Migrator().HasTable(table) {
+			t.Errorf("table %s left after reverting every migration", table)
+		}
+	}
+}
+
+func TestStatusReport(t *testing.T) {
+	db := openDB(t)
+	if _, err := Up(db); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if _, err := Down(db, 2); err != nil {
+		t.Fatalf("Down: %v", err)
+	}
+
+	report, err := StatusReport(db)
+	if err != nil {
+		t.Fatalf("StatusReport: %v", err)
+	}
+	if len(report) != len(All()) {
+		t.Fatalf("report has %d entries, want %d", len(report), len(All()))
+	}
+	for i, status := range report {
+		wantApplied := i < len(report)-2
+		if status.Applied != wantApplied || status.AppliedAt.IsZero() == wantApplied {
+			t.Errorf("migration %d: applied %v at %v, want applied %v", status.Version, status.Applied, status.AppliedAt, wantApplied)
+		}
+	}
+}
+
+func TestReadPending(t *testing.T) {
+	db := openDB(t)
+
+	if _, err := ReadPending(db); err == nil {
+		t.Fatal("ReadPending succeeded without a schema_migrations table")
+	}
+	if db.Migrator().HasTable(&SchemaMigration{}) {
+		t.Fatal("ReadPending created the schema_migrations table")
+	}
+
+	pending, err := Pending(db)
+	if err != nil {
+		t.Fatalf("Pending: %v", err)
+	}
+	if len(pending) != len(All()) {
+		t.Fatalf("Pending = %v, want every migration", versions(pending))
+	}
+	if pending, err := ReadPending(db); err != nil || len(pending) != len(All()) {
+		t.Fatalf("ReadPending = %v, %v; want every migration", versions(pending), err)
+	}
+
+	if _, err := Up(db); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if pending, err := ReadPending(db); err != nil || len(pending) != 0 {
+		t.Fatalf("ReadPending after Up = %v, %v; want none", versions(pending), err)
+	}
+}
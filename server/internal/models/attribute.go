@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// Types of custom profile attributes.
+const (
+	AttributeString  = "string"  // Free text
+	AttributeNumber  = "number"  // JSON number
+	AttributeBoolean = "boolean" // true or false
+	AttributeDate    = "date"    // Calendar date in the form 2006-01-02
+	AttributeEnum    = "enum"    // One of the definition's options
+)
+
+// Visibility of custom profile attributes.
+const (
+	VisibilityPublic  = "public"  // Shown to everyone, including on public profiles
+	VisibilityPrivate = "private" // Shown to the user and administrators
+	VisibilityAdmin   = "admin"   // Shown to administrators only
+)
+
+// Who may change custom profile attribute values.
+const (
+	EditableByUser  = "user"  // The user and administrators
+	EditableByAdmin = "admin" // Administrators only
+)
+
+// AttributeDefinition describes a custom profile attribute defined by an administrator.
+// The validation rules that apply depend on the type; unset rules are not checked.
+type AttributeDefinition struct {
+	Id         uint      `json:"id"`                                                           // Unique identifier for the definition
+	Key        string    `json:"key" gorm:"size:64;uniqueIndex:idx_attribute_definitions_key"` // Name of the attribute in profiles
+	Label      string    `json:"label" gorm:"size:100"`                                        // Human-readable name for forms
+	Type       string    `json:"type" gorm:"size:16"`                                          // One of the Attribute* types; cannot be changed
+	Required   bool      `json:"required"`                                                     // Whether a value must be present
+	Visibility string    `json:"visibility" gorm:"size:16"`                                    // One of the Visibility* values
+	EditableBy string    `json:"editable_by" gorm:"size:16"`                                   // One of the EditableBy* values
+	MinLength  *int      `json:"min_length,omitempty"`                                         // Minimum number of characters (strings)
+	MaxLength  *int      `json:"max_length,omitempty"`                                         // Maximum number of characters (strings)
+	Pattern    string    `json:"pattern,omitempty" gorm:"size:255"`                            // Regular expression the whole value must match (strings)
+	Min        *float64  `json:"min,omitempty"`                                                // Smallest allowed value (numbers)
+	Max        *float64  `json:"max,omitempty"`                                                // Largest allowed value (numbers)
+	Options    []string  `json:"options,omitempty" gorm:"serializer:json;type:text"`           // Allowed values (enums)
+	CreatedAt  time.Time `json:"created_at"`                                                   // Time the definition was created
+	UpdatedAt  time.Time `json:"updated_at"`                                                   // Time the definition was last changed
+}
+
+// UserAttribute is a user's value for a custom profile attribute, stored in a side table.
+type UserAttribute struct {
+	UserId      uint      `gorm:"primaryKey;autoIncrement:false"`       // User the value belongs to
+	AttributeId uint      `gorm:"primaryKey;autoIncrement:false;index"` // Definition the value is for
+	Value       string    // JSON encoding of the value
+	UpdatedAt   time.Time // Time the value was last changed
+}
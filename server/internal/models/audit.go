@@ -0,0 +1,42 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrAuditLogImmutable is returned when something attempts to modify or remove an audit log entry.
+var ErrAuditLogImmutable = errors.New("audit log entries are append-only")
+
+// Sizes of the client columns of audit log entries and sessions.
+const (
+	MaxIPLength        = 64
+	MaxUserAgentLength = 512
+)
+
+// AuditLog represents a single recorded account event.
+// Entries are append-only: the gorm hooks below reject updates and deletes.
+type AuditLog struct {
+	Id        uint            `json:"id"`                          // Unique identifier for the entry
+	ActorId   *uint           `json:"actor_id" gorm:"index"`       // User who performed the action (nil if anonymous)
+	TargetId  *uint           `json:"target_id" gorm:"index"`      // User the action was performed on (nil if unknown)
+	Action    string          `json:"action" gorm:"size:64;index"` // Event name, e.g. "login.success"
+	IP        string          `json:"ip" gorm:"size:64"`           // Client IP address
+	UserAgent string          `json:"user_agent" gorm:"size:512"`  // Client User-Agent header
+	Detail    string          `json:"detail,omitempty"`            // Free-form detail such as a failure reason
+	Changes   json.RawMessage `json:"changes,omitempty"`           // JSON diff of changed fields (secrets redacted)
+	CreatedAt time.Time       `json:"created_at" gorm:"index"`     // Time the event was recorded
+}
+
+// BeforeUpdate prevents existing audit log entries from being modified.
+func (a *AuditLog) BeforeUpdate(tx *gorm.DB) error {
+	return ErrAuditLogImmutable
+}
+
+// BeforeDelete prevents audit log entries from being removed.
+func (a *AuditLog) BeforeDelete(tx *gorm.DB) error {
+	return ErrAuditLogImmutable
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// UserPreferences holds a user's preferences document. The document only contains the settings the user chose;
+// everything else takes its default when the preferences are read.
+type UserPreferences struct {
+	UserId    uint      `json:"-" gorm:"primaryKey;autoIncrement:false"` // User the preferences belong to
+	Version   int       `json:"version" gorm:"not null"`                 // Schema version of the document
+	Document  string    `json:"-" gorm:"type:text"`                      // Preferences as a JSON document
+	UpdatedAt time.Time `json:"updated_at"`                              // Time of the last change
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// ProfileRevision records the change of a single profile field, so earlier values can be listed and restored.
+// Custom attribute fields are named attributes.<key> and their values are stored as JSON.
+type ProfileRevision struct {
+	Id        uint      `json:"id"`                                                 // Unique identifier for the revision
+	UserId    uint      `json:"user_id" gorm:"index:idx_profile_revisions_user"`    // User whose profile changed
+	Field     string    `json:"field" gorm:"size:100"`                              // Name of the changed field, e.g. "email"
+	OldValue  string    `json:"old_value"`                                          // Value before the change ("" = empty)
+	NewValue  string    `json:"new_value"`                                          // Value after the change ("" = empty)
+	ActorId   *uint     `json:"actor_id"`                                           // User who made the change (the user or an administrator)
+	SessionId string    `json:"session_id,omitempty" gorm:"size:64"`                // Session the change was made in, if known
+	RevertOf  *uint     `json:"revert_of,omitempty"`                                // Revision whose old value this change restored
+	CreatedAt time.Time `json:"created_at" gorm:"index:idx_profile_revisions_user"` // Time of the change
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// Session represents a login. Its ID is the "jti" claim of the token issued at the login, so the session can
+// be revoked before the token expires.
+type Session struct {
+	Id         string     `json:"id" gorm:"primaryKey;size:64"` // Random session ID, stored in the token
+	UserId     uint       `json:"-" gorm:"index"`               // User who logged in
+	IP         string     `json:"ip" gorm:"size:64"`            // Client IP address at login
+	UserAgent  string     `json:"user_agent" gorm:"size:512"`   // Client User-Agent header at login
+	Browser    string     `json:"browser" gorm:"size:64"`       // Browser parsed from the User-Agent ("" if unknown)
+	OS         string     `json:"os" gorm:"size:64"`            // Operating system parsed from the User-Agent ("" if unknown)
+	DeviceType string     `json:"device_type" gorm:"size:16"`   // desktop, mobile, tablet, bot or unknown
+	DeviceName string     `json:"device_name" gorm:"size:128"`  // Approximate device description, e.g. "Firefox on Windows"
+	CreatedAt  time.Time  `json:"created_at"`                   // Time of the login
+	LastSeenAt time.Time  `json:"last_seen_at"`                 // Time the session was last used, to within a minute
+	ExpiresAt  time.Time  `json:"expires_at" gorm:"index"`      // Time the token expires
+	RevokedAt  *time.Time `json:"revoked_at"`                   // Time the session was logged out or revoked, if it was
+}
+
+// Active reports whether the session can still be used at the given time.
+func (s *Session) Active(now time.Time) bool {
+	return s.RevokedAt == nil && s.ExpiresAt.After(now)
+}
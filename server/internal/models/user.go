@@ -1,6 +1,10 @@
 package models
 
 import (
+	"strconv"
+	"strings"
+	"time"
+
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -10,13 +14,41 @@ type User struct {
 	Name     string `json:"name"`     // User's name
 	Email    string `json:"email" gorm:"unique"` // User's email address (unique in the database)
 	Password []byte `json:"-"`        // Hashed password (not exposed in JSON)
+	Version  uint   `json:"-" gorm:"not null;default:1"` // Incremented on every update; exposed as the ETag
+	Role     string `json:"role" gorm:"size:16;not null;default:user"` // RoleUser or RoleAdmin; only changed with `server admin`
+	DisplayName string `json:"display_name" gorm:"size:100"` // Name shown to other users (optional)
+	Handle   *string `json:"handle" gorm:"size:30;uniqueIndex:idx_users_handle"` // Unique lower-case handle (optional)
+	Bio      string `json:"bio"`        // Short free-form description (optional)
+	Locale   string `json:"locale" gorm:"size:35"`   // Preferred BCP 47 language tag (optional)
+	Timezone string `json:"timezone" gorm:"size:64"` // IANA time zone name (optional)
+	Phone    string `json:"phone" gorm:"size:20"`    // E.164 phone number (optional)
+	AvatarKey   string `json:"-" gorm:"size:255"` // Storage key prefix of the avatar thumbnails ("" = no avatar)
+	AvatarSizes string `json:"-" gorm:"size:64"`  // Comma-separated thumbnail sizes stored under AvatarKey
+	ProfileVisibility map[string]string `json:"-" gorm:"serializer:json;type:text"` // Visibility of each profile field on the public profile, where chosen by the user
+	CreatedAt time.Time `json:"created_at"` // Time of registration
+	UpdatedAt time.Time `json:"updated_at"` // Time of the last update
+	LastLoginAt *time.Time `json:"last_login_at"` // Time of the last successful login
+}
+
+// User roles.
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin" // May use the admin API
+)
+
+// IsAdmin reports whether the user is an administrator.
+func (u *User) IsAdmin() bool {
+	return u.Role == RoleAdmin
 }
 
+// PasswordCost is the bcrypt cost used by HashPassword.
+const PasswordCost = bcrypt.DefaultCost
+
 // HashPassword hashes the given plaintext password using bcrypt and returns the hashed password.
 // It returns an error if the hashing operation fails.
 func (u *User) HashPassword(plainPassword string) ([]byte, error) {
 	// Generate a hashed password using bcrypt with the default cost
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(plainPassword), bcrypt.DefaultCost)
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(plainPassword), PasswordCost)
 	if err != nil {
 		return nil, err
 	}
@@ -31,3 +63,22 @@ func (u *User) CheckPassword(plainPassword string) bool {
 	err := bcrypt.CompareHashAndPassword(u.Password, []byte(plainPassword))
 	return err == nil // If there's no error, the passwords match
 }
+
+// AvatarThumbnails returns the storage key of each avatar thumbnail by its size, or nil if the user has no avatar.
+func (u *User) AvatarThumbnails() map[int]string {
+	if u.AvatarKey == "" {
+		return nil
+	}
+	keys := map[int]string{}
+	for _, size := range strings.Split(u.AvatarSizes, ",") {
+		if n, err := strconv.Atoi(size); err == nil {
+			keys[n] = AvatarThumbnailKey(u.AvatarKey, n)
+		}
+	}
+	return keys
+}
+
+// AvatarThumbnailKey returns the storage key of the avatar thumbnail of the given size.
+func AvatarThumbnailKey(prefix string, size int) string {
+	return prefix + "/" + strconv.Itoa(size) + ".jpg"
+}
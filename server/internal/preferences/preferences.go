@@ -0,0 +1,204 @@
+// Package preferences defines the per-user preferences document: the UI theme and language, and which email
+// notifications and security alerts the user receives. Stored documents only contain the settings the user chose
+// and are resolved against the defaults below when read, so changing a default affects every user who has not
+// chosen otherwise. Documents carry a schema version and older versions are upgraded when they are read.
+package preferences
+
+import (
+	"encoding/json"
+	"errors"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/problem"
+)
+
+// CurrentVersion is the schema version of documents written by this server.
+const CurrentVersion = 1
+
+// ErrUnsupportedVersion is returned for documents with a schema version newer than CurrentVersion.
+var ErrUnsupportedVersion = errors.New("unsupported preferences schema version")
+
+// upgrades[v] converts a document of schema version v to version v+1. When the schema changes incompatibly,
+// add an upgrade for the previous version and increment CurrentVersion.
+var upgrades = map[int]func(document map[string]json.RawMessage) error{}
+
+// UI themes.
+const (
+	ThemeSystem = "system" // Follow the operating system
+	ThemeLight  = "light"
+	ThemeDark   = "dark"
+)
+
+// Themes lists the valid UI themes.
+var Themes = []string{ThemeSystem, ThemeLight, ThemeDark}
+
+// DefaultLanguage is the UI language of users who have neither chosen one nor set a profile locale.
+const DefaultLanguage = "en"
+
+// EmailCategories are the categories of notification emails and whether they are sent by default.
+var EmailCategories = map[string]bool{
+	"account_activity": true,  // Summaries of activity on the account
+	"product_updates":  true,  // Announcements of new features
+	"newsletter":       false, // Periodic newsletter
+	"tips":             false, // Tips on using the application
+}
+
+// SecurityAlerts are the security alerts and whether they are sent by default.
+var SecurityAlerts = map[string]bool{
+	"new_login":       true, // A login from a new session
+	"password_change": true, // The password was changed
+	"email_change":    true, // The email address was changed
+}
+
+// RequiredSecurityAlerts lists the security alerts users cannot opt out of.
+var RequiredSecurityAlerts = []string{"email_change", "password_change"}
+
+// Document is a stored preferences document. Unset settings are omitted and take their defaults.
+type Document struct {
+	Version            int             `json:"version"`
+	Theme              string          `json:"theme,omitempty"`
+	Language           string          `json:"language,omitempty"`
+	EmailNotifications map[string]bool `json:"email_notifications,omitempty"`
+	SecurityAlerts     map[string]bool `json:"security_alerts,omitempty"`
+}
+
+// Resolved is a preferences document with every setting resolved, as returned to clients.
+type Resolved struct {
+	Version                int             `json:"version"`
+	Theme                  string          `json:"theme"`
+	Language               string          `json:"language"`
+	EmailNotifications     map[string]bool `json:"email_notifications"`
+	SecurityAlerts         map[string]bool `json:"security_alerts"`
+	RequiredSecurityAlerts []string        `json:"required_security_alerts"`
+}
+
+// Upgrade converts a JSON preferences document of any supported schema version to CurrentVersion.
+// Documents without a version are taken to be of the current version.
+func Upgrade(data []byte) ([]byte, error) {
+	return upgradeTo(data, CurrentVersion)
+}
+
+// upgradeTo converts a JSON preferences document to the target schema version like Upgrade.
+func upgradeTo(data []byte, target int) ([]byte, error) {
+	var document map[string]json.RawMessage
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+
+	version := target
+	if raw, ok := document["version"]; ok {
+		if err := json.Unmarshal(raw, &version); err != nil {
+			return nil, err
+		}
+	}
+	if version > target || version < 1 {
+		return nil, ErrUnsupportedVersion
+	}
+	if version == target {
+		return data, nil
+	}
+
+	for ; version < target; version++ {
+		upgrade, ok := upgrades[version]
+		if !ok {
+			return nil, ErrUnsupportedVersion
+		}
+		if err := upgrade(document); err != nil {
+			return nil, err
+		}
+	}
+	encoded, err := json.Marshal(target)
+	if err != nil {
+		return nil, err
+	}
+	document["version"] = encoded
+	return json.Marshal(document)
+}
+
+// Decode upgrades and decodes a stored preferences document.
+func Decode(data string) (Document, error) {
+	var document Document
+	upgraded, err := Upgrade([]byte(data))
+	if err != nil {
+		return document, err
+	}
+	err = json.Unmarshal(upgraded, &document)
+	return document, err
+}
+
+// Check validates a document of the current version and returns an error for each invalid setting.
+func Check(document *Document) []problem.FieldError {
+	var errors []problem.FieldError
+	if document.Theme != "" && !slices.Contains(Themes, document.Theme) {
+		errors = append(errors, problem.FieldError{Field: "theme", Code: problem.FieldInvalid, Message: "theme must be one of " + strings.Join(Themes, ", ")})
+	}
+	for category := range document.EmailNotifications {
+		if _, ok := EmailCategories[category]; !ok {
+			name := "email_notifications." + category
+			errors = append(errors, problem.FieldError{Field: name, Code: problem.FieldUnknown, Message: name + " is not a notification category"})
+		}
+	}
+	for alert, enabled := range document.SecurityAlerts {
+		name := "security_alerts." + alert
+		if _, ok := SecurityAlerts[alert]; !ok {
+			errors = append(errors, problem.FieldError{Field: name, Code: problem.FieldUnknown, Message: name + " is not a security alert"})
+			continue
+		}
+		if !enabled && slices.Contains(RequiredSecurityAlerts, alert) {
+			errors = append(errors, problem.FieldError{Field: name, Code: problem.FieldReadOnly, Message: name + " cannot be disabled"})
+		}
+	}
+
+	sort.Slice(errors, func(i, j int) bool { return errors[i].Field < errors[j].Field })
+	return errors
+}
+
+// Resolve fills in the defaults for every setting the document leaves unset. The language defaults to the
+// user's profile locale, if any.
+func Resolve(document Document, locale string) Resolved {
+	resolved := Resolved{
+		Version:                CurrentVersion,
+		Theme:                  ThemeSystem,
+		Language:               DefaultLanguage,
+		EmailNotifications:     maps.Clone(EmailCategories),
+		SecurityAlerts:         maps.Clone(SecurityAlerts),
+		RequiredSecurityAlerts: RequiredSecurityAlerts,
+	}
+	if document.Theme != "" {
+		resolved.Theme = document.Theme
+	}
+	if document.Language != "" {
+		resolved.Language = document.Language
+	} else if locale != "" {
+		resolved.Language = locale
+	}
+	for category, enabled := range document.EmailNotifications {
+		if _, ok := EmailCategories[category]; ok {
+			resolved.EmailNotifications[category] = enabled
+		}
+	}
+	for alert, enabled := range document.SecurityAlerts {
+		if _, ok := SecurityAlerts[alert]; ok && !slices.Contains(RequiredSecurityAlerts, alert) {
+			resolved.SecurityAlerts[alert] = enabled
+		}
+	}
+	return resolved
+}
+
+// Fields returns the settings of resolved preferences as comparable values by field name, for auditing changes.
+func Fields(resolved Resolved) map[string]any {
+	fields := map[string]any{
+		"theme":    resolved.Theme,
+		"language": resolved.Language,
+	}
+	for category, enabled := range resolved.EmailNotifications {
+		fields["email_notifications."+category] = enabled
+	}
+	for alert, enabled := range resolved.SecurityAlerts {
+		fields["security_alerts."+alert] = enabled
+	}
+	return fields
+}
@@ -0,0 +1,223 @@
+package preferences
+
+import (
+	"encoding/json"
+	"errors"
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/problem"
+)
+
+func TestUpgrade(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		err  bool // Otherwise the document must be returned unchanged
+	}{
+		{"current version", `{"version": 1, "theme": "dark"}`, false},
+		{"no version", `{"theme": "dark"}`, false},
+		{"newer version", `{"version": 2, "theme": "dark"}`, true},
+		{"version 0", `{"version": 0}`, true},
+		{"version of the wrong type", `{"version": "1"}`, true},
+		{"not an object", `["theme"]`, true},
+		{"malformed", `{"theme": `, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Upgrade([]byte(tt.data))
+			if tt.err {
+				if err == nil {
+					t.Fatalf("Upgrade succeeded with %s", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Upgrade: %v", err)
+			}
+			if string(got) != tt.data {
+				t.Fatalf("Upgrade = %s, want the document unchanged", got)
+			}
+		})
+	}
+
+	if _, err := Upgrade([]byte(`{"version": 2}`)); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("newer version: error %v, want ErrUnsupportedVersion", err)
+	}
+}
+
+// TestUpgradeChain upgrades through a chain of made-up schema versions, as the real schema has only one.
+func TestUpgradeChain(t *testing.T) {
+	saved := upgrades
+	t.Cleanup(func() { upgrades = saved })
+	upgrades = map[int]func(document map[string]json.RawMessage) error{
+		// Version 2 replaced the dark_mode flag with the theme
+		1: func(document map[string]json.RawMessage) error {
+			if string(document["dark_mode"]) == "true" {
+				document["theme"] = json.RawMessage(`"dark"`)
+			}
+			delete(document, "dark_mode")
+			return nil
+		},
+		// Version 3 renamed the newsletter category
+		2: func(document map[string]json.RawMessage) error {
+			var notifications map[string]bool
+			if raw, ok := document["email_notifications"]; ok {
+				if err := json.Unmarshal(raw, &notifications); err != nil {
+					return err
+				}
+			}
+			if enabled, ok := notifications["news"]; ok {
+				notifications["newsletter"] = enabled
+				delete(notifications, "news")
+				encoded, err := json.Marshal(notifications)
+				if err != nil {
+					return err
+				}
+				document["email_notifications"] = encoded
+			}
+			return nil
+		},
+	}
+
+	tests := []struct {
+		name string
+		data string
+		want Document
+		err  bool
+	}{
+		{"version 1", `{"version": 1, "dark_mode": true, "email_notifications": {"news": true}}`,
+			Document{Version: 3, Theme: "dark", EmailNotifications: map[string]bool{"newsletter": true}}, false},
+		{"version 2", `{"version": 2, "theme": "light", "email_notifications": {"news": false}}`,
+			Document{Version: 3, Theme: "light", EmailNotifications: map[string]bool{"newsletter": false}}, false},
+		{"version 3", `{"version": 3, "theme": "light"}`, Document{Version: 3, Theme: "light"}, false},
+		{"failing upgrade", `{"version": 2, "email_notifications": "all"}`, Document{}, true},
+		{"newer version", `{"version": 4}`, Document{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := upgradeTo([]byte(tt.data), 3)
+			if tt.err {
+				if err == nil {
+					t.Fatalf("upgrade succeeded with %s", data)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("upgrade: %v", err)
+			}
+			var got Document
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatalf("decode %s: %v", data, err)
+			}
+			if got.Version != tt.want.Version || got.Theme != tt.want.Theme || !maps.Equal(got.EmailNotifications, tt.want.EmailNotifications) {
+				t.Fatalf("upgraded to %s, want %+v", data, tt.want)
+			}
+		})
+	}
+
+	// A missing upgrade step is reported rather than skipped
+	delete(upgrades, 2)
+	if _, err := upgradeTo([]byte(`{"version": 1}`), 3); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("missing upgrade: error %v, want ErrUnsupportedVersion", err)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		document Document
+		errors   map[string]string // Error code by field
+	}{
+		{"empty", Document{}, nil},
+		{"valid", Document{
+			Theme:              ThemeDark,
+			EmailNotifications: map[string]bool{"newsletter": true, "tips": false},
+			SecurityAlerts:     map[string]bool{"new_login": false, "password_change": true},
+		}, nil},
+		{"invalid theme", Document{Theme: "blue"}, map[string]string{"theme": problem.FieldInvalid}},
+		{"unknown categories", Document{
+			EmailNotifications: map[string]bool{"spam": true},
+			SecurityAlerts:     map[string]bool{"logout": true},
+		}, map[string]string{"email_notifications.spam": problem.FieldUnknown, "security_alerts.logout": problem.FieldUnknown}},
+		{"required alerts", Document{
+			SecurityAlerts: map[string]bool{"email_change": false, "password_change": false, "new_login": false},
+		}, map[string]string{"security_alerts.email_change": problem.FieldReadOnly, "security_alerts.password_change": problem.FieldReadOnly}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errors := Check(&tt.document)
+			codes := map[string]string{}
+			for i, err := range errors {
+				codes[err.Field] = err.Code
+				if i > 0 && errors[i-1].Field > err.Field {
+					t.Errorf("errors not sorted by field: %+v", errors)
+				}
+			}
+			if len(codes) != len(tt.errors) || (len(codes) > 0 && !maps.Equal(codes, tt.errors)) {
+				t.Fatalf("errors = %+v, want %v", errors, tt.errors)
+			}
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		name     string
+		document Document
+		locale   string
+		theme    string
+		language string
+		email    map[string]bool // Notification settings that differ from the defaults
+		alerts   map[string]bool // Security alert settings that differ from the defaults
+	}{
+		{"defaults", Document{}, "", ThemeSystem, DefaultLanguage, nil, nil},
+		{"profile locale", Document{}, "bg-BG", ThemeSystem, "bg-BG", nil, nil},
+		{"chosen language", Document{Language: "de"}, "bg-BG", ThemeSystem, "de", nil, nil},
+		{"chosen settings", Document{
+			Theme:              ThemeDark,
+			EmailNotifications: map[string]bool{"newsletter": true, "product_updates": false},
+			SecurityAlerts:     map[string]bool{"new_login": false},
+		}, "", ThemeDark, DefaultLanguage,
+			map[string]bool{"newsletter": true, "product_updates": false}, map[string]bool{"new_login": false}},
+		{"unknown and required settings are ignored", Document{
+			EmailNotifications: map[string]bool{"spam": true},
+			SecurityAlerts:     map[string]bool{"password_change": false, "logout": true},
+		}, "", ThemeSystem, DefaultLanguage, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Resolve(tt.document, tt.locale)
+
+			email := maps.Clone(EmailCategories)
+			maps.Copy(email, tt.email)
+			alerts := maps.Clone(SecurityAlerts)
+			maps.Copy(alerts, tt.alerts)
+			if got.Version != CurrentVersion || got.Theme != tt.theme || got.Language != tt.language ||
+				!maps.Equal(got.EmailNotifications, email) || !maps.Equal(got.SecurityAlerts, alerts) ||
+				!slices.Equal(got.RequiredSecurityAlerts, RequiredSecurityAlerts) {
+				t.Fatalf("Resolve = %+v", got)
+			}
+		})
+	}
+
+	// Resolving must not change the defaults shared by every user
+	Resolve(Document{EmailNotifications: map[string]bool{"newsletter": true}}, "")
+	if EmailCategories["newsletter"] {
+		t.Fatal("Resolve changed the default email categories")
+	}
+}
+
+func TestDecode(t *testing.T) {
+	document, err := Decode(`{"version": 1, "theme": "light", "security_alerts": {"new_login": false}}`)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if document.Theme != ThemeLight || document.SecurityAlerts["new_login"] {
+		t.Fatalf("Decode = %+v", document)
+	}
+	if _, err := Decode(`{"version": 2}`); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Fatalf("Decode of a newer version: error %v", err)
+	}
+}
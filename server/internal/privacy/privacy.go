@@ -0,0 +1,96 @@
+// Package privacy decides which profile fields are shown on public profile pages. Each user chooses per field
+// whether it is shown to everyone, to signed-in users only, or to nobody; fields they have not chosen for use the
+// defaults below, which keep contact details and the account name private unless the user opts in.
+package privacy
+
+import (
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/problem"
+)
+
+// Visibility levels of profile fields.
+const (
+	Public        = "public"        // Shown to everyone, including anonymous visitors
+	Authenticated = "authenticated" // Shown to signed-in users
+	Private       = "private"       // Only shown to the user themselves
+)
+
+// Levels lists the visibility levels from most to least visible.
+var Levels = []string{Public, Authenticated, Private}
+
+// Defaults are the visibility of each field a user has not chosen a level for. Only these fields can appear
+// on a public profile, in addition to the handle and the public custom attributes.
+var Defaults = map[string]string{
+	"name":         Private,
+	"display_name": Public,
+	"bio":          Public,
+	"avatar":       Public,
+	"locale":       Private,
+	"timezone":     Private,
+	"email":        Private,
+	"phone":        Private,
+	"created_at":   Authenticated,
+}
+
+// Effective returns the visibility of every field, using the user's settings where present and the defaults
+// otherwise. Settings for unknown fields are ignored.
+func Effective(settings map[string]string) map[string]string {
+	effective := make(map[string]string, len(Defaults))
+	for field, level := range Defaults {
+		if chosen, ok := settings[field]; ok && slices.Contains(Levels, chosen) {
+			level = chosen
+		}
+		effective[field] = level
+	}
+	return effective
+}
+
+// Visible reports whether a field with the given level is shown to a viewer who is signed in or not.
+func Visible(level string, authenticated bool) bool {
+	switch level {
+	case Public:
+		return true
+	case Authenticated:
+		return authenticated
+	default:
+		return false
+	}
+}
+
+// Apply validates a patch of visibility levels by field and returns the user's settings with it applied.
+// A nil level resets the field to its default. Errors name the field as profile_visibility.<field>.
+func Apply(settings map[string]string, patch map[string]*string) (map[string]string, []problem.FieldError) {
+	updated := make(map[string]string, len(settings))
+	for field, level := range settings {
+		updated[field] = level
+	}
+
+	var errors []problem.FieldError
+	for field, level := range patch {
+		name := "profile_visibility." + field
+		if _, ok := Defaults[field]; !ok {
+			errors = append(errors, problem.FieldError{Field: name, Code: problem.FieldUnknown, Message: name + " is not a profile field"})
+			continue
+		}
+		if level == nil {
+			delete(updated, field)
+			continue
+		}
+		value := strings.ToLower(strings.TrimSpace(*level))
+		if !slices.Contains(Levels, value) {
+			errors = append(errors, problem.FieldError{
+				Field:   name,
+				Code:    problem.FieldInvalid,
+				Message: name + " must be one of " + strings.Join(Levels, ", "),
+			})
+			continue
+		}
+		updated[field] = value
+	}
+
+	sort.Slice(errors, func(i, j int) bool { return errors[i].Field < errors[j].Field })
+	return updated, errors
+}
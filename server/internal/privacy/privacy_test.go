@@ -0,0 +1,120 @@
+package privacy
+
+import (
+	"maps"
+	"testing"
+
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/problem"
+)
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func TestEffective(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings map[string]string
+		want     map[string]string // Fields that differ from the defaults
+	}{
+		{"no settings", nil, nil},
+		{"chosen levels", map[string]string{"email": Public, "bio": Private}, map[string]string{"email": Public, "bio": Private}},
+		{"unknown field", map[string]string{"password": Public}, nil},
+		{"invalid level", map[string]string{"phone": "friends"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := maps.Clone(Defaults)
+			maps.Copy(want, tt.want)
+			if got := Effective(tt.settings); !maps.Equal(got, want) {
+				t.Fatalf("Effective = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestVisible(t *testing.T) {
+	tests := []struct {
+		level         string
+		anonymous     bool
+		authenticated bool
+	}{
+		{Public, true, true},
+		{Authenticated, false, true},
+		{Private, false, false},
+		{"", false, false},
+		{"friends", false, false},
+	}
+	for _, tt := range tests {
+		if got := Visible(tt.level, false); got != tt.anonymous {
+			t.Errorf("Visible(%q, false) = %v, want %v", tt.level, got, tt.anonymous)
+		}
+		if got := Visible(tt.level, true); got != tt.authenticated {
+			t.Errorf("Visible(%q, true) = %v, want %v", tt.level, got, tt.authenticated)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings map[string]string
+		patch    map[string]*string
+		want     map[string]string
+		errors   map[string]string // Error code by field
+	}{
+		{
+			name:  "set levels",
+			patch: map[string]*string{"email": strPtr("authenticated"), "bio": strPtr(" Private ")},
+			want:  map[string]string{"email": Authenticated, "bio": Private},
+		},
+		{
+			name:     "keep other settings",
+			settings: map[string]string{"phone": Public},
+			patch:    map[string]*string{"email": strPtr("public")},
+			want:     map[string]string{"phone": Public, "email": Public},
+		},
+		{
+			name:     "null resets to the default",
+			settings: map[string]string{"phone": Public, "email": Public},
+			patch:    map[string]*string{"phone": nil},
+			want:     map[string]string{"email": Public},
+		},
+		{
+			name:     "unknown field",
+			settings: map[string]string{"phone": Public},
+			patch:    map[string]*string{"password": strPtr("public"), "email": strPtr("public")},
+			want:     map[string]string{"phone": Public, "email": Public},
+			errors:   map[string]string{"profile_visibility.password": problem.FieldUnknown},
+		},
+		{
+			name:   "invalid level",
+			patch:  map[string]*string{"bio": strPtr("friends"), "phone": strPtr("")},
+			want:   map[string]string{},
+			errors: map[string]string{"profile_visibility.bio": problem.FieldInvalid, "profile_visibility.phone": problem.FieldInvalid},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := maps.Clone(tt.settings)
+			got, errors := Apply(tt.settings, tt.patch)
+			if !maps.Equal(got, tt.want) {
+				t.Errorf("settings = %v, want %v", got, tt.want)
+			}
+			if !maps.Equal(tt.settings, before) {
+				t.Errorf("Apply changed the settings passed in to %v", tt.settings)
+			}
+
+			codes := map[string]string{}
+			for i, err := range errors {
+				codes[err.Field] = err.Code
+				if i > 0 && errors[i-1].Field > err.Field {
+					t.Errorf("errors not sorted by field: %+v", errors)
+				}
+			}
+			if len(codes) != len(tt.errors) || (len(codes) > 0 && !maps.Equal(codes, tt.errors)) {
+				t.Errorf("errors = %+v, want %v", errors, tt.errors)
+			}
+		})
+	}
+}
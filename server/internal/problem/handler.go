@@ -0,0 +1,66 @@
+package problem
+
+import (
+	"errors"
+
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/logging"
+	"github.com/gofiber/fiber/v3"
+)
+
+// ErrorHandler writes every error returned by a handler or middleware as a problem response.
+// Problems are sent as they are, Fiber errors (such as unmatched routes) are converted, and any other
+// error becomes a generic 500 problem whose cause is only logged.
+func ErrorHandler(c fiber.Ctx, err error) error {
+	var p *Problem
+	var fiberErr *fiber.Error
+	switch {
+	case errors.As(err, &p):
+		// Copy so the request-specific members do not leak into shared problem values
+		copied := *p
+		p = &copied
+	case errors.As(err, &fiberErr):
+		p = New(fiberErr.Code, codeForStatus(fiberErr.Code), fiberErr.Message)
+	default:
+		p = Internal("An unexpected error occurred.", err)
+	}
+
+	p.Instance = c.Path()
+	p.RequestID = logging.RequestID(c)
+
+	if p.Status >= fiber.StatusInternalServerError {
+		logging.FromContext(c.UserContext()).Error("Request failed", "code", p.Code, "error", err)
+	} else if p.cause != nil {
+		logging.FromContext(c.UserContext()).Debug("Request rejected", "code", p.Code, "error", p.cause)
+	}
+
+	return c.Status(p.Status).JSON(p, ContentType)
+}
+
+// codeForStatus returns the problem code for Fiber errors, which only carry an HTTP status.
+func codeForStatus(status int) string {
+	switch status {
+	case fiber.StatusBadRequest, fiber.StatusUnprocessableEntity:
+		return CodeInvalidRequest
+	case fiber.StatusUnauthorized:
+		return CodeUnauthenticated
+	case fiber.StatusForbidden:
+		return CodeForbidden
+	case fiber.StatusNotFound:
+		return CodeNotFound
+	case fiber.StatusMethodNotAllowed:
+		return CodeMethodNotAllowed
+	case fiber.StatusRequestEntityTooLarge:
+		return CodePayloadTooLarge
+	case fiber.StatusUnsupportedMediaType:
+		return CodeUnsupportedMedia
+	case fiber.StatusPreconditionFailed:
+		return CodePreconditionFailed
+	case fiber.StatusConflict:
+		return CodeEditConflict
+	default:
+		if status >= fiber.StatusInternalServerError {
+			return CodeInternal
+		}
+		return CodeInvalidRequest
+	}
+}
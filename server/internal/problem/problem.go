@@ -0,0 +1,103 @@
+// Package problem defines the API's error responses, which follow RFC 7807 (application/problem+json).
+// Every problem carries a stable machine-readable code so clients can branch on it rather than on messages.
+package problem
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// ContentType is the media type of problem responses.
+const ContentType = "application/problem+json"
+
+// Machine-readable problem codes. They are part of the API and must not change once published.
+const (
+	CodeInvalidRequest     = "invalid_request"     // The body or query could not be parsed
+	CodeValidationFailed   = "validation_failed"   // One or more fields are invalid; see the errors member
+	CodeUnauthenticated    = "unauthenticated"     // No valid credentials were sent
+	CodeTokenExpired       = "token_expired"       // The token was valid but has expired
+	CodeSessionRevoked     = "session_revoked"     // The token's session was logged out or revoked
+	CodeIncorrectPassword  = "incorrect_password"  // The password does not match
+	CodeForbidden          = "forbidden"           // The caller may not perform the operation
+	CodeInvalidCSRFToken   = "invalid_csrf_token"  // The CSRF header is missing or does not match the cookie
+	CodeUserNotFound       = "user_not_found"      // The user does not exist
+	CodeEmailInUse         = "email_in_use"        // Another account already uses the email address
+	CodeNotFound           = "not_found"           // No route matches the request
+	CodeMethodNotAllowed   = "method_not_allowed"  // The route does not support the method
+	CodePayloadTooLarge    = "payload_too_large"   // The request body is too large
+	CodeUnsupportedMedia   = "unsupported_media"   // The request body is not JSON
+	CodePreconditionFailed = "precondition_failed" // The If-Match header does not match the current version
+	CodeEditConflict       = "edit_conflict"       // The resource was changed by another request at the same time
+	CodeInternal           = "internal_error"      // An unexpected server error; details are only logged
+)
+
+// Field validation codes used in FieldError.Code.
+const (
+	FieldRequired = "required"
+	FieldInvalid  = "invalid"
+	FieldTooLong  = "too_long"
+	FieldTooShort = "too_short"
+	FieldTaken    = "taken"
+	FieldUnknown  = "unknown"   // The field is not part of the request schema
+	FieldReadOnly = "read_only" // The field may not be changed by the caller
+)
+
+// FieldError describes why a single request field is invalid.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Problem is an RFC 7807 problem detail. It implements error, so handlers return it and the
+// ErrorHandler writes the response.
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      string       `json:"code"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+
+	cause error // Underlying error, logged but never sent to the client
+}
+
+// New returns a problem with the given HTTP status, code and human-readable detail.
+func New(status int, code, detail string) *Problem {
+	return &Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Code:   code,
+		Detail: detail,
+	}
+}
+
+// Validation returns a 400 problem listing the invalid fields.
+func Validation(errors ...FieldError) *Problem {
+	p := New(http.StatusBadRequest, CodeValidationFailed, "The request contains invalid fields.")
+	p.Errors = errors
+	return p
+}
+
+// Internal returns a 500 problem with a generic detail. The cause is logged by the ErrorHandler.
+func Internal(detail string, cause error) *Problem {
+	p := New(http.StatusInternalServerError, CodeInternal, detail)
+	p.cause = cause
+	return p
+}
+
+// Error returns the code and detail of the problem, and the cause if there is one.
+func (p *Problem) Error() string {
+	if p.cause != nil {
+		return fmt.Sprintf("%s: %s: %v", p.Code, p.Detail, p.cause)
+	}
+	return p.Code + ": " + p.Detail
+}
+
+// Unwrap returns the underlying error.
+func (p *Problem) Unwrap() error {
+	return p.cause
+}
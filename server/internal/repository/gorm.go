@@ -0,0 +1,470 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// translateError maps gorm errors onto the repository errors.
+func translateError(err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return ErrNotFound
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		// Refined to ErrDuplicateHandle by duplicateField where users are written
+		return ErrDuplicateEmail
+	default:
+		return err
+	}
+}
+
+// emailCondition returns a case-insensitive email comparison that can use the dialect's unique email index.
+// MySQL compares case-insensitively by default; PostgreSQL and SQLite have dedicated indexes (see migration 3).
+func emailCondition(db *gorm.DB) string {
+	switch db.Dialector.Name() {
+	case "postgres":
+		return "LOWER(email) = LOWER(?)"
+	case "sqlite":
+		return "email = ? COLLATE NOCASE"
+	default:
+		return "email = ?"
+	}
+}
+
+// GormUserRepository is a UserRepository backed by a gorm database connection.
+type GormUserRepository struct {
+	db *gorm.DB
+}
+
+// NewGormUserRepository returns a UserRepository that uses the given gorm connection.
+// The connection should be opened with TranslateError enabled so duplicate emails are reported as ErrDuplicateEmail.
+func NewGormUserRepository(db *gorm.DB) *GormUserRepository {
+	return &GormUserRepository{db: db}
+}
+
+// FindByEmail returns the user with the given email address, or ErrNotFound.
+func (r *GormUserRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	var user models.User
+	if err := r.db.WithContext(ctx).Where(emailCondition(r.db), email).First(&user).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &user, nil
+}
+
+// FindByID returns the user with the given ID, or ErrNotFound.
+func (r *GormUserRepository) FindByID(ctx context.Context, id uint) (*models.User, error) {
+	var user models.User
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&user).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &user, nil
+}
+
+// FindByHandle returns the user with the given handle, or ErrNotFound.
+func (r *GormUserRepository) FindByHandle(ctx context.Context, handle string) (*models.User, error) {
+	var user models.User
+	if err := r.db.WithContext(ctx).Where("handle = ?", handle).First(&user).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &user, nil
+}
+
+// Create stores a new user and assigns its ID and first version.
+func (r *GormUserRepository) Create(ctx context.Context, user *models.User) error {
+	user.Version = 1
+	return r.duplicateField(ctx, user, translateError(r.db.WithContext(ctx).Create(user).Error))
+}
+
+// Update saves all fields of an existing user except its role and increments its version. The update only matches the row
+// if its version is still user.Version, so a concurrent change is reported as ErrConflict instead of being overwritten.
+func (r *GormUserRepository) Update(ctx context.Context, user *models.User) error {
+	updated := *user
+	updated.Version++
+	result := r.db.WithContext(ctx).Model(&updated).Where("version = ?", user.Version).
+		Select("*").Omit("id", "role", "created_at", "last_login_at").Updates(&updated)
+	if result.Error != nil {
+		return r.duplicateField(ctx, user, translateError(result.Error))
+	}
+	if result.RowsAffected == 0 {
+		if _, err := r.FindByID(ctx, user.Id); err != nil {
+			return err
+		}
+		return ErrConflict
+	}
+	*user = updated
+	return nil
+}
+
+// SetRole changes the role of the user with the given ID and increments its version, or returns ErrNotFound.
+func (r *GormUserRepository) SetRole(ctx context.Context, id uint, role string) error {
+	result := r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", id).
+		Updates(map[string]any{"role": role, "version": gorm.Expr("version + 1")})
+	if result.Error != nil {
+		return translateError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// FindByRole returns the users with the given role, ordered by ID.
+func (r *GormUserRepository) FindByRole(ctx context.Context, role string) ([]models.User, error) {
+	var users []models.User
+	err := r.db.WithContext(ctx).Where("role = ?", role).Order("id").Find(&users).Error
+	return users, translateError(err)
+}
+
+// RecordLogin stores the time of a successful login without changing the user's version.
+func (r *GormUserRepository) RecordLogin(ctx context.Context, id uint, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", id).UpdateColumn("last_login_at", at).Error
+}
+
+// duplicateField tells which unique column a duplicate key error was caused by, as the error itself does not say.
+// Other errors are returned unchanged.
+func (r *GormUserRepository) duplicateField(ctx context.Context, user *models.User, err error) error {
+	if !errors.Is(err, ErrDuplicateEmail) {
+		return err
+	}
+	if other, findErr := r.FindByEmail(ctx, user.Email); findErr == nil && other.Id != user.Id {
+		return ErrDuplicateEmail
+	}
+	if user.Handle != nil {
+		if other, findErr := r.FindByHandle(ctx, *user.Handle); findErr == nil && other.Id != user.Id {
+			return ErrDuplicateHandle
+		}
+	}
+	return err
+}
+
+// Delete removes the user with the given ID together with their attribute values, profile revisions, preferences
+// and sessions, or returns ErrNotFound.
+func (r *GormUserRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", id).Delete(&models.UserAttribute{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", id).Delete(&models.ProfileRevision{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", id).Delete(&models.UserPreferences{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", id).Delete(&models.Session{}).Error; err != nil {
+			return err
+		}
+		result := tx.Where("id = ?", id).Delete(&models.User{})
+		if result.Error != nil {
+			return translateError(result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+}
+
+// GormAuditRepository is an AuditRepository backed by a gorm database connection.
+type GormAuditRepository struct {
+	db *gorm.DB
+}
+
+// NewGormAuditRepository returns an AuditRepository that uses the given gorm connection.
+func NewGormAuditRepository(db *gorm.DB) *GormAuditRepository {
+	return &GormAuditRepository{db: db}
+}
+
+// Append stores a new audit log entry and assigns its ID.
+func (r *GormAuditRepository) Append(ctx context.Context, entry *models.AuditLog) error {
+	return r.db.WithContext(ctx).Create(entry).Error
+}
+
+// List returns the entries matching the filter, newest first, together with the total number of matches.
+func (r *GormAuditRepository) List(ctx context.Context, filter AuditFilter) ([]models.AuditLog, int64, error) {
+	query := r.db.WithContext(ctx).Model(&models.AuditLog{})
+
+	if filter.ActorId != nil {
+		query = query.Where("actor_id = ?", *filter.ActorId)
+	}
+	if filter.TargetId != nil {
+		query = query.Where("target_id = ?", *filter.TargetId)
+	}
+	if filter.UserId != nil {
+		query = query.Where("actor_id = ? OR target_id = ?", *filter.UserId, *filter.UserId)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where("created_at >= ?", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		query = query.Where("created_at < ?", filter.Until)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var entries []models.AuditLog
+	err := query.Order("created_at DESC, id DESC").Limit(filter.Limit).Offset(filter.Offset).Find(&entries).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return entries, total, nil
+}
+
+// GormAttributeRepository is an AttributeRepository backed by a gorm database connection.
+type GormAttributeRepository struct {
+	db *gorm.DB
+}
+
+// NewGormAttributeRepository returns an AttributeRepository that uses the given gorm connection.
+func NewGormAttributeRepository(db *gorm.DB) *GormAttributeRepository {
+	return &GormAttributeRepository{db: db}
+}
+
+// ListDefinitions returns every attribute definition in the order they were created.
+func (r *GormAttributeRepository) ListDefinitions(ctx context.Context) ([]models.AttributeDefinition, error) {
+	var defs []models.AttributeDefinition
+	if err := r.db.WithContext(ctx).Order("id").Find(&defs).Error; err != nil {
+		return nil, err
+	}
+	return defs, nil
+}
+
+// FindDefinition returns the definition with the given key, or ErrNotFound.
+func (r *GormAttributeRepository) FindDefinition(ctx context.Context, key string) (*models.AttributeDefinition, error) {
+	var def models.AttributeDefinition
+	// KEY is a reserved word in MySQL, so let gorm quote the column
+	if err := r.db.WithContext(ctx).Where(clause.Eq{Column: clause.Column{Name: "key"}, Value: key}).First(&def).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &def, nil
+}
+
+// CreateDefinition stores a new definition and assigns its ID, or returns ErrDuplicateKey.
+func (r *GormAttributeRepository) CreateDefinition(ctx context.Context, def *models.AttributeDefinition) error {
+	err := r.db.WithContext(ctx).Create(def).Error
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrDuplicateKey
+	}
+	return err
+}
+
+// UpdateDefinition saves all fields of an existing definition, or returns ErrNotFound.
+func (r *GormAttributeRepository) UpdateDefinition(ctx context.Context, def *models.AttributeDefinition) error {
+	result := r.db.WithContext(ctx).Model(def).Select("*").Omit("id", "key", "created_at").Updates(def)
+	if result.Error != nil {
+		return translateError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteDefinition removes the definition with the given ID together with every value stored for it, or returns ErrNotFound.
+func (r *GormAttributeRepository) DeleteDefinition(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("attribute_id = ?", id).Delete(&models.UserAttribute{}).Error; err != nil {
+			return err
+		}
+		result := tx.Where("id = ?", id).Delete(&models.AttributeDefinition{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+}
+
+// Values returns the attribute values of the user.
+func (r *GormAttributeRepository) Values(ctx context.Context, userId uint) ([]models.UserAttribute, error) {
+	var values []models.UserAttribute
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userId).Find(&values).Error; err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// SetValues stores or replaces the given values of the user and removes the values of the attributes
+// with the removed IDs in a single transaction.
+func (r *GormAttributeRepository) SetValues(ctx context.Context, userId uint, values []models.UserAttribute, removed []uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if len(removed) > 0 {
+			err := tx.Where("user_id = ? AND attribute_id IN ?", userId, removed).Delete(&models.UserAttribute{}).Error
+			if err != nil {
+				return err
+			}
+		}
+		if len(values) == 0 {
+			return nil
+		}
+		for i := range values {
+			values[i].UserId = userId
+		}
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "attribute_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"value", "updated_at"}),
+		}).Create(&values).Error
+	})
+}
+
+// GormRevisionRepository is a RevisionRepository backed by a gorm database connection.
+type GormRevisionRepository struct {
+	db *gorm.DB
+}
+
+// NewGormRevisionRepository returns a RevisionRepository that uses the given gorm connection.
+func NewGormRevisionRepository(db *gorm.DB) *GormRevisionRepository {
+	return &GormRevisionRepository{db: db}
+}
+
+// Append stores new revisions and assigns their IDs.
+func (r *GormRevisionRepository) Append(ctx context.Context, revisions []models.ProfileRevision) error {
+	if len(revisions) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Create(&revisions).Error
+}
+
+// Find returns the revision with the given ID, or ErrNotFound.
+func (r *GormRevisionRepository) Find(ctx context.Context, id uint) (*models.ProfileRevision, error) {
+	var revision models.ProfileRevision
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&revision).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &revision, nil
+}
+
+// List returns the revisions matching the filter, newest first, together with the total number of matches.
+func (r *GormRevisionRepository) List(ctx context.Context, filter RevisionFilter) ([]models.ProfileRevision, int64, error) {
+	query := r.db.WithContext(ctx).Model(&models.ProfileRevision{}).Where("user_id = ?", filter.UserId)
+	if filter.Field != "" {
+		query = query.Where("field = ?", filter.Field)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var revisions []models.ProfileRevision
+	err := query.Order("created_at DESC, id DESC").Limit(filter.Limit).Offset(filter.Offset).Find(&revisions).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return revisions, total, nil
+}
+
+// GormPreferencesRepository is a PreferencesRepository backed by a gorm database connection.
+type GormPreferencesRepository struct {
+	db *gorm.DB
+}
+
+// NewGormPreferencesRepository returns a PreferencesRepository that uses the given gorm connection.
+func NewGormPreferencesRepository(db *gorm.DB) *GormPreferencesRepository {
+	return &GormPreferencesRepository{db: db}
+}
+
+// Get returns the preferences of the user, or ErrNotFound if they never saved any.
+func (r *GormPreferencesRepository) Get(ctx context.Context, userId uint) (*models.UserPreferences, error) {
+	var preferences models.UserPreferences
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userId).First(&preferences).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &preferences, nil
+}
+
+// Save creates or replaces the preferences of the user.
+func (r *GormPreferencesRepository) Save(ctx context.Context, preferences *models.UserPreferences) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"version", "document", "updated_at"}),
+	}).Create(preferences).Error
+}
+
+// GormSessionRepository is a SessionRepository backed by a gorm database connection.
+type GormSessionRepository struct {
+	db *gorm.DB
+}
+
+// NewGormSessionRepository returns a SessionRepository that uses the given gorm connection.
+func NewGormSessionRepository(db *gorm.DB) *GormSessionRepository {
+	return &GormSessionRepository{db: db}
+}
+
+// Create stores a new session.
+func (r *GormSessionRepository) Create(ctx context.Context, session *models.Session) error {
+	return r.db.WithContext(ctx).Create(session).Error
+}
+
+// Find returns the session with the given ID, or ErrNotFound.
+func (r *GormSessionRepository) Find(ctx context.Context, id string) (*models.Session, error) {
+	var session models.Session
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&session).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &session, nil
+}
+
+// List returns the sessions matching the filter, newest first, together with the total number of matches.
+func (r *GormSessionRepository) List(ctx context.Context, filter SessionFilter) ([]models.Session, int64, error) {
+	query := r.db.WithContext(ctx).Model(&models.Session{}).Where("user_id = ?", filter.UserId)
+	if !filter.ActiveAt.IsZero() {
+		query = query.Where("revoked_at IS NULL AND expires_at > ?", filter.ActiveAt)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var sessions []models.Session
+	err := query.Order("created_at DESC, id").Limit(filter.Limit).Offset(filter.Offset).Find(&sessions).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return sessions, total, nil
+}
+
+// Touch records that the session was used at the given time.
+func (r *GormSessionRepository) Touch(ctx context.Context, id string, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.Session{}).Where("id = ?", id).Update("last_seen_at", at).Error
+}
+
+// Revoke marks the session as revoked at the given time, unless it already is.
+func (r *GormSessionRepository) Revoke(ctx context.Context, id string, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", at).Error
+}
+
+// RevokeOthers revokes every active session of the user except the one to keep and returns how many it revoked.
+func (r *GormSessionRepository) RevokeOthers(ctx context.Context, userId uint, keep string, at time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&models.Session{}).
+		Where("user_id = ? AND id <> ? AND revoked_at IS NULL AND expires_at > ?", userId, keep, at).
+		Update("revoked_at", at)
+	return result.RowsAffected, result.Error
+}
+
+// CountActive returns the number of sessions of all users that are neither revoked nor expired at the given time.
+func (r *GormSessionRepository) CountActive(ctx context.Context, at time.Time) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Session{}).
+		Where("revoked_at IS NULL AND expires_at > ?", at).
+		Count(&count).Error
+	return count, err
+}
@@ -0,0 +1,619 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/models"
+)
+
+// MemoryUserRepository is a UserRepository that keeps users in memory.
+// It is safe for concurrent use and intended for tests and local development.
+type MemoryUserRepository struct {
+	mu     sync.RWMutex
+	users  map[uint]models.User
+	nextId uint
+}
+
+// NewMemoryUserRepository returns an empty in-memory UserRepository.
+func NewMemoryUserRepository() *MemoryUserRepository {
+	return &MemoryUserRepository{
+		users:  map[uint]models.User{},
+		nextId: 1,
+	}
+}
+
+// FindByEmail returns the user with the given email address, ignoring case, or ErrNotFound.
+func (r *MemoryUserRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, user := range r.users {
+		if strings.EqualFold(user.Email, email) {
+			return &user, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// FindByID returns the user with the given ID, or ErrNotFound.
+func (r *MemoryUserRepository) FindByID(ctx context.Context, id uint) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &user, nil
+}
+
+// FindByHandle returns the user with the given handle, or ErrNotFound.
+func (r *MemoryUserRepository) FindByHandle(ctx context.Context, handle string) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, user := range r.users {
+		if user.Handle != nil && *user.Handle == handle {
+			return &user, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// Create stores a new user and assigns its ID.
+func (r *MemoryUserRepository) Create(ctx context.Context, user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.emailTaken(user.Email, 0) {
+		return ErrDuplicateEmail
+	}
+	if r.handleTaken(user.Handle, 0) {
+		return ErrDuplicateHandle
+	}
+
+	user.Id = r.nextId
+	user.Version = 1
+	if user.Role == "" {
+		user.Role = models.RoleUser
+	}
+	user.CreatedAt = time.Now()
+	user.UpdatedAt = user.CreatedAt
+	r.nextId++
+	r.users[user.Id] = *user
+	return nil
+}
+
+// Update saves all fields of an existing user except its role and increments its version, or returns ErrConflict
+// if the stored version no longer matches user.Version.
+func (r *MemoryUserRepository) Update(ctx context.Context, user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.users[user.Id]
+	if !ok {
+		return ErrNotFound
+	}
+	if stored.Version != user.Version {
+		return ErrConflict
+	}
+	if r.emailTaken(user.Email, user.Id) {
+		return ErrDuplicateEmail
+	}
+	if r.handleTaken(user.Handle, user.Id) {
+		return ErrDuplicateHandle
+	}
+
+	user.Role = stored.Role
+	user.Version++
+	user.UpdatedAt = time.Now()
+	r.users[user.Id] = *user
+	return nil
+}
+
+// SetRole changes the role of the user with the given ID and increments its version, or returns ErrNotFound.
+func (r *MemoryUserRepository) SetRole(ctx context.Context, id uint, role string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return ErrNotFound
+	}
+	user.Role = role
+	user.Version++
+	user.UpdatedAt = time.Now()
+	r.users[id] = user
+	return nil
+}
+
+// FindByRole returns the users with the given role, ordered by ID.
+func (r *MemoryUserRepository) FindByRole(ctx context.Context, role string) ([]models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var users []models.User
+	for _, user := range r.users {
+		if user.Role == role {
+			users = append(users, user)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].Id < users[j].Id })
+	return users, nil
+}
+
+// RecordLogin stores the time of a successful login without changing the user's version.
+func (r *MemoryUserRepository) RecordLogin(ctx context.Context, id uint, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return ErrNotFound
+	}
+	user.LastLoginAt = &at
+	r.users[id] = user
+	return nil
+}
+
+// Delete removes the user with the given ID, or returns ErrNotFound.
+func (r *MemoryUserRepository) Delete(ctx context.Context, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[id]; !ok {
+		return ErrNotFound
+	}
+	delete(r.users, id)
+	return nil
+}
+
+// emailTaken reports whether a user other than exceptId already uses the email address, ignoring case.
+// The caller must hold the lock.
+func (r *MemoryUserRepository) emailTaken(email string, exceptId uint) bool {
+	for id, user := range r.users {
+		if id != exceptId && strings.EqualFold(user.Email, email) {
+			return true
+		}
+	}
+	return false
+}
+
+// handleTaken reports whether a user other than exceptId already uses the handle. The caller must hold the lock.
+func (r *MemoryUserRepository) handleTaken(handle *string, exceptId uint) bool {
+	if handle == nil {
+		return false
+	}
+	for id, user := range r.users {
+		if id != exceptId && user.Handle != nil && *user.Handle == *handle {
+			return true
+		}
+	}
+	return false
+}
+
+// MemoryAuditRepository is an AuditRepository that keeps entries in memory.
+// It is safe for concurrent use and intended for tests and local development.
+type MemoryAuditRepository struct {
+	mu      sync.RWMutex
+	entries []models.AuditLog
+}
+
+// NewMemoryAuditRepository returns an empty in-memory AuditRepository.
+func NewMemoryAuditRepository() *MemoryAuditRepository {
+	return &MemoryAuditRepository{}
+}
+
+// Append stores a new audit log entry and assigns its ID.
+func (r *MemoryAuditRepository) Append(ctx context.Context, entry *models.AuditLog) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry.Id = uint(len(r.entries) + 1)
+	r.entries = append(r.entries, *entry)
+	return nil
+}
+
+// List returns the entries matching the filter, newest first, together with the total number of matches.
+func (r *MemoryAuditRepository) List(ctx context.Context, filter AuditFilter) ([]models.AuditLog, int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	matches := []models.AuditLog{}
+	for _, entry := range r.entries {
+		if matchesAuditFilter(entry, filter) {
+			matches = append(matches, entry)
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if !matches[i].CreatedAt.Equal(matches[j].CreatedAt) {
+			return matches[i].CreatedAt.After(matches[j].CreatedAt)
+		}
+		return matches[i].Id > matches[j].Id
+	})
+
+	total := int64(len(matches))
+	start := min(filter.Offset, len(matches))
+	end := len(matches)
+	if filter.Limit > 0 {
+		end = min(start+filter.Limit, len(matches))
+	}
+
+	return matches[start:end], total, nil
+}
+
+// matchesAuditFilter reports whether the entry satisfies every condition set in the filter.
+func matchesAuditFilter(entry models.AuditLog, filter AuditFilter) bool {
+	equal := func(a, b *uint) bool {
+		return a != nil && b != nil && *a == *b
+	}
+
+	if filter.ActorId != nil && !equal(entry.ActorId, filter.ActorId) {
+		return false
+	}
+	if filter.TargetId != nil && !equal(entry.TargetId, filter.TargetId) {
+		return false
+	}
+	if filter.UserId != nil && !equal(entry.ActorId, filter.UserId) && !equal(entry.TargetId, filter.UserId) {
+		return false
+	}
+	if filter.Action != "" && !strings.EqualFold(entry.Action, filter.Action) {
+		return false
+	}
+	if !filter.Since.IsZero() && entry.CreatedAt.Before(filter.Since) {
+		return false
+	}
+	if !filter.Until.IsZero() && !entry.CreatedAt.Before(filter.Until) {
+		return false
+	}
+	return true
+}
+
+// MemoryAttributeRepository is an AttributeRepository that keeps definitions and values in memory.
+// It is safe for concurrent use and intended for tests and local development.
+type MemoryAttributeRepository struct {
+	mu     sync.RWMutex
+	defs   map[uint]models.AttributeDefinition
+	values map[uint]map[uint]models.UserAttribute // By user ID, then attribute ID
+	nextId uint
+}
+
+// NewMemoryAttributeRepository returns an empty in-memory AttributeRepository.
+func NewMemoryAttributeRepository() *MemoryAttributeRepository {
+	return &MemoryAttributeRepository{
+		defs:   map[uint]models.AttributeDefinition{},
+		values: map[uint]map[uint]models.UserAttribute{},
+		nextId: 1,
+	}
+}
+
+// ListDefinitions returns every attribute definition in the order they were created.
+func (r *MemoryAttributeRepository) ListDefinitions(ctx context.Context) ([]models.AttributeDefinition, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	defs := make([]models.AttributeDefinition, 0, len(r.defs))
+	for _, def := range r.defs {
+		defs = append(defs, def)
+	}
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Id < defs[j].Id })
+	return defs, nil
+}
+
+// FindDefinition returns the definition with the given key, or ErrNotFound.
+func (r *MemoryAttributeRepository) FindDefinition(ctx context.Context, key string) (*models.AttributeDefinition, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, def := range r.defs {
+		if def.Key == key {
+			return &def, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// CreateDefinition stores a new definition and assigns its ID, or returns ErrDuplicateKey.
+func (r *MemoryAttributeRepository) CreateDefinition(ctx context.Context, def *models.AttributeDefinition) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.defs {
+		if existing.Key == def.Key {
+			return ErrDuplicateKey
+		}
+	}
+
+	def.Id = r.nextId
+	def.CreatedAt = time.Now()
+	def.UpdatedAt = def.CreatedAt
+	r.nextId++
+	r.defs[def.Id] = *def
+	return nil
+}
+
+// UpdateDefinition saves all fields of an existing definition except its key, or returns ErrNotFound.
+func (r *MemoryAttributeRepository) UpdateDefinition(ctx context.Context, def *models.AttributeDefinition) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.defs[def.Id]
+	if !ok {
+		return ErrNotFound
+	}
+	def.Key = stored.Key
+	def.CreatedAt = stored.CreatedAt
+	def.UpdatedAt = time.Now()
+	r.defs[def.Id] = *def
+	return nil
+}
+
+// DeleteDefinition removes the definition with the given ID together with every value stored for it, or returns ErrNotFound.
+func (r *MemoryAttributeRepository) DeleteDefinition(ctx context.Context, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.defs[id]; !ok {
+		return ErrNotFound
+	}
+	delete(r.defs, id)
+	for _, values := range r.values {
+		delete(values, id)
+	}
+	return nil
+}
+
+// Values returns the attribute values of the user.
+func (r *MemoryAttributeRepository) Values(ctx context.Context, userId uint) ([]models.UserAttribute, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	values := make([]models.UserAttribute, 0, len(r.values[userId]))
+	for _, value := range r.values[userId] {
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// SetValues stores or replaces the given values of the user and removes the values of the attributes
+// with the removed IDs.
+func (r *MemoryAttributeRepository) SetValues(ctx context.Context, userId uint, values []models.UserAttribute, removed []uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored := r.values[userId]
+	if stored == nil {
+		stored = map[uint]models.UserAttribute{}
+		r.values[userId] = stored
+	}
+	for _, id := range removed {
+		delete(stored, id)
+	}
+	for _, value := range values {
+		value.UserId = userId
+		value.UpdatedAt = time.Now()
+		stored[value.AttributeId] = value
+	}
+	return nil
+}
+
+// MemoryRevisionRepository is a RevisionRepository that keeps revisions in memory.
+// It is safe for concurrent use and intended for tests and local development.
+type MemoryRevisionRepository struct {
+	mu        sync.RWMutex
+	revisions []models.ProfileRevision
+}
+
+// NewMemoryRevisionRepository returns an empty in-memory RevisionRepository.
+func NewMemoryRevisionRepository() *MemoryRevisionRepository {
+	return &MemoryRevisionRepository{}
+}
+
+// Append stores new revisions and assigns their IDs.
+func (r *MemoryRevisionRepository) Append(ctx context.Context, revisions []models.ProfileRevision) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range revisions {
+		revisions[i].Id = uint(len(r.revisions) + 1)
+		if revisions[i].CreatedAt.IsZero() {
+			revisions[i].CreatedAt = time.Now()
+		}
+		r.revisions = append(r.revisions, revisions[i])
+	}
+	return nil
+}
+
+// Find returns the revision with the given ID, or ErrNotFound.
+func (r *MemoryRevisionRepository) Find(ctx context.Context, id uint) (*models.ProfileRevision, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if id == 0 || id > uint(len(r.revisions)) {
+		return nil, ErrNotFound
+	}
+	revision := r.revisions[id-1]
+	return &revision, nil
+}
+
+// List returns the revisions matching the filter, newest first, together with the total number of matches.
+func (r *MemoryRevisionRepository) List(ctx context.Context, filter RevisionFilter) ([]models.ProfileRevision, int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	matches := []models.ProfileRevision{}
+	for i := len(r.revisions) - 1; i >= 0; i-- {
+		revision := r.revisions[i]
+		if revision.UserId == filter.UserId && (filter.Field == "" || revision.Field == filter.Field) {
+			matches = append(matches, revision)
+		}
+	}
+
+	total := int64(len(matches))
+	start := min(filter.Offset, len(matches))
+	end := len(matches)
+	if filter.Limit > 0 {
+		end = min(start+filter.Limit, len(matches))
+	}
+
+	return matches[start:end], total, nil
+}
+
+// MemoryPreferencesRepository is a PreferencesRepository that keeps preferences in memory.
+// It is safe for concurrent use and intended for tests and local development.
+type MemoryPreferencesRepository struct {
+	mu          sync.RWMutex
+	preferences map[uint]models.UserPreferences
+}
+
+// NewMemoryPreferencesRepository returns an empty in-memory PreferencesRepository.
+func NewMemoryPreferencesRepository() *MemoryPreferencesRepository {
+	return &MemoryPreferencesRepository{preferences: map[uint]models.UserPreferences{}}
+}
+
+// Get returns the preferences of the user, or ErrNotFound if they never saved any.
+func (r *MemoryPreferencesRepository) Get(ctx context.Context, userId uint) (*models.UserPreferences, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	preferences, ok := r.preferences[userId]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &preferences, nil
+}
+
+// Save creates or replaces the preferences of the user.
+func (r *MemoryPreferencesRepository) Save(ctx context.Context, preferences *models.UserPreferences) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	preferences.UpdatedAt = time.Now()
+	r.preferences[preferences.UserId] = *preferences
+	return nil
+}
+
+// MemorySessionRepository is a SessionRepository that keeps sessions in memory.
+// It is safe for concurrent use and intended for tests and local development.
+type MemorySessionRepository struct {
+	mu       sync.RWMutex
+	sessions map[string]models.Session
+}
+
+// NewMemorySessionRepository returns an empty in-memory SessionRepository.
+func NewMemorySessionRepository() *MemorySessionRepository {
+	return &MemorySessionRepository{sessions: map[string]models.Session{}}
+}
+
+// Create stores a new session.
+func (r *MemorySessionRepository) Create(ctx context.Context, session *models.Session) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if session.CreatedAt.IsZero() {
+		session.CreatedAt = time.Now()
+	}
+	r.sessions[session.Id] = *session
+	return nil
+}
+
+// Find returns the session with the given ID, or ErrNotFound.
+func (r *MemorySessionRepository) Find(ctx context.Context, id string) (*models.Session, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	session, ok := r.sessions[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &session, nil
+}
+
+// List returns the sessions matching the filter, newest first, together with the total number of matches.
+func (r *MemorySessionRepository) List(ctx context.Context, filter SessionFilter) ([]models.Session, int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	matches := []models.Session{}
+	for _, session := range r.sessions {
+		if session.UserId != filter.UserId || (!filter.ActiveAt.IsZero() && !session.Active(filter.ActiveAt)) {
+			continue
+		}
+		matches = append(matches, session)
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if !matches[i].CreatedAt.Equal(matches[j].CreatedAt) {
+			return matches[i].CreatedAt.After(matches[j].CreatedAt)
+		}
+		return matches[i].Id < matches[j].Id
+	})
+
+	total := int64(len(matches))
+	start := min(filter.Offset, len(matches))
+	end := len(matches)
+	if filter.Limit > 0 {
+		end = min(start+filter.Limit, len(matches))
+	}
+
+	return matches[start:end], total, nil
+}
+
+// Touch records that the session was used at the given time.
+func (r *MemorySessionRepository) Touch(ctx context.Context, id string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if session, ok := r.sessions[id]; ok {
+		session.LastSeenAt = at
+		r.sessions[id] = session
+	}
+	return nil
+}
+
+// Revoke marks the session as revoked at the given time, unless it already is.
+func (r *MemorySessionRepository) Revoke(ctx context.Context, id string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if session, ok := r.sessions[id]; ok && session.RevokedAt == nil {
+		session.RevokedAt = &at
+		r.sessions[id] = session
+	}
+	return nil
+}
+
+// RevokeOthers revokes every active session of the user except the one to keep and returns how many it revoked.
+func (r *MemorySessionRepository) RevokeOthers(ctx context.Context, userId uint, keep string, at time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var revoked int64
+	for id, session := range r.sessions {
+		if session.UserId != userId || id == keep || !session.Active(at) {
+			continue
+		}
+		session.RevokedAt = &at
+		r.sessions[id] = session
+		revoked++
+	}
+	return revoked, nil
+}
+
+// CountActive returns the number of sessions of all users that are neither revoked nor expired at the given time.
+func (r *MemorySessionRepository) CountActive(ctx context.Context, at time.Time) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var count int64
+	for _, session := range r.sessions {
+		if session.Active(at) {
+			count++
+		}
+	}
+	return count, nil
+}
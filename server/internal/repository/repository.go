@@ -0,0 +1,142 @@
+// Package repository defines the persistence interfaces used by the HTTP handlers,
+// together with a gorm implementation for production and an in-memory implementation for tests and local development.
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/models"
+)
+
+// ErrNotFound is returned when the requested record does not exist.
+var ErrNotFound = errors.New("record not found")
+
+// ErrDuplicateEmail is returned when creating or updating a user would duplicate an existing email address.
+var ErrDuplicateEmail = errors.New("email is already in use")
+
+// ErrDuplicateHandle is returned when creating or updating a user would duplicate an existing handle.
+var ErrDuplicateHandle = errors.New("handle is already in use")
+
+// ErrDuplicateKey is returned when creating an attribute definition whose key is already defined.
+var ErrDuplicateKey = errors.New("attribute key is already defined")
+
+// ErrConflict is returned when updating a record that was changed since it was read.
+var ErrConflict = errors.New("record was modified concurrently")
+
+// UserRepository stores and retrieves users.
+type UserRepository interface {
+	// FindByEmail returns the user with the given email address, or ErrNotFound.
+	FindByEmail(ctx context.Context, email string) (*models.User, error)
+	// FindByID returns the user with the given ID, or ErrNotFound.
+	FindByID(ctx context.Context, id uint) (*models.User, error)
+	// FindByHandle returns the user with the given handle, or ErrNotFound.
+	FindByHandle(ctx context.Context, handle string) (*models.User, error)
+	// Create stores a new user and assigns its ID and first version.
+	Create(ctx context.Context, user *models.User) error
+	// Update saves all fields of an existing user except its role and increments its version. It returns ErrConflict
+	// if the stored version no longer matches user.Version, or ErrNotFound if the user was deleted.
+	Update(ctx context.Context, user *models.User) error
+	// SetRole changes the role of the user with the given ID and increments its version, or returns ErrNotFound.
+	SetRole(ctx context.Context, id uint, role string) error
+	// FindByRole returns the users with the given role, ordered by ID.
+	FindByRole(ctx context.Context, role string) ([]models.User, error)
+	// RecordLogin stores the time of a successful login without changing the user's version.
+	RecordLogin(ctx context.Context, id uint, at time.Time) error
+	// Delete removes the user with the given ID, or returns ErrNotFound.
+	Delete(ctx context.Context, id uint) error
+}
+
+// AuditFilter narrows down the audit log entries returned by AuditRepository.List.
+type AuditFilter struct {
+	ActorId  *uint
+	TargetId *uint
+	// UserId matches entries where the user is either the actor or the target.
+	UserId *uint
+	Action string
+	Since  time.Time
+	Until  time.Time
+	Limit  int
+	Offset int
+}
+
+// AuditRepository appends to and queries the audit log. Entries can never be modified or removed.
+type AuditRepository interface {
+	// Append stores a new audit log entry and assigns its ID.
+	Append(ctx context.Context, entry *models.AuditLog) error
+	// List returns the entries matching the filter, newest first, together with the total number of matches.
+	List(ctx context.Context, filter AuditFilter) ([]models.AuditLog, int64, error)
+}
+
+// AttributeRepository stores custom profile attribute definitions and the values users have for them.
+type AttributeRepository interface {
+	// ListDefinitions returns every attribute definition in the order they were created.
+	ListDefinitions(ctx context.Context) ([]models.AttributeDefinition, error)
+	// FindDefinition returns the definition with the given key, or ErrNotFound.
+	FindDefinition(ctx context.Context, key string) (*models.AttributeDefinition, error)
+	// CreateDefinition stores a new definition and assigns its ID, or returns ErrDuplicateKey.
+	CreateDefinition(ctx context.Context, def *models.AttributeDefinition) error
+	// UpdateDefinition saves all fields of an existing definition, or returns ErrNotFound.
+	UpdateDefinition(ctx context.Context, def *models.AttributeDefinition) error
+	// DeleteDefinition removes the definition with the given ID together with every value stored for it,
+	// or returns ErrNotFound.
+	DeleteDefinition(ctx context.Context, id uint) error
+	// Values returns the attribute values of the user.
+	Values(ctx context.Context, userId uint) ([]models.UserAttribute, error)
+	// SetValues stores or replaces the given values of the user and removes the values of the attributes
+	// with the removed IDs, all at once.
+	SetValues(ctx context.Context, userId uint, values []models.UserAttribute, removed []uint) error
+}
+
+// RevisionFilter narrows down the profile revisions returned by RevisionRepository.List.
+type RevisionFilter struct {
+	UserId uint
+	Field  string
+	Limit  int
+	Offset int
+}
+
+// RevisionRepository stores the history of profile field changes.
+type RevisionRepository interface {
+	// Append stores new revisions and assigns their IDs.
+	Append(ctx context.Context, revisions []models.ProfileRevision) error
+	// Find returns the revision with the given ID, or ErrNotFound.
+	Find(ctx context.Context, id uint) (*models.ProfileRevision, error)
+	// List returns the revisions matching the filter, newest first, together with the total number of matches.
+	List(ctx context.Context, filter RevisionFilter) ([]models.ProfileRevision, int64, error)
+}
+
+// PreferencesRepository stores the preferences documents of users.
+type PreferencesRepository interface {
+	// Get returns the preferences of the user, or ErrNotFound if they never saved any.
+	Get(ctx context.Context, userId uint) (*models.UserPreferences, error)
+	// Save creates or replaces the preferences of the user.
+	Save(ctx context.Context, preferences *models.UserPreferences) error
+}
+
+// SessionFilter narrows down the sessions returned by SessionRepository.List.
+type SessionFilter struct {
+	UserId   uint
+	ActiveAt time.Time // If set, only sessions that are neither revoked nor expired at this time
+	Limit    int
+	Offset   int
+}
+
+// SessionRepository stores the sessions started by logging in.
+type SessionRepository interface {
+	// Create stores a new session.
+	Create(ctx context.Context, session *models.Session) error
+	// Find returns the session with the given ID, or ErrNotFound.
+	Find(ctx context.Context, id string) (*models.Session, error)
+	// List returns the sessions matching the filter, newest first, together with the total number of matches.
+	List(ctx context.Context, filter SessionFilter) ([]models.Session, int64, error)
+	// Touch records that the session was used at the given time.
+	Touch(ctx context.Context, id string, at time.Time) error
+	// Revoke marks the session as revoked at the given time, unless it already is.
+	Revoke(ctx context.Context, id string, at time.Time) error
+	// RevokeOthers revokes every active session of the user except the one to keep and returns how many it revoked.
+	RevokeOthers(ctx context.Context, userId uint, keep string, at time.Time) (int64, error)
+	// CountActive returns the number of sessions of all users that are neither revoked nor expired at the given time.
+	CountActive(ctx context.Context, at time.Time) (int64, error)
+}
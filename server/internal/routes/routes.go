@@ -5,20 +5,60 @@ import (
 	"github.com/gofiber/fiber/v3"
 )
 
-// Setup configures the Fiber app with the necessary routes for the application.
+// Setup configures the Fiber app with the necessary routes for the application, served by the given handler.
 // The routes include:
 // - POST /api/register: Handles user registration
 // - POST /api/login: Handles user login
 // - POST /api/logout: Handles user logout
 // - GET /api/user: Retrieves the currently authenticated user
 // - PUT /api/user: Updates the currently authenticated user
+// - PATCH /api/user: Applies a JSON merge patch to the currently authenticated user
 // - DELETE /api/user: Deletes the currently authenticated user
-func Setup(app *fiber.App) {
-	app.Post("/api/register", controllers.Register)
-	app.Post("/api/login", controllers.Login)
-	app.Post("/api/logout", controllers.Logout)
+// - POST /api/user/avatar: Uploads a new avatar for the currently authenticated user
+// - DELETE /api/user/avatar: Removes the avatar of the currently authenticated user
+// - GET /api/user/activity: Lists the account activity of the currently authenticated user
+// - GET /api/user/sessions: Lists the active sessions of the currently authenticated user
+// - DELETE /api/user/sessions: Revokes every session of the currently authenticated user except the current one
+// - DELETE /api/user/sessions/:id: Revokes a session of the currently authenticated user
+// - GET /api/user/preferences: Retrieves the preferences of the currently authenticated user
+// - PUT /api/user/preferences: Replaces the preferences of the currently authenticated user
+// - GET /api/user/revisions: Lists the profile change history of the currently authenticated user
+// - POST /api/user/revisions/:id/revert: Restores a profile field to its value before the revision
+// - GET /api/users/:handle: Retrieves the public profile of the user with the handle
+// - GET /api/attributes: Lists the custom profile attributes the current user may see
+// - GET /api/admin/audit: Lists audit log entries across all users (administrators only)
+// - POST /api/admin/attributes: Defines a custom profile attribute (administrators only)
+// - PUT /api/admin/attributes/:key: Changes a custom profile attribute (administrators only)
+// - DELETE /api/admin/attributes/:key: Removes a custom profile attribute and its values (administrators only)
+// - GET /api/admin/users/:id/attributes: Retrieves the custom attributes of a user (administrators only)
+// - PATCH /api/admin/users/:id/attributes: Updates the custom attributes of a user (administrators only)
+func Setup(app *fiber.App, h *controllers.Handler) {
+	app.Post("/api/register", h.Register)
+	app.Post("/api/login", h.Login)
+	app.Post("/api/logout", h.Logout)
 
-	app.Get("/api/user", controllers.GetUser)
-	app.Put("/api/user", controllers.UpdateUser)
-	app.Delete("/api/user", controllers.DeleteUser)
+	app.Get("/api/user", h.GetUser)
+	app.Put("/api/user", h.UpdateUser)
+	app.Patch("/api/user", h.PatchUser)
+	app.Delete("/api/user", h.DeleteUser)
+	app.Post("/api/user/avatar", h.UploadAvatar)
+	app.Delete("/api/user/avatar", h.DeleteAvatar)
+	app.Get("/api/user/activity", h.GetAccountActivity)
+	app.Get("/api/user/sessions", h.ListSessions)
+	app.Delete("/api/user/sessions", h.RevokeOtherSessions)
+	app.Delete("/api/user/sessions/:id", h.RevokeSession)
+	app.Get("/api/user/preferences", h.GetPreferences)
+	app.Put("/api/user/preferences", h.UpdatePreferences)
+	app.Get("/api/user/revisions", h.ListRevisions)
+	app.Post("/api/user/revisions/:id/revert", h.RevertRevision)
+
+	app.Get("/api/users/:handle", h.GetPublicProfile)
+	app.Get("/api/attributes", h.ListAttributes)
+
+	app.Get("/api/admin/audit", h.ListAuditLogs)
+	app.Post("/api/admin/attributes", h.CreateAttribute)
+	app.Put("/api/admin/attributes/:key", h.UpdateAttribute)
+	app.Delete("/api/admin/attributes/:key", h.DeleteAttribute)
+	app.Get("/api/admin/users/:id/attributes", h.GetUserAttributes)
+	app.Patch("/api/admin/users/:id/attributes", h.PatchUserAttributes)
 }
@@ -0,0 +1,177 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// keystoreVersion identifies the on-disk format written by SaveKeystore.
+const keystoreVersion = 1
+
+// scrypt parameters used to derive the encryption key from the passphrase.
+const (
+	scryptN      = 1 << 15
+	scryptR      = 8
+	scryptP      = 1
+	scryptKeyLen = 32
+)
+
+// keystoreFile is the on-disk format of an encrypted keystore.
+// The secrets are stored as a JSON object encrypted with AES-256-GCM under a key derived with scrypt.
+type keystoreFile struct {
+	Version    int    `json:"version"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// KeystoreProvider serves secrets from an encrypted local keystore file.
+// The file is decrypted again whenever it changes on disk, so rotated secrets are picked up.
+type KeystoreProvider struct {
+	path       string
+	passphrase string
+
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+	values  map[string]string
+}
+
+// OpenKeystore opens and decrypts the keystore at path.
+func OpenKeystore(path, passphrase string) (*KeystoreProvider, error) {
+	k := &KeystoreProvider{path: path, passphrase: passphrase}
+	if err := k.reload(); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// Lookup returns the value of the named secret, or ErrNotFound.
+// The keystore is re-read first if the file has changed since it was last decrypted.
+func (k *KeystoreProvider) Lookup(name string) (string, error) {
+	if err := k.reload(); err != nil {
+		return "", err
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if value, ok := k.values[name]; ok && value != "" {
+		return value, nil
+	}
+	return "", ErrNotFound
+}
+
+// reload decrypts the keystore if it changed on disk since the last load.
+func (k *KeystoreProvider) reload() error {
+	info, err := os.Stat(k.path)
+	if err != nil {
+		return fmt.Errorf("could not read keystore: %w", err)
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.values != nil && info.ModTime().Equal(k.modTime) && info.Size() == k.size {
+		return nil
+	}
+
+	values, err := LoadKeystore(k.path, k.passphrase)
+	if err != nil {
+		return err
+	}
+	k.values, k.modTime, k.size = values, info.ModTime(), info.Size()
+	return nil
+}
+
+// LoadKeystore decrypts the keystore at path and returns its secrets.
+func LoadKeystore(path, passphrase string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read keystore: %w", err)
+	}
+
+	var file keystoreFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("could not parse keystore: %w", err)
+	}
+	if file.Version != keystoreVersion {
+		return nil, fmt.Errorf("unsupported keystore version %d", file.Version)
+	}
+
+	gcm, err := keystoreCipher(passphrase, file.Salt)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := gcm.Open(nil, file.Nonce, file.Ciphertext, nil)
+	if err != nil {
+		return nil, errors.New("could not decrypt keystore: wrong passphrase or corrupted file")
+	}
+
+	values := map[string]string{}
+	if err := json.Unmarshal(plaintext, &values); err != nil {
+		return nil, fmt.Errorf("could not parse keystore contents: %w", err)
+	}
+	return values, nil
+}
+
+// SaveKeystore encrypts the secrets with the passphrase and writes them to path.
+// The file is written to a temporary file first and renamed so readers never see a partial keystore.
+func SaveKeystore(path, passphrase string, values map[string]string) error {
+	plaintext, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	gcm, err := keystoreCipher(passphrase, salt)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(keystoreFile{
+		Version:    keystoreVersion,
+		Salt:       salt,
+		Nonce:      nonce,
+		Ciphertext: gcm.Seal(nil, nonce, plaintext, nil),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// keystoreCipher derives the AES-256-GCM cipher for a passphrase and salt.
+func keystoreCipher(passphrase string, salt []byte) (cipher.AEAD, error) {
+	if passphrase == "" {
+		return nil, errors.New("keystore passphrase is empty")
+	}
+	key, err := scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, scryptKeyLen)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,156 @@
+package secrets
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestKeystoreRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.json")
+	values := map[string]string{"JWT_SECRET_KEY": "s3cret", "DB_PASSWORD": "pässwörd"}
+	if err := SaveKeystore(path, "passphrase", values); err != nil {
+		t.Fatalf("SaveKeystore: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "s3cret") {
+		t.Fatal("keystore file contains a secret in plain text")
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Fatalf("keystore file mode = %v, %v, want 0600", info.Mode().Perm(), err)
+	}
+
+	loaded, err := LoadKeystore(path, "passphrase")
+	if err != nil {
+		t.Fatalf("LoadKeystore: %v", err)
+	}
+	if len(loaded) != len(values) || loaded["JWT_SECRET_KEY"] != "s3cret" || loaded["DB_PASSWORD"] != "pässwörd" {
+		t.Fatalf("LoadKeystore = %v, want %v", loaded, values)
+	}
+}
+
+func TestKeystoreFreshSaltAndNonce(t *testing.T) {
+	dir := t.TempDir()
+	var files [2]keystoreFile
+	for i := range files {
+		path := filepath.Join(dir, "secrets.json")
+		if err := SaveKeystore(path, "passphrase", map[string]string{"A": "value"}); err != nil {
+			t.Fatalf("SaveKeystore: %v", err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(data, &files[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if files[0].Version != keystoreVersion || len(files[0].Salt) != 16 || len(files[0].Nonce) != 12 {
+		t.Fatalf("keystore header: version %d, %d-byte salt, %d-byte nonce", files[0].Version, len(files[0].Salt), len(files[0].Nonce))
+	}
+	if string(files[0].Salt) == string(files[1].Salt) || string(files[0].Nonce) == string(files[1].Nonce) {
+		t.Fatal("salt or nonce reused between saves")
+	}
+	if string(files[0].Ciphertext) == string(files[1].Ciphertext) {
+		t.Fatal("ciphertext identical between saves")
+	}
+}
+
+func TestLoadKeystoreErrors(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "valid.json")
+	if err := SaveKeystore(valid, "passphrase", map[string]string{"A": "value"}); err != nil {
+		t.Fatalf("SaveKeystore: %v", err)
+	}
+	data, err := os.ReadFile(valid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var file keystoreFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		t.Fatal(err)
+	}
+
+	// write stores a modified copy of the valid keystore and returns its path
+	write := func(name string, modify func(*keystoreFile)) string {
+		copied := file
+		copied.Ciphertext = append([]byte(nil), file.Ciphertext...)
+		modify(&copied)
+		encoded, err := json.Marshal(copied)
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, encoded, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	notJSON := filepath.Join(dir, "not-json")
+	if err := os.WriteFile(notJSON, []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		passphrase string
+		want       string
+	}{
+		{"wrong passphrase", valid, "wrong", "wrong passphrase or corrupted file"},
+		{"empty passphrase", valid, "", "passphrase is empty"},
+		{"tampered ciphertext", write("tampered.json", func(f *keystoreFile) { f.Ciphertext[0] ^= 1 }), "passphrase", "wrong passphrase or corrupted file"},
+		{"other salt", write("salt.json", func(f *keystoreFile) { f.Salt = make([]byte, 16) }), "passphrase", "wrong passphrase or corrupted file"},
+		{"unsupported version", write("version.json", func(f *keystoreFile) { f.Version = 2 }), "passphrase", "unsupported keystore version 2"},
+		{"not JSON", notJSON, "passphrase", "could not parse keystore"},
+		{"missing file", filepath.Join(dir, "missing.json"), "passphrase", "could not read keystore"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadKeystore(tt.path, tt.passphrase)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("LoadKeystore error = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeystoreProviderReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.json")
+	if err := SaveKeystore(path, "passphrase", map[string]string{"JWT_SECRET_KEY": "first", "EMPTY": ""}); err != nil {
+		t.Fatalf("SaveKeystore: %v", err)
+	}
+	provider, err := OpenKeystore(path, "passphrase")
+	if err != nil {
+		t.Fatalf("OpenKeystore: %v", err)
+	}
+
+	if value, err := provider.Lookup("JWT_SECRET_KEY"); value != "first" || err != nil {
+		t.Fatalf("Lookup = %q, %v", value, err)
+	}
+	for _, name := range []string{"EMPTY", "MISSING"} {
+		if _, err := provider.Lookup(name); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("Lookup(%s) error = %v, want ErrNotFound", name, err)
+		}
+	}
+
+	// A rewritten keystore is decrypted again on the next lookup
+	if err := SaveKeystore(path, "passphrase", map[string]string{"JWT_SECRET_KEY": "second, longer"}); err != nil {
+		t.Fatalf("SaveKeystore: %v", err)
+	}
+	if value, err := provider.Lookup("JWT_SECRET_KEY"); value != "second, longer" || err != nil {
+		t.Fatalf("Lookup after the keystore changed = %q, %v", value, err)
+	}
+
+	if _, err := OpenKeystore(path, "wrong"); err == nil {
+		t.Fatal("OpenKeystore with a wrong passphrase succeeded")
+	}
+}
@@ -0,0 +1,116 @@
+// Package secrets resolves secret values such as JWT_SECRET_KEY and DB_PASSWORD from pluggable providers
+// (files written by Docker or Kubernetes, or an encrypted local keystore) and reloads them when they are rotated.
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/config"
+)
+
+// ErrNotFound is returned when a provider has no value for the requested secret.
+var ErrNotFound = errors.New("secret not found")
+
+// SecretProvider looks up secret values by name, e.g. "JWT_SECRET_KEY".
+// Implementations must return the current value on every call so rotated secrets are picked up.
+type SecretProvider interface {
+	// Lookup returns the current value of the named secret, or ErrNotFound.
+	Lookup(name string) (string, error)
+}
+
+// StaticProvider serves fixed values, such as those read from the configuration at startup.
+// Empty values are treated as missing.
+type StaticProvider map[string]string
+
+// Lookup returns the stored value of the named secret, or ErrNotFound.
+func (p StaticProvider) Lookup(name string) (string, error) {
+	if value := p[name]; value != "" {
+		return value, nil
+	}
+	return "", ErrNotFound
+}
+
+// EnvFileProvider reads a secret from the file named by the <NAME>_FILE environment variable,
+// re-reading it on every lookup so files updated in place by Docker or Kubernetes are picked up.
+type EnvFileProvider struct{}
+
+// Lookup returns the contents of the file named by <name>_FILE, or ErrNotFound if the variable is not set.
+func (EnvFileProvider) Lookup(name string) (string, error) {
+	path := os.Getenv(name + "_FILE")
+	if path == "" {
+		return "", ErrNotFound
+	}
+	return readSecretFile(path)
+}
+
+// FileProvider reads each secret from a file of the same name in a directory, such as /run/secrets.
+// A lowercase file name is also accepted, matching the usual Docker secret naming.
+type FileProvider struct {
+	Dir string
+}
+
+// Lookup returns the contents of <Dir>/<name> or <Dir>/<lowercase name>, or ErrNotFound if neither exists.
+func (p FileProvider) Lookup(name string) (string, error) {
+	for _, candidate := range []string{name, strings.ToLower(name)} {
+		value, err := readSecretFile(filepath.Join(p.Dir, candidate))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		return value, err
+	}
+	return "", ErrNotFound
+}
+
+// Chain tries each provider in order and returns the first value found.
+type Chain []SecretProvider
+
+// Lookup returns the value from the first provider that has the named secret, or ErrNotFound.
+func (c Chain) Lookup(name string) (string, error) {
+	for _, provider := range c {
+		value, err := provider.Lookup(name)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		return value, err
+	}
+	return "", ErrNotFound
+}
+
+// NewProvider builds the provider chain for the configuration: <NAME>_FILE files come first,
+// then the configured provider (file or keystore), then the values loaded into the configuration itself.
+func NewProvider(cfg *config.Config) (SecretProvider, error) {
+	chain := Chain{EnvFileProvider{}}
+
+	switch cfg.Secrets.Provider {
+	case "":
+		// Only files and the configuration
+	case "file":
+		chain = append(chain, FileProvider{Dir: cfg.Secrets.Dir})
+	case "keystore":
+		keystore, err := OpenKeystore(cfg.Secrets.KeystorePath, cfg.Secrets.KeystorePassphrase)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, keystore)
+	default:
+		return nil, fmt.Errorf("unsupported secrets provider %q (expected file or keystore)", cfg.Secrets.Provider)
+	}
+
+	return append(chain, StaticProvider{
+		"JWT_SECRET_KEY": cfg.Auth.JWTSecretKey,
+		"DB_PASSWORD":    cfg.Database.Password,
+	}), nil
+}
+
+// readSecretFile reads a secret file, dropping the trailing newline most editors and tools add.
+func readSecretFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
@@ -0,0 +1,117 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// DefaultPreviousTTL is how long the value in use before a rotation is kept by default: the lifetime of the
+// tokens signed with it.
+const DefaultPreviousTTL = 24 * time.Hour
+
+// Secret holds the current value of a named secret and reloads it from its provider when asked.
+// The value in use before the most recent rotation is kept for a limited time, so tokens issued with it
+// before the rotation can still be verified until they expire. New tokens are always signed with the current value.
+type Secret struct {
+	name        string
+	provider    SecretProvider
+	previousTTL time.Duration
+
+	mu        sync.RWMutex
+	current   string
+	previous  string
+	rotatedAt time.Time
+}
+
+// Load looks up the named secret and returns it as a reloadable Secret.
+// It returns ErrNotFound if no provider has a value for it.
+func Load(provider SecretProvider, name string) (*Secret, error) {
+	value, err := provider.Lookup(name)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return &Secret{name: name, provider: provider, previousTTL: DefaultPreviousTTL, current: value}, nil
+}
+
+// SetPreviousTTL sets how long after a rotation the previous value is still returned by Previous.
+// It should be at least the lifetime of anything signed with the secret; 0 drops the previous value right away.
+func (s *Secret) SetPreviousTTL(ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.previousTTL = ttl
+}
+
+// Name returns the name the secret is looked up by.
+func (s *Secret) Name() string {
+	return s.name
+}
+
+// Current returns the value currently in use.
+func (s *Secret) Current() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.current
+}
+
+// Previous returns the value in use before the most recent rotation, only for verifying what was signed
+// before it. It returns "" if the secret has not been rotated or the rotation is older than the previous TTL.
+func (s *Secret) Previous() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if time.Since(s.rotatedAt) >= s.previousTTL {
+		return ""
+	}
+	return s.previous
+}
+
+// Reload looks the secret up again and reports whether its value changed.
+// On error the current value is kept.
+func (s *Secret) Reload() (bool, error) {
+	value, err := s.provider.Lookup(s.name)
+	if err != nil {
+		return false, err
+	}
+
+	s.mu.Lock()
+	if value == s.current {
+		s.mu.Unlock()
+		return false, nil
+	}
+	s.previous, s.current = s.current, value
+	s.rotatedAt = time.Now()
+	s.mu.Unlock()
+	return true, nil
+}
+
+// Watch reloads the secrets every interval until the context is cancelled, logging rotations and failures.
+func Watch(ctx context.Context, interval time.Duration, secrets ...*Secret) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			for _, secret := range secrets {
+				changed, err := secret.Reload()
+				if err != nil {
+					slog.Error("Could not reload secret", "name", secret.Name(), "error", err)
+				} else if changed {
+					slog.Info("Secret was rotated", "name", secret.Name())
+				}
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
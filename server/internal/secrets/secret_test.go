@@ -0,0 +1,71 @@
+package secrets
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSecretRotation(t *testing.T) {
+	provider := StaticProvider{"JWT_SECRET_KEY": "first"}
+	secret, err := Load(provider, "JWT_SECRET_KEY")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if secret.Current() != "first" || secret.Previous() != "" {
+		t.Fatalf("after Load: current %q, previous %q", secret.Current(), secret.Previous())
+	}
+
+	if changed, err := secret.Reload(); changed || err != nil {
+		t.Fatalf("Reload without a rotation = %v, %v", changed, err)
+	}
+
+	provider["JWT_SECRET_KEY"] = "second"
+	if changed, err := secret.Reload(); !changed || err != nil {
+		t.Fatalf("Reload after a rotation = %v, %v", changed, err)
+	}
+	if secret.Current() != "second" || secret.Previous() != "first" {
+		t.Fatalf("after rotation: current %q, previous %q", secret.Current(), secret.Previous())
+	}
+
+	delete(provider, "JWT_SECRET_KEY")
+	if _, err := secret.Reload(); err == nil {
+		t.Fatal("Reload of a removed secret succeeded")
+	}
+	if secret.Current() != "second" {
+		t.Fatalf("failed reload changed the current value to %q", secret.Current())
+	}
+}
+
+func TestSecretPreviousTTL(t *testing.T) {
+	tests := []struct {
+		name      string
+		ttl       time.Duration
+		rotatedAt time.Duration // Time since the rotation
+		want      string
+	}{
+		{"within the default TTL", DefaultPreviousTTL, 23 * time.Hour, "first"},
+		{"after the default TTL", DefaultPreviousTTL, 24 * time.Hour, ""},
+		{"within a custom TTL", time.Hour, 59 * time.Minute, "first"},
+		{"after a custom TTL", time.Hour, 61 * time.Minute, ""},
+		{"zero TTL", 0, 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := StaticProvider{"JWT_SECRET_KEY": "first"}
+			secret, err := Load(provider, "JWT_SECRET_KEY")
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			secret.SetPreviousTTL(tt.ttl)
+			provider["JWT_SECRET_KEY"] = "second"
+			if _, err := secret.Reload(); err != nil {
+				t.Fatalf("Reload: %v", err)
+			}
+			secret.rotatedAt = time.Now().Add(-tt.rotatedAt)
+
+			if got := secret.Previous(); got != tt.want {
+				t.Fatalf("Previous() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
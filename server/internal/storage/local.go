@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// LocalStore is a BlobStore that writes blobs to a directory on the local filesystem.
+// The server serves the directory itself under the public URL prefix.
+type LocalStore struct {
+	dir       string
+	publicURL string
+}
+
+// NewLocalStore returns a LocalStore writing to dir, which is created if needed.
+func NewLocalStore(dir, publicURL string) (*LocalStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("storage: could not create %s: %w", dir, err)
+	}
+	return &LocalStore{dir: dir, publicURL: publicURL}, nil
+}
+
+// Dir returns the directory the blobs are stored in.
+func (s *LocalStore) Dir() string {
+	return s.dir
+}
+
+// Put writes the blob to a temporary file and renames it into place, so readers never see a partial file.
+func (s *LocalStore) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	target := filepath.Join(s.dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), target)
+}
+
+// Delete removes the blob's file.
+func (s *LocalStore) Delete(ctx context.Context, key string) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	target := filepath.Join(s.dir, filepath.FromSlash(key))
+	if err := os.Remove(target); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	// Remove directories left empty, which fails harmlessly for directories that still hold blobs
+	for parent := filepath.Dir(target); parent != filepath.Clean(s.dir); parent = filepath.Dir(parent) {
+		if os.Remove(parent) != nil {
+			break
+		}
+	}
+	return nil
+}
+
+// URL returns the path the server serves the blob on.
+func (s *LocalStore) URL(key string) string {
+	return joinURL(s.publicURL, key)
+}
@@ -0,0 +1,186 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/config"
+)
+
+// S3Store is a BlobStore backed by an S3-compatible object store such as AWS S3 or MinIO.
+// Requests are signed with AWS Signature Version 4, so no SDK is needed.
+type S3Store struct {
+	cfg       config.S3Config
+	endpoint  *url.URL
+	publicURL string
+	client    *http.Client
+}
+
+// NewS3Store returns an S3Store for the configured bucket. Blob URLs start with publicURL if it is an absolute URL
+// (such as a CDN in front of the bucket), and point at the bucket itself otherwise.
+func NewS3Store(cfg config.S3Config, publicURL string) (*S3Store, error) {
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil || endpoint.Host == "" || (endpoint.Scheme != "http" && endpoint.Scheme != "https") {
+		return nil, fmt.Errorf("storage: invalid S3 endpoint %q", cfg.Endpoint)
+	}
+
+	store := &S3Store{
+		cfg:      cfg,
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: 30 * time.Second},
+	}
+	if strings.HasPrefix(publicURL, "http://") || strings.HasPrefix(publicURL, "https://") {
+		store.publicURL = publicURL
+	} else {
+		store.publicURL = store.objectURL("").String()
+	}
+	return store, nil
+}
+
+// Put uploads the blob. Keys are never reused, so the object may be cached indefinitely.
+func (s *S3Store) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	header := http.Header{}
+	header.Set("Content-Type", contentType)
+	header.Set("Cache-Control", "public, max-age=31536000, immutable")
+	return s.do(ctx, http.MethodPut, key, data, header, http.StatusOK)
+}
+
+// Delete removes the object. S3 reports success for missing objects as well.
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	return s.do(ctx, http.MethodDelete, key, nil, http.Header{}, http.StatusNoContent, http.StatusOK, http.StatusNotFound)
+}
+
+// URL returns the public URL of the object.
+func (s *S3Store) URL(key string) string {
+	return joinURL(s.publicURL, escapePath(key))
+}
+
+// objectURL returns the request URL of the object, addressing the bucket by path or host name.
+func (s *S3Store) objectURL(key string) *url.URL {
+	u := *s.endpoint
+	if s.cfg.PathStyle {
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + s.cfg.Bucket + "/" + key
+	} else {
+		u.Host = s.cfg.Bucket + "." + u.Host
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + key
+	}
+	u.RawPath = escapePath(u.Path)
+	return &u
+}
+
+// do sends a signed request and checks the response status.
+func (s *S3Store) do(ctx context.Context, method, key string, body []byte, header http.Header, expected ...int) error {
+	target := s.objectURL(key)
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.ContentLength = int64(len(body))
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	s.sign(req, body, time.Now().UTC())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("storage: %s %s: %w", method, key, err)
+	}
+	defer resp.Body.Close()
+
+	for _, status := range expected {
+		if resp.StatusCode == status {
+			io.Copy(io.Discard, resp.Body)
+			return nil
+		}
+	}
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("storage: %s %s: unexpected status %s: %s", method, key, resp.Status, bytes.TrimSpace(message))
+}
+
+// sign adds the AWS Signature Version 4 Authorization header to the request.
+// See https://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-header-based-auth.html.
+func (s *S3Store) sign(req *http.Request, body []byte, now time.Time) {
+	const algorithm = "AWS4-HMAC-SHA256"
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("Host", req.URL.Host)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	// Sign every header that is sent, in lower case and sorted
+	names := make([]string, 0, len(req.Header))
+	for name := range req.Header {
+		names = append(names, strings.ToLower(name))
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(req.Header.Get(name)) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+	req.Header.Del("Host") // net/http sends req.Host instead
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.Query().Encode(),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s.cfg.Region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{algorithm, amzDate, scope, sha256Hex([]byte(canonicalRequest))}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.cfg.SecretAccessKey), date)
+	key = hmacSHA256(key, s.cfg.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		algorithm, s.cfg.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// escapePath percent-encodes every byte of the path except unreserved characters and slashes, as SigV4 requires.
+func escapePath(p string) string {
+	var b strings.Builder
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || strings.IndexByte("-_.~/", c) >= 0 {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
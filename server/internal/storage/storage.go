@@ -0,0 +1,51 @@
+// Package storage stores uploaded files such as avatars behind the BlobStore interface,
+// with a local filesystem implementation and one for S3-compatible object storage.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/config"
+)
+
+// ErrInvalidKey is returned for keys that are empty, absolute or contain ".." segments.
+var ErrInvalidKey = errors.New("storage: invalid key")
+
+// BlobStore stores immutable blobs under slash-separated keys such as "avatars/1/ab12/64.jpg".
+type BlobStore interface {
+	// Put stores data under the key, replacing any existing blob.
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	// Delete removes the blob stored under the key. Deleting a missing blob is not an error.
+	Delete(ctx context.Context, key string) error
+	// URL returns the URL the blob is served from.
+	URL(key string) string
+}
+
+// New creates the BlobStore selected by the configuration.
+func New(cfg config.StorageConfig) (BlobStore, error) {
+	switch cfg.Backend {
+	case "local":
+		return NewLocalStore(cfg.Dir, cfg.PublicURL)
+	case "s3":
+		return NewS3Store(cfg.S3, cfg.PublicURL)
+	default:
+		return nil, fmt.Errorf("storage: unknown backend %q", cfg.Backend)
+	}
+}
+
+// checkKey rejects keys that could escape the storage root.
+func checkKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || path.Clean(key) != key || strings.HasPrefix(key, "../") || key == ".." {
+		return fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+	return nil
+}
+
+// joinURL appends the key to a URL prefix.
+func joinURL(prefix, key string) string {
+	return strings.TrimSuffix(prefix, "/") + "/" + key
+}
@@ -0,0 +1,81 @@
+package tracing
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// headerCarrier adapts the Fiber request headers to the OpenTelemetry propagation interface.
+type headerCarrier struct {
+	c fiber.Ctx
+}
+
+// Get returns the value of the request header.
+func (h headerCarrier) Get(key string) string {
+	return h.c.Get(key)
+}
+
+// Set sets a response header.
+func (h headerCarrier) Set(key, value string) {
+	h.c.Set(key, value)
+}
+
+// Keys returns the names of the request headers.
+func (h headerCarrier) Keys() []string {
+	headers := h.c.GetReqHeaders()
+	keys := make([]string, 0, len(headers))
+	for key := range headers {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// Middleware starts a server span for every request, continuing the trace from the W3C traceparent header
+// if present, and makes it available to the handlers through the request's user context.
+func Middleware() fiber.Handler {
+	return func(c fiber.Ctx) error {
+		ctx := otel.GetTextMapPropagator().Extract(c.UserContext(), headerCarrier{c})
+
+		// Fiber reuses the request buffers, so copy the strings kept on the span
+		method := strings.Clone(c.Method())
+		ctx, span := Tracer().Start(ctx, method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(method),
+				semconv.URLPath(strings.Clone(c.Path())),
+				semconv.ClientAddress(strings.Clone(c.IP())),
+				semconv.UserAgentOriginal(strings.Clone(c.Get(fiber.HeaderUserAgent))),
+			),
+		)
+		defer span.End()
+
+		c.SetUserContext(ctx)
+		err := c.Next()
+
+		// Name the span after the matched route pattern to keep span names low-cardinality
+		route := strings.Clone(c.Route().Path)
+		span.SetName(method + " " + route)
+		span.SetAttributes(semconv.HTTPRoute(route))
+
+		status := c.Response().StatusCode()
+		if err != nil {
+			span.RecordError(err)
+			if fiberErr, ok := err.(*fiber.Error); ok {
+				status = fiberErr.Code
+			} else {
+				status = fiber.StatusInternalServerError
+			}
+		}
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= fiber.StatusInternalServerError {
+			span.SetStatus(codes.Error, "")
+		}
+
+		return err
+	}
+}
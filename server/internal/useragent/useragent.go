@@ -0,0 +1,114 @@
+// Package useragent derives an approximate browser, operating system and device from a User-Agent header,
+// so users can recognise their sessions. It only knows the common browsers and platforms; anything else is
+// reported as unknown.
+package useragent
+
+import "strings"
+
+// Device types.
+const (
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceBot     = "bot"
+	DeviceUnknown = "unknown"
+)
+
+// Agent describes the client that sent a request.
+type Agent struct {
+	Browser    string // e.g. "Firefox", or "" if unknown
+	OS         string // e.g. "Windows", or "" if unknown
+	DeviceType string // One of the device types
+}
+
+// browsers maps User-Agent tokens to browser names. Order matters, as most browsers also claim to be the
+// browsers they are derived from.
+var browsers = []struct{ token, name string }{
+	{"Edg/", "Edge"},
+	{"EdgA/", "Edge"},
+	{"EdgiOS/", "Edge"},
+	{"OPR/", "Opera"},
+	{"SamsungBrowser/", "Samsung Internet"},
+	{"YaBrowser/", "Yandex Browser"},
+	{"Vivaldi/", "Vivaldi"},
+	{"Firefox/", "Firefox"},
+	{"FxiOS/", "Firefox"},
+	{"CriOS/", "Chrome"},
+	{"Chrome/", "Chrome"},
+	{"Safari/", "Safari"},
+	{"curl/", "curl"},
+	{"PostmanRuntime/", "Postman"},
+	{"okhttp/", "OkHttp"},
+	{"Go-http-client/", "Go HTTP client"},
+	{"python-requests/", "Python Requests"},
+}
+
+// systems maps User-Agent tokens to operating system names, again in order of precedence.
+var systems = []struct{ token, name string }{
+	{"iPhone", "iOS"},
+	{"iPad", "iPadOS"},
+	{"Android", "Android"},
+	{"CrOS", "ChromeOS"},
+	{"Windows", "Windows"},
+	{"Mac OS X", "macOS"},
+	{"Macintosh", "macOS"},
+	{"Linux", "Linux"},
+}
+
+// botTokens identify crawlers and other automated clients.
+var botTokens = []string{"bot", "crawler", "spider", "slurp"}
+
+// Parse derives the browser, operating system and device type from a User-Agent header.
+func Parse(header string) Agent {
+	agent := Agent{DeviceType: DeviceUnknown}
+	for _, browser := range browsers {
+		if strings.Contains(header, browser.token) {
+			agent.Browser = browser.name
+			break
+		}
+	}
+	for _, system := range systems {
+		if strings.Contains(header, system.token) {
+			agent.OS = system.name
+			break
+		}
+	}
+
+	lower := strings.ToLower(header)
+	switch {
+	case containsAny(lower, botTokens):
+		agent.DeviceType = DeviceBot
+	case strings.Contains(header, "iPad") || strings.Contains(header, "Tablet") ||
+		(agent.OS == "Android" && !strings.Contains(header, "Mobile")):
+		agent.DeviceType = DeviceTablet
+	case strings.Contains(header, "Mobile") || strings.Contains(header, "iPhone"):
+		agent.DeviceType = DeviceMobile
+	case agent.OS != "":
+		agent.DeviceType = DeviceDesktop
+	}
+	return agent
+}
+
+// DeviceName returns a short description of the device for lists of sessions, e.g. "Firefox on Windows".
+func (a Agent) DeviceName() string {
+	switch {
+	case a.Browser != "" && a.OS != "":
+		return a.Browser + " on " + a.OS
+	case a.Browser != "":
+		return a.Browser
+	case a.OS != "":
+		return "Unknown browser on " + a.OS
+	default:
+		return "Unknown device"
+	}
+}
+
+// containsAny reports whether s contains any of the substrings.
+func containsAny(s string, substrings []string) bool {
+	for _, substring := range substrings {
+		if strings.Contains(s, substring) {
+			return true
+		}
+	}
+	return false
+}
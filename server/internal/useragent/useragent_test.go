@@ -0,0 +1,112 @@
+package useragent
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name       string
+		header     string
+		want       Agent
+		deviceName string
+	}{
+		{
+			"Edge on Windows",
+			"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 Edg/120.0.0.0",
+			Agent{"Edge", "Windows", DeviceDesktop},
+			"Edge on Windows",
+		},
+		{
+			"Chrome on macOS",
+			"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+			Agent{"Chrome", "macOS", DeviceDesktop},
+			"Chrome on macOS",
+		},
+		{
+			"Safari on macOS",
+			"Mozilla/5.0 (Macintosh; Intel Mac OS X 14_2) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.2 Safari/605.1.15",
+			Agent{"Safari", "macOS", DeviceDesktop},
+			"Safari on macOS",
+		},
+		{
+			"Firefox on Linux",
+			"Mozilla/5.0 (X11; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0",
+			Agent{"Firefox", "Linux", DeviceDesktop},
+			"Firefox on Linux",
+		},
+		{
+			"Chrome on ChromeOS",
+			"Mozilla/5.0 (X11; CrOS x86_64 14541.0.0) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+			Agent{"Chrome", "ChromeOS", DeviceDesktop},
+			"Chrome on ChromeOS",
+		},
+		{
+			"Opera on Windows",
+			"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/119.0.0.0 Safari/537.36 OPR/105.0.0.0",
+			Agent{"Opera", "Windows", DeviceDesktop},
+			"Opera on Windows",
+		},
+		{
+			"Safari on iPhone",
+			"Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1",
+			Agent{"Safari", "iOS", DeviceMobile},
+			"Safari on iOS",
+		},
+		{
+			"Chrome on iPhone",
+			"Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) CriOS/120.0.6099.119 Mobile/15E148 Safari/604.1",
+			Agent{"Chrome", "iOS", DeviceMobile},
+			"Chrome on iOS",
+		},
+		{
+			"Safari on iPad",
+			"Mozilla/5.0 (iPad; CPU OS 16_6 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.6 Mobile/15E148 Safari/604.1",
+			Agent{"Safari", "iPadOS", DeviceTablet},
+			"Safari on iPadOS",
+		},
+		{
+			"Samsung Internet on an Android phone",
+			"Mozilla/5.0 (Linux; Android 13; SM-S911B) AppleWebKit/537.36 (KHTML, like Gecko) SamsungBrowser/23.0 Chrome/115.0.0.0 Mobile Safari/537.36",
+			Agent{"Samsung Internet", "Android", DeviceMobile},
+			"Samsung Internet on Android",
+		},
+		{
+			"Chrome on an Android tablet",
+			"Mozilla/5.0 (Linux; Android 13; SM-X700) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+			Agent{"Chrome", "Android", DeviceTablet},
+			"Chrome on Android",
+		},
+		{
+			"Firefox on an Android phone",
+			"Mozilla/5.0 (Android 14; Mobile; rv:121.0) Gecko/121.0 Firefox/121.0",
+			Agent{"Firefox", "Android", DeviceMobile},
+			"Firefox on Android",
+		},
+		{
+			"Googlebot",
+			"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			Agent{"", "", DeviceBot},
+			"Unknown device",
+		},
+		{
+			"Bingbot on a phone",
+			"Mozilla/5.0 (Linux; Android 6.0.1; Nexus 5X Build/MMB29P) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/116.0.1938.76 Mobile Safari/537.36 (compatible; bingbot/2.0; +http://www.bing.com/bingbot.htm)",
+			Agent{"Chrome", "Android", DeviceBot},
+			"Chrome on Android",
+		},
+		{"curl", "curl/8.4.0", Agent{"curl", "", DeviceUnknown}, "curl"},
+		{"Go HTTP client", "Go-http-client/1.1", Agent{"Go HTTP client", "", DeviceUnknown}, "Go HTTP client"},
+		{"unknown browser on Linux", "SomeApp/1.0 (Linux)", Agent{"", "Linux", DeviceDesktop}, "Unknown browser on Linux"},
+		{"empty", "", Agent{"", "", DeviceUnknown}, "Unknown device"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Parse(tt.header)
+			if got != tt.want {
+				t.Fatalf("Parse = %+v, want %+v", got, tt.want)
+			}
+			if name := got.DeviceName(); name != tt.deviceName {
+				t.Errorf("DeviceName = %q, want %q", name, tt.deviceName)
+			}
+		})
+	}
+}
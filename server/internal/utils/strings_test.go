@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		n    int
+		want string
+	}{
+		{"shorter", "abc", 5, "abc"},
+		{"exact", "abcde", 5, "abcde"},
+		{"longer", "abcdef", 5, "abcde"},
+		{"empty", "", 5, ""},
+		{"zero", "abc", 0, ""},
+		{"before a multi-byte rune", "abcdé", 5, "abcd"},
+		{"inside a multi-byte rune", "ab€", 4, "ab"},
+		{"after a multi-byte rune", "é€x", 5, "é€"},
+		{"only multi-byte runes", "€€", 2, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Truncate(tt.s, tt.n)
+			if got != tt.want {
+				t.Fatalf("Truncate(%q, %d) = %q, want %q", tt.s, tt.n, got, tt.want)
+			}
+			if !utf8.ValidString(got) {
+				t.Fatalf("Truncate(%q, %d) = %q is not valid UTF-8", tt.s, tt.n, got)
+			}
+		})
+	}
+}
@@ -2,7 +2,6 @@ package utils
 
 import (
 	"errors"
-	"os"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -15,9 +14,9 @@ type Claims struct {
 }
 
 // GenerateToken generates a JWT token with the provided user ID.
-// The token is signed using the JWT_SECRET_KEY environment variable and
+// The token is signed using the provided secret key and
 // has an expiration time of 24 hours.
-func GenerateToken(userID uint) (string, error) {
+func GenerateToken(userID uint, secretKey string) (string, error) {
 	// Create custom claims with user ID and expiration time
 	claims := Claims{
 		UserID: userID,
@@ -29,10 +28,9 @@ func GenerateToken(userID uint) (string, error) {
 	// Create a new token with the claims
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
-	// Get the secret key from environment variables
-	secretKey := os.Getenv("JWT_SECRET_KEY")
+	// Make sure a secret key was provided
 	if secretKey == "" {
-		return "", errors.New("JWT secret key not set in environment variables")
+		return "", errors.New("JWT secret key not set")
 	}
 
 	// Sign the token with the secret key
@@ -44,16 +42,15 @@ func GenerateToken(userID uint) (string, error) {
 	return tokenString, nil
 }
 
-// ValidateToken validates the provided JWT token and returns the claims
+// ValidateToken validates the provided JWT token using the provided secret key and returns the claims
 // contained in the token. If the token is invalid, an error is returned.
-func ValidateToken(tokenString string) (*Claims, error) {
+func ValidateToken(tokenString string, secretKey string) (*Claims, error) {
 	// Initialize claims struct
 	claims := &Claims{}
 
-	// Get the secret key from environment variables
-	secretKey := os.Getenv("JWT_SECRET_KEY")
+	// Make sure a secret key was provided
 	if secretKey == "" {
-		return nil, errors.New("JWT secret key not set in environment variables")
+		return nil, errors.New("JWT secret key not set")
 	}
 
 	// Parse and validate the token
@@ -0,0 +1,221 @@
+// Package validation normalises and validates request structs according to their `validate` struct tags.
+//
+// A tag is a comma-separated list of rules applied in order to string and *string fields:
+//   - trim: remove leading and trailing white space
+//   - nfc: normalise to Unicode Normalization Form C, so visually identical input compares equal
+//   - lower: convert to lower case
+//   - required: the value must not be empty
+//   - email: the value must be a plain email address such as user@example.com
+//   - nocontrol: the value must not contain control characters such as newlines
+//   - min=N, max=N: the value must have at least or at most N characters
+//   - maxbytes=N: the value must be at most N bytes long when encoded as UTF-8
+//   - handle: 3 to 30 lower-case letters, digits and underscores
+//   - locale: a BCP 47 language tag such as en-US, rewritten to its canonical form
+//   - timezone: an IANA time zone name such as Europe/Sofia
+//   - phone: an E.164 phone number such as +359888123456; spaces, dashes, dots and parentheses are removed
+//
+// trim, nfc, lower, locale and phone rewrite the field. A nil *string is skipped, so optional fields can be
+// omitted, and the rules after required are skipped for empty values. Fields are reported by their JSON names.
+package validation
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // Time zone names are validated even on hosts without a time zone database
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/problem"
+	"golang.org/x/text/language"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Struct normalises the fields of the struct v points to and returns an error for each invalid field.
+// It panics if v is not a pointer to a struct or a tag is malformed, as both are programming errors.
+func Struct(v any) []problem.FieldError {
+	value := reflect.ValueOf(v)
+	if value.Kind() != reflect.Pointer || value.Elem().Kind() != reflect.Struct {
+		panic(fmt.Sprintf("validation: expected a pointer to a struct, got %T", v))
+	}
+	value = value.Elem()
+
+	var errors []problem.FieldError
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		tag, ok := field.Tag.Lookup("validate")
+		if !ok || !field.IsExported() {
+			continue
+		}
+
+		target := value.Field(i)
+		if target.Kind() == reflect.Pointer {
+			if target.IsNil() {
+				continue
+			}
+			target = target.Elem()
+		}
+		if target.Kind() != reflect.String {
+			panic(fmt.Sprintf("validation: field %s is not a string", field.Name))
+		}
+
+		if err := validateString(jsonName(field), target, strings.Split(tag, ",")); err != nil {
+			errors = append(errors, *err)
+		}
+	}
+	return errors
+}
+
+// validateString applies the rules to a string field and returns the first violation.
+func validateString(name string, target reflect.Value, rules []string) *problem.FieldError {
+	fail := func(code, format string, args ...any) *problem.FieldError {
+		return &problem.FieldError{Field: name, Code: code, Message: name + " " + fmt.Sprintf(format, args...)}
+	}
+
+	for _, rule := range rules {
+		rule, arg, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		value := target.String()
+
+		switch rule {
+		case "trim":
+			target.SetString(strings.TrimSpace(value))
+		case "nfc":
+			target.SetString(norm.NFC.String(value))
+		case "lower":
+			target.SetString(strings.ToLower(value))
+		case "required":
+			if value == "" {
+				return fail(problem.FieldRequired, "is required")
+			}
+		default:
+			// The remaining rules only check non-empty values
+			if value == "" {
+				continue
+			}
+			switch rule {
+			case "email":
+				if !isEmail(value) {
+					return fail(problem.FieldInvalid, "must be a valid email address")
+				}
+			case "nocontrol":
+				if strings.IndexFunc(value, unicode.IsControl) >= 0 {
+					return fail(problem.FieldInvalid, "must not contain control characters")
+				}
+			case "min":
+				if n := ruleInt(rule, arg); utf8.RuneCountInString(value) < n {
+					return fail(problem.FieldTooShort, "must be at least %d characters", n)
+				}
+			case "max":
+				if n := ruleInt(rule, arg); utf8.RuneCountInString(value) > n {
+					return fail(problem.FieldTooLong, "must be at most %d characters", n)
+				}
+			case "maxbytes":
+				if n := ruleInt(rule, arg); len(value) > n {
+					return fail(problem.FieldTooLong, "must be at most %d bytes", n)
+				}
+			case "handle":
+				if !handlePattern.MatchString(value) {
+					return fail(problem.FieldInvalid, "must be 3 to 30 lower-case letters, digits or underscores")
+				}
+			case "locale":
+				tag, err := language.Parse(value)
+				if err != nil {
+					return fail(problem.FieldInvalid, "must be a language tag such as en-US")
+				}
+				target.SetString(tag.String())
+			case "timezone":
+				if value == "Local" || strings.EqualFold(value, "local") {
+					return fail(problem.FieldInvalid, "must be a time zone name such as Europe/Sofia")
+				}
+				if _, err := time.LoadLocation(value); err != nil {
+					return fail(problem.FieldInvalid, "must be a time zone name such as Europe/Sofia")
+				}
+			case "phone":
+				value = phoneSeparators.Replace(value)
+				if !phonePattern.MatchString(value) {
+					return fail(problem.FieldInvalid, "must be a phone number in international format such as +359888123456")
+				}
+				target.SetString(value)
+			default:
+				panic(fmt.Sprintf("validation: unknown rule %q", rule))
+			}
+		}
+	}
+	return nil
+}
+
+var (
+	handlePattern   = regexp.MustCompile(`^[a-z0-9_]{3,30}$`)
+	phonePattern    = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+	phoneSeparators = strings.NewReplacer(" ", "", "-", "", ".", "", "(", "", ")", "")
+)
+
+// isEmail reports whether value is a bare address with a domain, rejecting display names and angle brackets.
+func isEmail(value string) bool {
+	address, err := mail.ParseAddress(value)
+	if err != nil || address.Name != "" || address.Address != value {
+		return false
+	}
+	_, domain, _ := strings.Cut(value, "@")
+	return strings.Contains(domain, ".") && !strings.HasSuffix(domain, ".")
+}
+
+// ruleInt parses the numeric argument of a rule.
+func ruleInt(rule, arg string) int {
+	n, err := strconv.Atoi(arg)
+	if err != nil || n < 0 {
+		panic(fmt.Sprintf("validation: rule %s needs a non-negative number, got %q", rule, arg))
+	}
+	return n
+}
+
+// jsonName returns the name of the field in JSON documents.
+func jsonName(field reflect.StructField) string {
+	if name, _, _ := strings.Cut(field.Tag.Get("json"), ","); name != "" && name != "-" {
+		return name
+	}
+	return field.Name
+}
+
+// IsRequired reports whether the field of the struct v points to with the given JSON name has the required rule.
+func IsRequired(v any, name string) bool {
+	field, ok := fieldByJSONName(v, name)
+	if !ok {
+		return false
+	}
+	for _, rule := range strings.Split(field.Tag.Get("validate"), ",") {
+		if strings.TrimSpace(rule) == "required" {
+			return true
+		}
+	}
+	return false
+}
+
+// FieldKind returns the kind of the field of the struct v points to with the given JSON name, looking
+// through pointers, or reflect.Invalid if there is no such field.
+func FieldKind(v any, name string) reflect.Kind {
+	field, ok := fieldByJSONName(v, name)
+	if !ok {
+		return reflect.Invalid
+	}
+	fieldType := field.Type
+	for fieldType.Kind() == reflect.Pointer {
+		fieldType = fieldType.Elem()
+	}
+	return fieldType.Kind()
+}
+
+// fieldByJSONName returns the field of the struct v points to with the given JSON name.
+func fieldByJSONName(v any, name string) (reflect.StructField, bool) {
+	structType := reflect.TypeOf(v).Elem()
+	for i := 0; i < structType.NumField(); i++ {
+		if field := structType.Field(i); jsonName(field) == name {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
@@ -0,0 +1,196 @@
+package validation
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dobromirpetrov00/go_react_jwtauth/server/internal/problem"
+)
+
+// validateTag validates value as a string field with the given validate tag and returns the field after
+// normalisation and the code of the error, if any.
+func validateTag(tag, value string) (string, string) {
+	structType := reflect.StructOf([]reflect.StructField{{
+		Name: "Value",
+		Type: reflect.TypeOf(""),
+		Tag:  reflect.StructTag(fmt.Sprintf(`json:"value" validate:%q`, tag)),
+	}})
+	v := reflect.New(structType)
+	v.Elem().Field(0).SetString(value)
+
+	errors := Struct(v.Interface())
+	code := ""
+	if len(errors) > 0 {
+		code = errors[0].Code
+	}
+	return v.Elem().Field(0).String(), code
+}
+
+func TestRules(t *testing.T) {
+	tests := []struct {
+		tag   string
+		value string
+		want  string // The normalised value
+		code  string // The error code, or "" if the value is valid
+	}{
+		{"trim", "  Ann \n", "Ann", ""},
+		{"nfc", "Cafe\u0301", "Caf\u00e9", ""},
+		{"lower", "Ann@Example.COM", "ann@example.com", ""},
+		{"trim,required", "   ", "", problem.FieldRequired},
+		{"required", "x", "x", ""},
+		{"required,max=3", "", "", problem.FieldRequired},
+
+		{"email", "ann@example.com", "ann@example.com", ""},
+		{"email", "ann@localhost", "ann@localhost", problem.FieldInvalid},
+		{"email", "ann@example.", "ann@example.", problem.FieldInvalid},
+		{"email", "Ann <ann@example.com>", "Ann <ann@example.com>", problem.FieldInvalid},
+		{"email", "not an address", "not an address", problem.FieldInvalid},
+		{"email", "", "", ""},
+
+		{"nocontrol", "Ann Smith", "Ann Smith", ""},
+		{"nocontrol", "Ann\nSmith", "Ann\nSmith", problem.FieldInvalid},
+
+		{"min=3", "ab", "ab", problem.FieldTooShort},
+		{"min=3", "абв", "абв", ""},
+		{"max=3", "абв", "абв", ""},
+		{"max=3", "abcd", "abcd", problem.FieldTooLong},
+		{"maxbytes=4", "абв", "абв", problem.FieldTooLong},
+		{"maxbytes=6", "абв", "абв", ""},
+
+		{"handle", "ann_99", "ann_99", ""},
+		{"handle", "an", "an", problem.FieldInvalid},
+		{"handle", "Ann", "Ann", problem.FieldInvalid},
+		{"handle", "ann-smith", "ann-smith", problem.FieldInvalid},
+		{"handle", strings.Repeat("a", 31), strings.Repeat("a", 31), problem.FieldInvalid},
+
+		{"locale", "en-us", "en-US", ""},
+		{"locale", "bg", "bg", ""},
+		{"locale", "not a locale", "not a locale", problem.FieldInvalid},
+
+		{"timezone", "Europe/Sofia", "Europe/Sofia", ""},
+		{"timezone", "UTC", "UTC", ""},
+		{"timezone", "Local", "Local", problem.FieldInvalid},
+		{"timezone", "Mars/Olympus", "Mars/Olympus", problem.FieldInvalid},
+
+		{"phone", "+359 (888) 123-456", "+359888123456", ""},
+		{"phone", "+359.888.123.456", "+359888123456", ""},
+		{"phone", "0888123456", "0888123456", problem.FieldInvalid},
+		{"phone", "+0888123456", "+0888123456", problem.FieldInvalid},
+		{"phone", "+12345", "+12345", problem.FieldInvalid},
+
+		{"trim,lower,email", "  Ann@Example.com ", "ann@example.com", ""},
+		{"trim, max=2", " abc ", "abc", problem.FieldTooLong},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tag+"/"+tt.value, func(t *testing.T) {
+			got, code := validateTag(tt.tag, tt.value)
+			if got != tt.want || code != tt.code {
+				t.Fatalf("got %q with code %q, want %q with code %q", got, code, tt.want, tt.code)
+			}
+		})
+	}
+}
+
+func TestStruct(t *testing.T) {
+	type request struct {
+		Name     string  `json:"name" validate:"trim,required"`
+		Email    *string `json:"email,omitempty" validate:"trim,email"`
+		Bio      *string `json:"bio" validate:"max=5"`
+		Nickname string  `validate:"min=2"`
+		Ignored  string  `json:"ignored"`
+		internal string  `validate:"required"`
+	}
+
+	email := " ann@example.com "
+	req := request{Name: " Ann ", Email: &email, Nickname: "A", internal: ""}
+	errors := Struct(&req)
+
+	if req.Name != "Ann" || *req.Email != "ann@example.com" {
+		t.Errorf("fields not normalised: %+v", req)
+	}
+	if len(errors) != 1 || errors[0].Field != "Nickname" || errors[0].Code != problem.FieldTooShort {
+		t.Fatalf("errors = %+v, want one too_short error for Nickname", errors)
+	}
+	if want := "Nickname must be at least 2 characters"; errors[0].Message != want {
+		t.Errorf("message = %q, want %q", errors[0].Message, want)
+	}
+
+	// Every invalid field is reported, by its JSON name
+	bio := "too long"
+	errors = Struct(&request{Bio: &bio, Nickname: "Al"})
+	var fields []string
+	for _, err := range errors {
+		fields = append(fields, err.Field)
+	}
+	if strings.Join(fields, ",") != "name,bio" {
+		t.Fatalf("invalid fields %v, want name and bio", fields)
+	}
+}
+
+func TestStructPanics(t *testing.T) {
+	tests := []struct {
+		name string
+		v    any
+	}{
+		{"not a pointer", struct{}{}},
+		{"pointer to a non-struct", new(string)},
+		{"unknown rule", &struct {
+			A string `validate:"shiny"`
+		}{A: "x"}},
+		{"missing rule argument", &struct {
+			A string `validate:"max"`
+		}{A: "x"}},
+		{"negative rule argument", &struct {
+			A string `validate:"min=-1"`
+		}{A: "x"}},
+		{"non-string field", &struct {
+			A int `validate:"required"`
+		}{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Fatal("Struct did not panic")
+				}
+			}()
+			Struct(tt.v)
+		})
+	}
+}
+
+func TestIsRequired(t *testing.T) {
+	type request struct {
+		Name  string `json:"name" validate:"trim, required"`
+		Email string `json:"email" validate:"email"`
+		Phone string `json:"phone"`
+	}
+	for name, want := range map[string]bool{"name": true, "email": false, "phone": false, "missing": false} {
+		if got := IsRequired(&request{}, name); got != want {
+			t.Errorf("IsRequired(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestFieldKind(t *testing.T) {
+	type request struct {
+		Name       *string           `json:"name"`
+		Bio        string            `json:"bio"`
+		Attributes map[string]string `json:"attributes"`
+		Age        **int             `json:"age"`
+	}
+	tests := map[string]reflect.Kind{
+		"name":       reflect.String,
+		"bio":        reflect.String,
+		"attributes": reflect.Map,
+		"age":        reflect.Int,
+		"missing":    reflect.Invalid,
+	}
+	for name, want := range tests {
+		if got := FieldKind(&request{}, name); got != want {
+			t.Errorf("FieldKind(%q) = %v, want %v", name, got, want)
+		}
+	}
+}